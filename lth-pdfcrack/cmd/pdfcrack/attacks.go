@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lth/pdfcrack/internal/attacks"
+)
+
+var attackNames []string
+
+// applyAttacks turns -a names into the per-mode switches. The mask itself is
+// the positional argument, as in hashcat.
+func applyAttacks(args []string) error {
+	if len(wordlistArgs) > 0 {
+		wordlist = wordlistArgs[0]
+	}
+	for _, name := range attackNames {
+		name = strings.ToLower(name)
+		switch name {
+		case "wordlist", "0":
+			useWordlist = true
+		case "harvest":
+			useHarvest = true
+		case "mask", "3":
+			useMask = true
+		case "hybrid-wm", "6", "hybrid-mw", "7":
+			maskFirst := name == "hybrid-mw" || name == "7"
+			if useHybrid && hybridMaskFirst != maskFirst {
+				return fmt.Errorf("use either hybrid-wm or hybrid-mw, not both")
+			}
+			useHybrid = true
+			hybridMaskFirst = maskFirst
+		case "combinator", "1":
+			useCombinator = true
+		case "markov":
+			useMarkov = true
+		case "pcfg":
+			usePCFG = true
+		case "prince":
+			usePrince = true
+		case "template":
+			useTemplate = true
+		case "pattern":
+			usePattern = true
+		case "keyboard":
+			useKeyboard = true
+		case "passphrase":
+			usePassphrase = true
+		case "incremental":
+			useIncremental = true
+		case "random":
+			useRandom = true
+		default:
+			return fmt.Errorf("unknown attack %q (use wordlist, combinator, mask, hybrid-wm, hybrid-mw, markov, pcfg, prince, template, pattern, keyboard, passphrase, harvest, incremental or random)", name)
+		}
+	}
+
+	needMask := useMask || useHybrid
+	if len(args) > 0 {
+		if !needMask {
+			return fmt.Errorf("unexpected argument %q; masks need -a mask or a hybrid attack", args[0])
+		}
+		mask = args[0]
+	}
+	if needMask && mask == "" {
+		return fmt.Errorf("mask and hybrid attacks need a mask, e.g. -a mask '?u?l?l?l?d?d?d?d'")
+	}
+	if useHybrid && wordlist == "" {
+		return fmt.Errorf("hybrid attacks need -w <wordlist_file>")
+	}
+	if (useHybrid || usePrince) && (len(wordlistArgs) > 1 || wordlist == attacks.Stdin) {
+		return fmt.Errorf("hybrid and PRINCE attacks read a single -w wordlist file")
+	}
+	if needMask {
+		if _, err := attacks.NewMaskKeyspace(maskConfig()); err != nil {
+			return err
+		}
+	}
+	if useWordlist {
+		if _, err := attacks.ExpandWordlists(wordlistArgs); err != nil {
+			return err
+		}
+		if _, err := newNormalizer(); err != nil {
+			return err
+		}
+		if wordlistShards < 0 {
+			return fmt.Errorf("--shards must not be negative")
+		}
+	}
+	if useHarvest && harvestMax < 0 {
+		return fmt.Errorf("--harvest-max must not be negative")
+	}
+	if variantsEnabled() {
+		if !useWordlist && !useHarvest {
+			return fmt.Errorf("--toggle-case, --leet and --accents apply to the wordlist and harvest modes")
+		}
+		if _, err := newVariants(); err != nil {
+			return err
+		}
+	}
+	if useHybrid {
+		if _, err := newHybrid(); err != nil {
+			return err
+		}
+	}
+	if useCombinator {
+		if _, err := newCombinator(); err != nil {
+			return err
+		}
+	}
+	if useMarkov {
+		if markovThreshold < 0 {
+			return fmt.Errorf("--markov-threshold must not be negative")
+		}
+		if _, err := attacks.LoadMarkovStats(markovStats); err != nil {
+			return err
+		}
+	}
+	if usePCFG {
+		if _, err := attacks.LoadPCFGGrammar(pcfgGrammar); err != nil {
+			return err
+		}
+	}
+	if usePrince {
+		if _, err := newPrinceKeyspace(); err != nil {
+			return err
+		}
+	}
+	if useTemplate {
+		if _, err := newTemplateKeyspace(); err != nil {
+			return err
+		}
+	}
+	if usePattern {
+		if dateTo == "" {
+			// Pin today so a restored checkpoint walks the same keyspace.
+			dateTo = time.Now().Format("2006-01-02")
+		}
+		if _, err := newPatternKeyspace(); err != nil {
+			return err
+		}
+	}
+	if useKeyboard {
+		if _, err := newKeyboardKeyspace(); err != nil {
+			return err
+		}
+	}
+	if usePassphrase {
+		if _, err := newPassphraseKeyspace(); err != nil {
+			return err
+		}
+	}
+	if useRandom && randomSeed == 0 {
+		// A fixed seed is what lets a checkpoint resume the same order.
+		randomSeed = time.Now().UnixNano()
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/lth/pdfcrack/internal/attacks"
+	"github.com/spf13/cobra"
+)
+
+var (
+	useCombinator  bool
+	leftList       string
+	middleList     string
+	rightList      string
+	separators     []string
+	leftRuleFiles  []string
+	rightRuleFiles []string
+)
+
+func addCombinatorFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&leftList, "left", "", "Left wordlist for the combinator attack")
+	cmd.Flags().StringVar(&middleList, "middle", "", "Optional middle wordlist for the combinator attack")
+	cmd.Flags().StringVar(&rightList, "right", "", "Right wordlist for the combinator attack")
+	cmd.Flags().StringArrayVar(&separators, "separator", nil, "Separator placed between combined words (repeatable, \"\" for none)")
+	cmd.Flags().StringArrayVar(&leftRuleFiles, "left-rules", nil, "Rule file applied to left words (repeatable, stacks)")
+	cmd.Flags().StringArrayVar(&rightRuleFiles, "right-rules", nil, "Rule file applied to right words (repeatable, stacks)")
+}
+
+func newCombinator() (*attacks.Combinator, error) {
+	if leftList == "" || rightList == "" {
+		return nil, fmt.Errorf("combinator attack needs --left and --right wordlists")
+	}
+	left, err := loadRuleEngine(leftRuleFiles)
+	if err != nil {
+		return nil, err
+	}
+	right, err := loadRuleEngine(rightRuleFiles)
+	if err != nil {
+		return nil, err
+	}
+	return attacks.NewCombinator(attacks.CombinatorConfig{
+		Left:       leftList,
+		Middle:     middleList,
+		Right:      rightList,
+		Separators: separators,
+		LeftRules:  left,
+		RightRules: right,
+	})
+}
+
+func combinatorGenerator(progress *attacks.WordlistProgress) func(ctx context.Context) <-chan string {
+	start := resumePositions["C"]
+	return func(ctx context.Context) <-chan string {
+		c, err := newCombinator()
+		if err == nil {
+			var ch <-chan string
+			if ch, err = c.Generator(ctx, start, math.MaxUint64, progress); err == nil {
+				return ch
+			}
+		}
+		fmt.Fprintf(os.Stderr, "\nCombinator: %v\n", err)
+		closed := make(chan string)
+		close(closed)
+		return closed
+	}
+}
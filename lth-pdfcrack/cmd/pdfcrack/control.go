@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/lth/pdfcrack/internal/attacks"
+	"github.com/lth/pdfcrack/internal/cracker"
+	"golang.org/x/term"
+)
+
+// runControl connects the shared worker gate to signals and keyboard input.
+// Pause/resume arrive as SIGUSR1/SIGUSR2 or the p/r keys; q, Ctrl+C and
+// SIGTERM stop the run and ask for a checkpoint.
+type runControl struct {
+	gate         *cracker.Gate
+	keys         chan byte
+	sigChan      chan os.Signal
+	restoreTerm  func()
+	quitOnce     sync.Once
+	quit         func()
+	checkpointed atomic.Bool
+	// done closes when Stop is called, so readKeys stops delivering keys.
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+func startControl(gate *cracker.Gate, quit func()) *runControl {
+	ctl := &runControl{
+		gate:    gate,
+		keys:    make(chan byte, 8),
+		sigChan: make(chan os.Signal, 4),
+		quit:    quit,
+		done:    make(chan struct{}),
+	}
+
+	signals := []os.Signal{os.Interrupt, syscall.SIGTERM}
+	if pauseSignal != nil {
+		signals = append(signals, pauseSignal, resumeSignal)
+	}
+	signal.Notify(ctl.sigChan, signals...)
+	go ctl.handleSignals()
+
+	// Keys are read from a terminal, unless it is also the wordlist.
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) && !slices.Contains(wordlistArgs, attacks.Stdin) {
+		if restore, err := enableKeyInput(fd); err == nil {
+			ctl.restoreTerm = restore
+			go ctl.readKeys()
+		}
+	}
+
+	return ctl
+}
+
+func (ctl *runControl) handleSignals() {
+	for sig := range ctl.sigChan {
+		switch {
+		case pauseSignal != nil && sig == pauseSignal:
+			ctl.pause()
+		case resumeSignal != nil && sig == resumeSignal:
+			ctl.resume()
+		default:
+			fmt.Println("\nStopping...")
+			ctl.stop()
+		}
+	}
+}
+
+// readKeys runs until the run stops. A read already waiting then returns
+// with the next key press, which is dropped.
+func (ctl *runControl) readKeys() {
+	buf := make([]byte, 1)
+	for {
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			return
+		}
+		if n == 1 {
+			select {
+			case ctl.keys <- buf[0]:
+			case <-ctl.done:
+				return
+			}
+		}
+	}
+}
+
+// Keys delivers key presses to the status display loop.
+func (ctl *runControl) Keys() <-chan byte {
+	return ctl.keys
+}
+
+func (ctl *runControl) HandleKey(key byte, printStatus func()) {
+	switch key {
+	case 'p', 'P':
+		ctl.pause()
+	case 'r', 'R':
+		ctl.resume()
+	case 's', 'S':
+		fmt.Println()
+		printStatus()
+	case 'q', 'Q', 0x03:
+		fmt.Println("\nQuitting...")
+		ctl.stop()
+	}
+}
+
+func (ctl *runControl) pause() {
+	if !ctl.gate.Paused() {
+		ctl.gate.Pause()
+		fmt.Println("\nPaused. Press r (or send SIGUSR2) to resume.")
+	}
+}
+
+func (ctl *runControl) resume() {
+	if ctl.gate.Paused() {
+		ctl.gate.Resume()
+		fmt.Println("\nResumed.")
+	}
+}
+
+func (ctl *runControl) stop() {
+	ctl.quitOnce.Do(func() {
+		ctl.checkpointed.Store(true)
+		ctl.gate.Resume()
+		ctl.quit()
+	})
+}
+
+// Checkpoint reports whether the run was stopped on request rather than by
+// finding the password or running out of candidates.
+func (ctl *runControl) Checkpoint() bool {
+	return ctl.checkpointed.Load()
+}
+
+// Stop releases the signals and the terminal. It may be called more than
+// once.
+func (ctl *runControl) Stop() {
+	ctl.stopOnce.Do(func() {
+		// No signal is delivered once signal.Stop returns, so the channel
+		// can be closed to end handleSignals.
+		signal.Stop(ctl.sigChan)
+		close(ctl.sigChan)
+		close(ctl.done)
+		if ctl.restoreTerm != nil {
+			ctl.restoreTerm()
+		}
+	})
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/lth/pdfcrack/internal/attacks"
+	"github.com/lth/pdfcrack/internal/distributed"
+	"github.com/lth/pdfcrack/internal/pdf"
+	"github.com/spf13/cobra"
+)
+
+func runServer(cmd *cobra.Command, args []string) {
+	fmt.Printf("LTH PDF Password Cracker v%s - coordinator\n", version)
+	fmt.Println("================================")
+
+	if heartbeatTimeout <= 0 {
+		fmt.Fprintln(os.Stderr, "Error: --heartbeat-timeout must be positive")
+		os.Exit(1)
+	}
+	coord := distributed.NewCoordinator(heartbeatTimeout)
+	coord.SetFoundCallback(func(st distributed.JobStatus) {
+		fmt.Printf("\n[%s] PASSWORD FOUND: %s\n", st.ID, st.Password)
+	})
+
+	if err := applyAttacks(args); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if pdfFile != "" {
+		if !useWordlist && !useIncremental && !useRandom && !useMask && !useHybrid && !useTemplate {
+			fmt.Fprintln(os.Stderr, "Error: -f needs -W, -I, -R and/or -a mask|hybrid-wm|hybrid-mw|template to describe the job")
+			os.Exit(1)
+		}
+
+		encInfo, err := pdf.ExtractEncryptionInfo(pdfFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("File: %s\n", pdfFile)
+		fmt.Printf("Encryption: %s\n", encInfo.String())
+
+		var specs []distributed.AttackSpec
+		if useWordlist {
+			if wordlist == "" {
+				fmt.Fprintln(os.Stderr, "Error: Wordlist mode requires -w <wordlist_file>")
+				os.Exit(1)
+			}
+			specs = append(specs, distributed.AttackSpec{Mode: distributed.ModeWordlist, Wordlist: wordlist})
+		}
+		maskSpec := distributed.AttackSpec{
+			Mask:           mask,
+			CustomCharsets: customCharsetList(),
+			Increment:      increment,
+			IncrementMin:   incrementMin,
+			IncrementMax:   incrementMax,
+		}
+		if useMask {
+			spec := maskSpec
+			spec.Mode = distributed.ModeMask
+			specs = append(specs, spec)
+		}
+		if useHybrid {
+			spec := maskSpec
+			spec.Mode = distributed.ModeHybridWM
+			if hybridMaskFirst {
+				spec.Mode = distributed.ModeHybridMW
+			}
+			spec.Wordlist = wordlist
+			specs = append(specs, spec)
+		}
+		if useTemplate {
+			specs = append(specs, distributed.AttackSpec{
+				Mode:           distributed.ModeTemplate,
+				Template:       passwordTemplate,
+				CustomCharsets: customCharsetList(),
+			})
+		}
+		if useIncremental {
+			specs = append(specs, distributed.AttackSpec{
+				Mode:      distributed.ModeIncremental,
+				Charset:   attacks.ResolveCharset(charset),
+				MinLength: minLength,
+				MaxLength: maxLength,
+			})
+		}
+		if useRandom {
+			specs = append(specs, distributed.AttackSpec{
+				Mode:      distributed.ModeRandom,
+				Charset:   attacks.ResolveCharset(charset),
+				MinLength: minLength,
+				MaxLength: maxLength,
+				Seed:      randomSeed,
+			})
+		}
+
+		for _, spec := range specs {
+			st, err := coord.AddJob(distributed.JobRequest{Target: encInfo, Attack: spec, ChunkSize: chunkSize})
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("Queued %s: %s, %d candidates\n", st.ID, spec.Mode, st.Keyspace)
+		}
+	}
+
+	srv := &http.Server{Addr: listenAddr, Handler: coord.Handler()}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigChan
+		fmt.Println("\nStopping...")
+		cancel()
+		srv.Close()
+	}()
+
+	go func() {
+		// A timeout of a few nanoseconds would round the interval down to
+		// zero, which NewTicker rejects.
+		ticker := time.NewTicker(max(heartbeatTimeout/3, time.Millisecond))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if n := coord.Reap(); n > 0 {
+					fmt.Printf("\nRe-queued %d chunk(s) from unresponsive workers\n", n)
+				}
+			}
+		}
+	}()
+
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		startTime := time.Now()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				fmt.Printf("%-120s", "\r"+serverStatusLine(coord.Status(), time.Since(startTime)))
+			}
+		}
+	}()
+
+	fmt.Printf("Listening on %s\n\n", listenAddr)
+	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Println()
+	printServerSummary(coord.Status())
+}
+
+func serverStatusLine(st distributed.Status, elapsed time.Duration) string {
+	parts := []string{}
+	for _, j := range st.Jobs {
+		state := "running"
+		switch {
+		case j.Found:
+			state = "found"
+		case j.Done:
+			state = "exhausted"
+		}
+		pct := 100.0
+		if j.Keyspace > 0 {
+			pct = float64(j.Completed) / float64(j.Keyspace) * 100
+		}
+		parts = append(parts, fmt.Sprintf("%s %.1f%% %s", j.ID, pct, state))
+	}
+
+	alive := 0
+	var rate float64
+	for _, w := range st.Workers {
+		if w.Alive {
+			alive++
+			rate += w.Rate
+		}
+	}
+	parts = append(parts, fmt.Sprintf("%d worker(s) @ %.0f/s", alive, rate))
+
+	return fmt.Sprintf("[%s] %s", formatDuration(elapsed), strings.Join(parts, " | "))
+}
+
+func printServerSummary(st distributed.Status) {
+	fmt.Println("Jobs:")
+	for _, j := range st.Jobs {
+		if j.Found {
+			fmt.Printf("  %s: PASSWORD FOUND: %s\n", j.ID, j.Password)
+		} else {
+			fmt.Printf("  %s: %d/%d candidates, not found\n", j.ID, j.Completed, j.Keyspace)
+		}
+	}
+
+	fmt.Println("Workers:")
+	for _, w := range st.Workers {
+		fmt.Printf("  %s: %d attempts, %d chunks, %.0f/s, last seen %s\n",
+			w.ID, w.Attempts, w.Chunks, w.Rate, w.LastSeen.Format(time.TimeOnly))
+	}
+}
+
+func runWorker(cmd *cobra.Command, args []string) {
+	w := distributed.NewWorker(serverURL, workers)
+	w.Logf = func(format string, args ...interface{}) {
+		fmt.Fprintf(os.Stderr, format+"\n", args...)
+	}
+
+	fmt.Printf("LTH PDF Password Cracker v%s - worker %s\n", version, w.ID)
+	fmt.Printf("Server: %s\n", w.Server)
+	fmt.Printf("Workers: %d\n", workers)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigChan
+		fmt.Println("\nStopping...")
+		cancel()
+	}()
+
+	w.Run(ctx)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/lth/pdfcrack/internal/attacks"
+	"github.com/lth/pdfcrack/internal/attacks/rules"
+	"github.com/lth/pdfcrack/internal/cracker"
+	"github.com/lth/pdfcrack/internal/harvest"
+	"github.com/spf13/cobra"
+)
+
+var (
+	useHarvest bool
+	harvestMax int
+
+	// harvestCandidates are the harvested words after rules, without
+	// repeats. Positions index this list.
+	harvestCandidates []string
+	// harvestVariants numbers the candidates' variants when variant flags
+	// are set; positions then index it instead.
+	harvestVariants *attacks.VariantKeyspace
+)
+
+func addHarvestFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&useHarvest, "harvest", false, "Try words from the PDF's name and metadata and from .pdf, .txt and .eml files beside it, through -r or best64 rules")
+	cmd.Flags().IntVar(&harvestMax, "harvest-max", 5000, "Most harvested words to keep (0 = all)")
+}
+
+// prepareHarvest collects the words for targets and expands them with the
+// -r rules, or best64 without any.
+func prepareHarvest(targets []cracker.Target) error {
+	var sources []harvest.Target
+	for _, t := range targets {
+		if st, err := os.Stat(t.Name); err == nil && st.Mode().IsRegular() {
+			sources = append(sources, harvest.Target{Path: t.Name, EncryptMeta: t.Info.EncryptMeta})
+		}
+	}
+	if len(sources) == 0 {
+		fmt.Fprintln(os.Stderr, "Warning: --harvest needs PDF files; hash-file targets have nothing to harvest")
+	}
+	words, counts := harvest.Words(sources, harvestMax)
+
+	engine := ruleEngine
+	ruleName := "-r rules"
+	if engine == nil {
+		set, err := rules.Load("best64")
+		if err != nil {
+			return err
+		}
+		engine = rules.NewEngine(set)
+		ruleName = "best64"
+	}
+
+	seen := make(map[string]bool)
+	harvestCandidates = harvestCandidates[:0]
+	for _, word := range words {
+		for i := uint64(0); i < engine.Size(); i++ {
+			candidate, ok := engine.Apply(word, i)
+			if ok && !seen[candidate] {
+				seen[candidate] = true
+				harvestCandidates = append(harvestCandidates, candidate)
+			}
+		}
+	}
+
+	fmt.Printf("Harvest: %d words (%d from file names, %d from metadata, %d from nearby files), %d candidates with %s\n",
+		len(words), counts.Names, counts.Metadata, counts.Siblings, len(harvestCandidates), ruleName)
+	v, err := newVariants()
+	if err != nil {
+		return err
+	}
+	if v != nil {
+		harvestVariants = attacks.NewVariantKeyspace(harvestCandidates, v)
+		fmt.Printf("Harvest: %d variants of the candidates\n", harvestVariants.Size())
+	}
+	if verbose {
+		for _, word := range words {
+			fmt.Printf("  %s\n", word)
+		}
+	}
+	return nil
+}
+
+func harvestGenerator() func(ctx context.Context) <-chan string {
+	if harvestVariants != nil {
+		start := min(resumePositions["V"], harvestVariants.Size())
+		return func(ctx context.Context) <-chan string {
+			return attacks.KeyspaceGenerator(ctx, harvestVariants, start, harvestVariants.Size())
+		}
+	}
+	start := min(resumePositions["V"], uint64(len(harvestCandidates)))
+	return func(ctx context.Context) <-chan string {
+		return attacks.SliceGenerator(ctx, harvestCandidates[start:])
+	}
+}
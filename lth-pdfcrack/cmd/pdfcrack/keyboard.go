@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/lth/pdfcrack/internal/attacks"
+	"github.com/spf13/cobra"
+)
+
+var (
+	useKeyboard bool
+	kbLayouts   []string
+	kbMin       int
+	kbMax       int
+	kbTurns     int
+	kbCopies    int
+	kbShift     []string
+)
+
+func addKeyboardFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&kbLayouts, "kb-layout", []string{"us"}, "Keyboard: layouts to walk: us, uk, azerty, qwertz or a layout file")
+	cmd.Flags().IntVar(&kbMin, "kb-min", 4, "Keyboard: fewest keys in a walk")
+	cmd.Flags().IntVar(&kbMax, "kb-max", 8, "Keyboard: most keys in a walk")
+	cmd.Flags().IntVar(&kbTurns, "kb-turns", 1, "Keyboard: most changes of direction in a walk")
+	cmd.Flags().IntVar(&kbCopies, "kb-copies", 2, "Keyboard: most side-by-side repeats of a walk, as 1qaz2wsx")
+	cmd.Flags().StringSliceVar(&kbShift, "kb-shift", []string{attacks.ShiftNone, attacks.ShiftFirst}, "Keyboard: shift styles: none, first, all, copies")
+}
+
+func newKeyboardKeyspace() (*attacks.KeyboardKeyspace, error) {
+	config := attacks.KeyboardConfig{
+		MinLength: kbMin,
+		MaxLength: kbMax,
+		MaxTurns:  kbTurns,
+		MaxCopies: kbCopies,
+		Shift:     kbShift,
+	}
+	for _, name := range kbLayouts {
+		layout, err := attacks.LoadKeyboardLayout(name)
+		if err != nil {
+			return nil, err
+		}
+		config.Layouts = append(config.Layouts, layout)
+	}
+	return attacks.NewKeyboardKeyspace(config)
+}
+
+// keyboardGenerator walks the keyboard and passes each walk through the -r
+// rules, if any. Positions count walks.
+func keyboardGenerator() func(ctx context.Context) <-chan string {
+	start := resumePositions["B"]
+	return func(ctx context.Context) <-chan string {
+		ks, err := newKeyboardKeyspace()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "\nKeyboard: %v\n", err)
+			closed := make(chan string)
+			close(closed)
+			return closed
+		}
+		walks := attacks.KeyspaceGenerator(ctx, ks, start, ks.Size())
+		if ruleEngine == nil {
+			return walks
+		}
+		return ruleEngine.Generator(ctx, walks)
+	}
+}
@@ -4,17 +4,18 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"os/signal"
 	"runtime"
 	"strings"
 	"sync"
-	"syscall"
 	"time"
 
 	"github.com/lth/pdfcrack/internal/attacks"
+	"github.com/lth/pdfcrack/internal/attacks/rules"
 	"github.com/lth/pdfcrack/internal/cracker"
+	"github.com/lth/pdfcrack/internal/distributed"
 	"github.com/lth/pdfcrack/internal/gpu"
 	"github.com/lth/pdfcrack/internal/pdf"
+	"github.com/lth/pdfcrack/internal/session"
 	"github.com/spf13/cobra"
 )
 
@@ -22,6 +23,8 @@ var (
 	version = "1.2.0"
 
 	pdfFile   string
+	pdfFiles  []string
+	hashFile  string
 	wordlist  string
 	charset   string
 	minLength int
@@ -31,17 +34,35 @@ var (
 	batchSize int
 	verbose   bool
 
+	// wordlistArgs are the -w arguments; wordlist is the first, for modes
+	// that read a single file.
+	wordlistArgs     []string
+	wordlistEncoding string
+	dedupeWords      bool
+	wordlistShards   int
+	useMmap          bool
+
 	useWordlist    bool
 	useIncremental bool
 	useRandom      bool
-)
+	randomSeed     int64
 
-type modeStatus struct {
-	attempts uint64
-	rate     float64
-	current  string
-	active   bool
-}
+	listenAddr       string
+	serverURL        string
+	chunkSize        uint64
+	heartbeatTimeout time.Duration
+
+	potfilePath string
+	noPotfile   bool
+
+	sessionName    string
+	restoreSession bool
+
+	modeWeights map[string]int
+
+	ruleFiles  []string
+	ruleEngine *rules.Engine
+)
 
 type attackResult struct {
 	mode   string
@@ -59,22 +80,53 @@ Supports CPU multi-threading and optional GPU acceleration.
 Run one or more attack modes simultaneously:
   --wordlist (-W)     Dictionary attack using a wordlist file
   --incremental (-I)  Brute-force through all combinations  
-  --random (-R)       Random password generation
+  --random (-R)       Incremental keyspace in a random order, without repeats
+  --harvest           Words from the PDF's name, metadata and nearby files
+  -a mask MASK        Brute-force with a per-position mask
+  -a hybrid-wm MASK   Wordlist words followed by a mask (hybrid-mw: mask first)
+  -a combinator       Every word of --left joined with every word of --right
+  -a markov           Brute-force, likeliest candidates first
+  -a pcfg             Guesses from a trained password grammar
+  -a prince           Chains of wordlist words (PRINCE)
+  -a template         Known fragments around unknown parts, e.g. Rex{?a,1-4}{?d?d}
+  -a pattern          Dates, phone numbers, check-digit IDs and name+date presets
+  -a keyboard         Keyboard walks such as qwerty, 1qaz2wsx and zxcvbn
+  -a passphrase       Several words from a list, e.g. correct-horse-battery
 
 Examples:
   pdfcrack -f doc.pdf -W -w rockyou.txt              # Wordlist only
   pdfcrack -f doc.pdf -I -c digits -m 4 -M 6         # Incremental only
   pdfcrack -f doc.pdf -W -I -w list.txt              # Wordlist + Incremental
-  pdfcrack -f doc.pdf -W -I -R -w list.txt           # All three modes`,
-		Run: runCracker,
-	}
-
-	rootCmd.Flags().StringVarP(&pdfFile, "file", "f", "", "PDF file to crack (required)")
-	rootCmd.Flags().StringVarP(&wordlist, "wordlist-file", "w", "", "Wordlist file for dictionary attack")
+  pdfcrack -f doc.pdf -W -I -R -w list.txt           # All three modes
+  pdfcrack -f doc.pdf -a mask '?u?l?l?l?d?d?d?d'     # Mask
+  pdfcrack -f doc.pdf -a hybrid-wm -w list.txt '?d?d' # Words + 2 digits
+  pdfcrack -f doc.pdf -a combinator --left a.txt --right b.txt
+  pdfcrack -f doc.pdf -a markov -c alnum -m 6 -M 8   # Markov-ordered
+  pdfcrack -f doc.pdf -a pcfg --pcfg-grammar leaked.pcfg
+  pdfcrack -f doc.pdf -a prince -w words.txt -m 8 -M 12
+  pdfcrack -f doc.pdf -a template --template 'Rex{?a,1-4}{?d?d}{?s}'
+  pdfcrack -f doc.pdf --harvest                      # Words found around the PDF
+  pdfcrack -f doc.pdf -a pattern --pattern name4-ddmm --name Dupont
+  pdfcrack -f doc.pdf -a keyboard --kb-layout us,azerty -r best64
+  pdfcrack -f doc.pdf -W -w list.txt --leet basic --toggle-case 1
+  pdfcrack -f doc.pdf -a passphrase --phrase-max 3 --phrase-suffix '?d'
+  pdfcrack -f docs/ -f extra.pdf -W -w list.txt      # Many PDFs, one pass`,
+		Args: cobra.MaximumNArgs(1),
+		Run:  runCracker,
+	}
+
+	rootCmd.Flags().StringArrayVarP(&pdfFiles, "file", "f", nil, "PDF file or directory to crack (repeatable)")
+	rootCmd.Flags().StringVar(&hashFile, "hash-file", "", "File of $pdf$ hashes (pdf2john format), one target per line")
+	rootCmd.Flags().StringArrayVarP(&wordlistArgs, "wordlist-file", "w", nil, "Wordlist file, directory, glob or - for stdin; .gz and .bz2 are read directly (repeatable)")
+	rootCmd.Flags().StringVar(&wordlistEncoding, "wordlist-encoding", attacks.EncodingUTF8, "Wordlist character set: utf-8, latin1 or cp1252")
+	rootCmd.Flags().BoolVar(&dedupeWords, "dedupe", false, "Skip repeated wordlist lines (uses 64 MiB; a rare unique line may be skipped too)")
+	rootCmd.Flags().IntVar(&wordlistShards, "shards", 0, "Read large plain wordlists as N byte ranges in parallel")
+	rootCmd.Flags().BoolVar(&useMmap, "mmap", false, "Memory-map sharded wordlists instead of reading them")
+	rootCmd.Flags().StringArrayVarP(&ruleFiles, "rules", "r", nil, "Rule file applied to wordlist candidates; repeat to stack, \"best64\" for the built-in set")
 	rootCmd.Flags().StringVarP(&charset, "charset", "c", "alnum", "Character set: lower, upper, digits, alnum, all, or custom")
 	rootCmd.Flags().IntVarP(&minLength, "min", "m", 1, "Minimum password length")
 	rootCmd.Flags().IntVarP(&maxLength, "max", "M", 8, "Maximum password length")
-	rootCmd.Flags().IntVarP(&workers, "workers", "t", runtime.NumCPU(), "Number of CPU worker threads")
+	rootCmd.Flags().IntVarP(&workers, "workers", "t", runtime.NumCPU(), "Number of CPU worker threads, shared by all modes")
 	rootCmd.Flags().BoolVarP(&useGPU, "gpu", "g", false, "Enable GPU acceleration (requires OpenCL)")
 	rootCmd.Flags().IntVarP(&batchSize, "batch", "b", 10000, "GPU batch size")
 	rootCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Verbose output")
@@ -82,8 +134,25 @@ Examples:
 	rootCmd.Flags().BoolVarP(&useWordlist, "use-wordlist", "W", false, "Enable wordlist/dictionary attack")
 	rootCmd.Flags().BoolVarP(&useIncremental, "use-incremental", "I", false, "Enable incremental brute-force attack")
 	rootCmd.Flags().BoolVarP(&useRandom, "use-random", "R", false, "Enable random password attack")
-
-	rootCmd.MarkFlagRequired("file")
+	rootCmd.Flags().Int64Var(&randomSeed, "seed", 0, "Seed for the random mode's order (default: new each run, kept in checkpoints)")
+	rootCmd.Flags().StringArrayVarP(&attackNames, "attack", "a", nil, "Enable an attack by name: wordlist, combinator, mask, hybrid-wm, hybrid-mw, markov, pcfg, prince, template, pattern, keyboard, passphrase, harvest, incremental or random (repeatable)")
+	addMaskFlags(rootCmd)
+	addCombinatorFlags(rootCmd)
+	addMarkovFlags(rootCmd)
+	addPCFGFlags(rootCmd)
+	addPrinceFlags(rootCmd)
+	addTemplateFlags(rootCmd)
+	addPatternFlags(rootCmd)
+	addKeyboardFlags(rootCmd)
+	addPassphraseFlags(rootCmd)
+	addVariantFlags(rootCmd)
+	addHarvestFlags(rootCmd)
+	rootCmd.Flags().StringVar(&potfilePath, "potfile", "", "Potfile of cracked documents (default ~/.pdfcrack/pdfcrack.pot)")
+	rootCmd.Flags().BoolVar(&noPotfile, "no-potfile", false, "Do not read or write the potfile")
+	rootCmd.Flags().StringVar(&sessionName, "session", session.DefaultName, "Session name for checkpoints")
+	rootCmd.Flags().BoolVar(&restoreSession, "restore", false, "Resume the checkpointed session")
+	rootCmd.Flags().StringVar(&planFile, "plan", "", "Run the stages of an attack plan file (YAML or JSON)")
+	rootCmd.Flags().StringToIntVar(&modeWeights, "weights", nil, "Share of the workers per mode (default W=70,I=20,R=10)")
 
 	infoCmd := &cobra.Command{
 		Use:   "info",
@@ -103,7 +172,46 @@ Examples:
 	benchCmd.Flags().BoolVarP(&useGPU, "gpu", "g", false, "Benchmark GPU mode")
 	benchCmd.MarkFlagRequired("file")
 
-	rootCmd.AddCommand(infoCmd, benchCmd)
+	serverCmd := &cobra.Command{
+		Use:   "server [MASK]",
+		Short: "Coordinate distributed cracking across workers on the LAN",
+		Args:  cobra.MaximumNArgs(1),
+		Run:   runServer,
+	}
+	serverCmd.Flags().StringVar(&listenAddr, "listen", ":7420", "Address to listen on")
+	serverCmd.Flags().StringVarP(&pdfFile, "file", "f", "", "PDF file to queue as a job")
+	serverCmd.Flags().StringVarP(&wordlist, "wordlist-file", "w", "", "Wordlist file (must be readable by every worker at the same path)")
+	serverCmd.Flags().StringVarP(&charset, "charset", "c", "alnum", "Character set: lower, upper, digits, alnum, all, or custom")
+	serverCmd.Flags().IntVarP(&minLength, "min", "m", 1, "Minimum password length")
+	serverCmd.Flags().IntVarP(&maxLength, "max", "M", 8, "Maximum password length")
+	serverCmd.Flags().BoolVarP(&useWordlist, "use-wordlist", "W", false, "Queue a wordlist job")
+	serverCmd.Flags().BoolVarP(&useIncremental, "use-incremental", "I", false, "Queue an incremental job")
+	serverCmd.Flags().BoolVarP(&useRandom, "use-random", "R", false, "Queue a random-order job")
+	serverCmd.Flags().Int64Var(&randomSeed, "seed", 0, "Seed for the random job's order (default: new each run)")
+	serverCmd.Flags().StringArrayVarP(&attackNames, "attack", "a", nil, "Queue a job by attack name: wordlist, mask, hybrid-wm, hybrid-mw, template, incremental or random (repeatable)")
+	addMaskFlags(serverCmd)
+	addTemplateFlags(serverCmd)
+	serverCmd.Flags().Uint64Var(&chunkSize, "chunk-size", distributed.DefaultChunkSize, "Candidates per work chunk")
+	serverCmd.Flags().DurationVar(&heartbeatTimeout, "heartbeat-timeout", distributed.DefaultHeartbeatTimeout, "Re-assign chunks from workers silent for this long")
+
+	workerCmd := &cobra.Command{
+		Use:   "worker",
+		Short: "Pull work chunks from a pdfcrack server",
+		Run:   runWorker,
+	}
+	workerCmd.Flags().StringVar(&serverURL, "server", "", "Coordinator URL, e.g. http://10.0.0.5:7420 (required)")
+	workerCmd.Flags().IntVarP(&workers, "workers", "t", runtime.NumCPU(), "Number of CPU worker threads")
+	workerCmd.MarkFlagRequired("server")
+
+	showCmd := &cobra.Command{
+		Use:   "show FILES...",
+		Short: "List known passwords from the potfile",
+		Args:  cobra.MinimumNArgs(1),
+		Run:   runShow,
+	}
+	showCmd.Flags().StringVar(&potfilePath, "potfile", "", "Potfile of cracked documents (default ~/.pdfcrack/pdfcrack.pot)")
+
+	rootCmd.AddCommand(infoCmd, benchCmd, serverCmd, workerCmd, showCmd, newMarkovCmd(), newPCFGCmd())
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
@@ -126,9 +234,11 @@ func runInfo(cmd *cobra.Command, args []string) {
 	fmt.Printf("Key Length:  %d bits\n", encInfo.Length)
 	fmt.Printf("Algorithm:   %s\n", map[bool]string{true: "AES", false: "RC4"}[encInfo.IsAES])
 	fmt.Printf("Permissions: %d\n", encInfo.Permissions)
+	fmt.Printf("Password:    first %d bytes significant\n", encInfo.SignificantLength())
 	fmt.Printf("Owner Hash:  %x\n", encInfo.OwnerHash)
 	fmt.Printf("User Hash:   %x\n", encInfo.UserHash)
 	fmt.Printf("File ID:     %x\n", encInfo.FileID)
+	fmt.Printf("Hash:        %s\n", encInfo.Hash())
 }
 
 func runBenchmark(cmd *cobra.Command, args []string) {
@@ -178,51 +288,103 @@ func runBenchmark(cmd *cobra.Command, args []string) {
 }
 
 func runCracker(cmd *cobra.Command, args []string) {
-	if pdfFile == "" {
+	if restoreSession {
+		applySession()
+	}
+	if err := applyAttacks(args); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if len(pdfFiles) == 0 && hashFile == "" {
 		cmd.Help()
 		return
 	}
 
-	if !useWordlist && !useIncremental && !useRandom {
+	if planFile == "" && !anyModeEnabled() {
 		fmt.Fprintln(os.Stderr, "Error: No attack mode selected.")
-		fmt.Fprintln(os.Stderr, "Use one or more of: -W (wordlist), -I (incremental), -R (random)")
+		fmt.Fprintln(os.Stderr, "Use one or more of: -W (wordlist), -I (incremental), -R (random), -a mask, -a hybrid-wm, -a combinator, -a markov, -a pcfg, -a prince, -a template, -a pattern, -a keyboard, -a passphrase, --harvest, or --plan")
 		fmt.Fprintln(os.Stderr, "")
 		fmt.Fprintln(os.Stderr, "Examples:")
 		fmt.Fprintln(os.Stderr, "  pdfcrack -f doc.pdf -W -w wordlist.txt")
 		fmt.Fprintln(os.Stderr, "  pdfcrack -f doc.pdf -I -c digits -m 4 -M 6")
 		fmt.Fprintln(os.Stderr, "  pdfcrack -f doc.pdf -W -I -R -w wordlist.txt")
+		fmt.Fprintln(os.Stderr, "  pdfcrack -f doc.pdf -a mask '?u?l?l?l?d?d?d?d'")
 		os.Exit(1)
 	}
 
-	if useWordlist && wordlist == "" {
+	if useWordlist && len(wordlistArgs) == 0 {
 		fmt.Fprintln(os.Stderr, "Error: Wordlist mode requires -w <wordlist_file>")
 		os.Exit(1)
 	}
 
+	if err := checkWeights(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if len(ruleFiles) > 0 && !useWordlist && !useHarvest && !useKeyboard && planFile == "" {
+		fmt.Fprintln(os.Stderr, "Error: Rules (-r) apply to the wordlist, harvest and keyboard modes; add -W -w <wordlist_file>")
+		os.Exit(1)
+	}
+	if err := loadRules(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
 	fmt.Printf("LTH PDF Password Cracker v%s\n", version)
 	fmt.Println("================================")
 
-	encInfo, err := pdf.ExtractEncryptionInfo(pdfFile)
+	targets, err := loadTargets()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
+	if planFile != "" {
+		runPlan(targets)
+		return
+	}
+	if err := fitFlagLengths(cracker.SignificantLength(targets)); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if useHarvest {
+		if err := prepareHarvest(targets); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if len(targets) > 1 {
+		runMultiCracker(targets)
+		return
+	}
+
+	pdfFile = targets[0].Name
+	encInfo := targets[0].Info
 
 	fmt.Printf("File: %s\n", pdfFile)
 	fmt.Printf("Encryption: %s\n", encInfo.String())
 
-	var modes []string
-	if useWordlist {
-		modes = append(modes, "Wordlist")
-	}
-	if useIncremental {
-		modes = append(modes, "Incremental")
+	pot := openPotfile()
+	if pot != nil {
+		if password, ok := pot.Lookup(encInfo); ok {
+			fmt.Println()
+			fmt.Println("================================")
+			fmt.Printf("PASSWORD FOUND: %s\n", password)
+			fmt.Printf("Found by: potfile (%s)\n", pot.Path())
+			return
+		}
 	}
-	if useRandom {
-		modes = append(modes, "Random")
+
+	var modes []string
+	for _, m := range attackModes {
+		if *m.enabled {
+			modes = append(modes, m.name)
+		}
 	}
 	fmt.Printf("Modes: %s\n", strings.Join(modes, " + "))
-	fmt.Printf("Workers: %d per mode\n", workers)
+	fmt.Printf("Workers: %d shared%s\n", workers, weightSummary())
+	warnKeyspace()
 
 	var gpuCracker *gpu.GPUCracker
 	if useGPU {
@@ -238,28 +400,23 @@ func runCracker(cmd *cobra.Command, args []string) {
 	}
 
 	fmt.Println()
-	fmt.Println("Press Ctrl+C to stop.")
+	fmt.Println(controlHelp)
 	fmt.Println()
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
-	go func() {
-		<-sigChan
-		fmt.Println("\nStopping...")
-		cancel()
-	}()
+	gate := cracker.NewGate()
+	ctl := startControl(gate, cancel)
+	defer ctl.Stop()
 
 	resultChan := make(chan attackResult, 3)
 	var wg sync.WaitGroup
 
 	statusMu := sync.Mutex{}
-	statuses := map[string]*modeStatus{
-		"W": {active: useWordlist},
-		"I": {active: useIncremental},
-		"R": {active: useRandom},
+	statuses := map[string]*modeStatus{}
+	for _, m := range attackModes {
+		statuses[m.key] = newModeStatus(m.key, *m.enabled)
 	}
 
 	updateStatus := func(mode string, attempts uint64, rate float64, current string) {
@@ -275,6 +432,21 @@ func runCracker(cmd *cobra.Command, args []string) {
 	stopDisplay := make(chan struct{})
 	startTime := time.Now()
 
+	printStatus := func() {
+		statusMu.Lock()
+		defer statusMu.Unlock()
+		fmt.Printf("Elapsed: %s", formatDuration(time.Since(startTime)))
+		if gate.Paused() {
+			fmt.Print(" (paused)")
+		}
+		fmt.Println()
+		for _, m := range attackModes {
+			if s := statuses[m.key]; s.active {
+				fmt.Printf("  %s: %d attempts, %s, current %q\n", m.name, s.offset+s.attempts, s.summary(), s.current)
+			}
+		}
+	}
+
 	go func() {
 		ticker := time.NewTicker(500 * time.Millisecond)
 		defer ticker.Stop()
@@ -283,20 +455,23 @@ func runCracker(cmd *cobra.Command, args []string) {
 			select {
 			case <-stopDisplay:
 				return
+			case key := <-ctl.Keys():
+				ctl.HandleKey(key, printStatus)
 			case <-ticker.C:
 				statusMu.Lock()
 				elapsed := time.Since(startTime)
 				line := fmt.Sprintf("\r[%s] ", formatDuration(elapsed))
+				if gate.Paused() {
+					line += "PAUSED "
+				}
 				
 				parts := []string{}
-				modeKeys := []string{"W", "I", "R"}
-				modeNames := []string{"Wordlist", "Incremental", "Random"}
 				
-				for i, key := range modeKeys {
-					s := statuses[key]
+				for _, m := range attackModes {
+					s := statuses[m.key]
 					if s.active {
-						parts = append(parts, fmt.Sprintf("%s: %d @ %.0f/s [%s]",
-							modeNames[i], s.attempts, s.rate, truncate(s.current, 10)))
+						parts = append(parts, fmt.Sprintf("%s: %s [%s]",
+							m.name, s.summary(), truncate(s.current, 10)))
 					}
 				}
 				
@@ -307,11 +482,12 @@ func runCracker(cmd *cobra.Command, args []string) {
 		}
 	}()
 
-	if useWordlist {
+	gpuWordlist := useWordlist && useGPU && gpuCracker != nil
+	if gpuWordlist {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			result := runWordlistAttack(ctx, encInfo, gpuCracker, updateStatus)
+			result := runGPUWordlistAttack(ctx, gpuCracker, gate, statuses["W"].wordlist, updateStatus)
 			resultChan <- attackResult{mode: "Wordlist", result: result}
 			if result.Found {
 				cancel()
@@ -319,26 +495,30 @@ func runCracker(cmd *cobra.Command, args []string) {
 		}()
 	}
 
-	if useIncremental {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			result := runIncrementalAttack(ctx, encInfo, updateStatus)
-			resultChan <- attackResult{mode: "Incremental", result: result}
-			if result.Found {
-				cancel()
-			}
-		}()
+	var scheduled []string
+	sched := cracker.NewScheduler(workers)
+	sched.SetGate(gate)
+	sched.SetProgressCallback(func(mode string, p cracker.Progress) {
+		updateStatus(modeKey(mode), p.Attempts, p.Rate, p.Current)
+	})
+	for _, m := range attackModes {
+		if !*m.enabled || (m.key == "W" && gpuWordlist) {
+			continue
+		}
+		sched.AddSource(cracker.Source{Name: m.name, Weight: modeWeight(m.key), Generate: m.generator(statuses[m.key])})
+		scheduled = append(scheduled, m.name)
 	}
 
-	if useRandom {
+	if len(scheduled) > 0 {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			result := runRandomAttack(ctx, encInfo, updateStatus)
-			resultChan <- attackResult{mode: "Random", result: result}
-			if result.Found {
-				cancel()
+			results := sched.Crack(ctx, encInfo)
+			for i, result := range results {
+				resultChan <- attackResult{mode: scheduled[i], result: result}
+				if result.Found {
+					cancel()
+				}
 			}
 		}()
 	}
@@ -355,9 +535,11 @@ func runCracker(cmd *cobra.Command, args []string) {
 	for res := range resultChan {
 		allResults = append(allResults, res)
 		if res.result.Found && foundResult == nil {
-			foundResult = &res
+			found := res
+			foundResult = &found
 		}
 	}
+	ctl.Stop()
 
 	fmt.Println()
 	fmt.Println()
@@ -367,8 +549,18 @@ func runCracker(cmd *cobra.Command, args []string) {
 		fmt.Printf("PASSWORD FOUND: %s\n", foundResult.result.Password)
 		fmt.Printf("Found by: %s\n", foundResult.mode)
 		fmt.Printf("Time: %s\n", formatDuration(foundResult.result.Duration))
+		savePotfile(pot, encInfo, foundResult.result.Password)
+		clearSession()
+	} else if ctl.Checkpoint() {
+		fmt.Println("Password not found yet.")
+		positions := map[string]uint64{}
+		for _, res := range allResults {
+			positions[modeKey(res.mode)] = checkpointPosition(modeKey(res.mode), res.result.Attempts)
+		}
+		saveCheckpoint(positions)
 	} else {
 		fmt.Println("Password not found.")
+		clearSession()
 	}
 
 	fmt.Println()
@@ -382,80 +574,225 @@ func runCracker(cmd *cobra.Command, args []string) {
 	fmt.Printf("  Total: %d attempts\n", totalAttempts)
 }
 
-func runWordlistAttack(ctx context.Context, encInfo *pdf.EncryptionInfo, gpuCracker *gpu.GPUCracker, updateStatus func(string, uint64, float64, string)) cracker.Result {
-	c := cracker.New(encInfo, workers)
-
-	c.SetProgressCallback(func(p cracker.Progress) {
-		updateStatus("W", p.Attempts, p.Rate, p.Current)
-	})
-
-	if useGPU && gpuCracker != nil {
-		return crackWithGPU(ctx, gpuCracker, wordlist, updateStatus)
-	}
-
-	passwords, err := attacks.WordlistGenerator(ctx, wordlist)
+func runGPUWordlistAttack(ctx context.Context, gpuCracker *gpu.GPUCracker, gate *cracker.Gate, progress *attacks.WordlistProgress, updateStatus func(string, uint64, float64, string)) cracker.Result {
+	passwords, err := openWordlist(ctx, progress)
 	if err != nil {
+		fmt.Fprintf(os.Stderr, "\nWordlist: %v\n", err)
 		updateStatus("W", 0, 0, "ERROR")
 		return cracker.Result{}
 	}
+	return crackWithGPU(ctx, gpuCracker, passwords, gate, updateStatus)
+}
 
-	return c.CrackWithWordlist(ctx, passwords)
+// lengthLimits are the length settings fitLengths works on, taken from the
+// flags or from a plan stage.
+type lengthLimits struct {
+	lengths   bool
+	minLength int
+	maxLength int
+	mask      *attacks.MaskConfig
 }
 
-func runIncrementalAttack(ctx context.Context, encInfo *pdf.EncryptionInfo, updateStatus func(string, uint64, float64, string)) cracker.Result {
-	c := cracker.New(encInfo, workers)
+// fitFlagLengths fits the length flags of a normal run.
+func fitFlagLengths(significant int) error {
+	limits := lengthLimits{lengths: useIncremental || useRandom || useMarkov, minLength: minLength, maxLength: maxLength}
+	if useMask {
+		config := maskConfig()
+		limits.mask = &config
+	}
+	if err := fitLengths(&limits, significant); err != nil {
+		return err
+	}
+	maxLength = limits.maxLength
+	if limits.mask != nil {
+		incrementMax = limits.mask.IncrementMax
+	}
+	return nil
+}
 
-	charsetStr := resolveCharset(charset)
-	config := attacks.IncrementalConfig{
-		Charset:   charsetStr,
+// fitLengths lowers length limits that go past the password bytes the
+// targets look at, since longer candidates only repeat shorter ones, and
+// refuses settings that leave nothing new to try.
+func fitLengths(limits *lengthLimits, significant int) error {
+	why := fmt.Sprintf("R2-R4 PDFs use only the first %d bytes of a password", significant)
+	if significant > 32 {
+		why = fmt.Sprintf("R5/R6 PDFs use only the first %d bytes of a password", significant)
+	}
+
+	if limits.lengths {
+		if limits.minLength > significant {
+			return fmt.Errorf("minimum length %d is too long: %s", limits.minLength, why)
+		}
+		if limits.maxLength > significant {
+			fmt.Fprintf(os.Stderr, "Warning: maximum length %d lowered to %d; %s\n", limits.maxLength, significant, why)
+			limits.maxLength = significant
+		}
+	}
+
+	if config := limits.mask; config != nil {
+		charsets, err := attacks.ParseMask(config.Mask, config.Custom)
+		if err != nil || len(charsets) <= significant {
+			return err
+		}
+		if !config.Increment {
+			return fmt.Errorf("mask has %d positions but %s; shorten it to %d", len(charsets), why, significant)
+		}
+		if config.IncrementMin > significant {
+			return fmt.Errorf("mask increment minimum %d is too long: %s", config.IncrementMin, why)
+		}
+		if config.IncrementMax == 0 || config.IncrementMax > significant {
+			fmt.Fprintf(os.Stderr, "Warning: mask increment stops at %d positions; %s\n", significant, why)
+			config.IncrementMax = significant
+		}
+	}
+	return nil
+}
+
+func incrementalConfig() attacks.IncrementalConfig {
+	return attacks.IncrementalConfig{
+		Charset:   attacks.ResolveCharset(charset),
 		MinLength: minLength,
 		MaxLength: maxLength,
 	}
+}
 
-	c.SetProgressCallback(func(p cracker.Progress) {
-		updateStatus("I", p.Attempts, p.Rate, p.Current)
-	})
+func incrementalGenerator() func(ctx context.Context) <-chan string {
+	config := incrementalConfig()
+
+	if start := resumePositions["I"]; start > 0 {
+		ks := attacks.NewIncrementalKeyspace(config)
+		return func(ctx context.Context) <-chan string {
+			return attacks.KeyspaceGenerator(ctx, ks, start, ks.Size())
+		}
+	}
 
-	generator := func(ctx context.Context) <-chan string {
+	return func(ctx context.Context) <-chan string {
 		return attacks.IncrementalGenerator(ctx, config)
 	}
+}
 
-	return c.CrackWithGenerator(ctx, generator)
+func openWordlist(ctx context.Context, progress *attacks.WordlistProgress) (<-chan string, error) {
+	sources, err := attacks.ExpandWordlists(wordlistArgs)
+	if err != nil {
+		return nil, err
+	}
+	skip := resumeWordlistPositions
+	if skip == nil && len(sources) > 0 {
+		// Sessions from before per-source positions had one plain file.
+		skip = map[string]uint64{sources[0]: resumePositions["W"]}
+	}
+	norm, err := newNormalizer()
+	if err != nil {
+		return nil, err
+	}
+	wordlistProgress = progress
+	shards := attacks.ShardConfig{Shards: wordlistShards, Mmap: useMmap}
+	words, err := attacks.WordlistSourcesGenerator(ctx, sources, skip, progress, norm, shards)
+	if err != nil {
+		return nil, err
+	}
+	v, err := newVariants()
+	if err != nil {
+		return nil, err
+	}
+	if v != nil {
+		variantProgress = &attacks.VariantProgress{}
+		return attacks.VariantGenerator(ctx, words, ruleEngine, v, variantProgress), nil
+	}
+	if ruleEngine == nil {
+		return words, nil
+	}
+	return ruleEngine.Generator(ctx, words), nil
 }
 
-func runRandomAttack(ctx context.Context, encInfo *pdf.EncryptionInfo, updateStatus func(string, uint64, float64, string)) cracker.Result {
-	c := cracker.New(encInfo, workers)
+func newNormalizer() (*attacks.Normalizer, error) {
+	return attacks.NewNormalizer(attacks.NormalizeConfig{
+		Encoding: wordlistEncoding,
+		Dedupe:   dedupeWords,
+	})
+}
 
-	charsetStr := resolveCharset(charset)
-	config := attacks.RandomConfig{
-		Charset:   charsetStr,
-		MinLength: minLength,
-		MaxLength: maxLength,
+func loadRules() error {
+	var err error
+	ruleEngine, err = loadRuleEngine(ruleFiles)
+	return err
+}
+
+// loadRuleEngine stacks the named rule files, or returns nil for none.
+func loadRuleEngine(names []string) (*rules.Engine, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	var sets [][]rules.Rule
+	for _, name := range names {
+		set, err := rules.Load(name)
+		if err != nil {
+			return nil, fmt.Errorf("rules: %w", err)
+		}
+		sets = append(sets, set)
 	}
+	return rules.NewEngine(sets...), nil
+}
 
-	c.SetProgressCallback(func(p cracker.Progress) {
-		updateStatus("R", p.Attempts, p.Rate, p.Current)
-	})
+// checkpointPosition converts a mode's attempts into the position its
+// generator resumes from. With rules, wordlist and keyboard positions count
+// words and walks, and rounding down means a resumed run repeats part of a
+// word rather than skipping any.
+func checkpointPosition(key string, attempts uint64) uint64 {
+	if key == "W" && variantProgress != nil {
+		return variantProgress.Words(attempts)
+	}
+	if (key == "W" || key == "B") && ruleEngine != nil {
+		return attempts / ruleEngine.Size()
+	}
+	if key == "C" && (leftRuleFiles != nil || rightRuleFiles != nil) {
+		// Rejected candidates still take a position, so round down to the
+		// start of a left word.
+		if c, err := newCombinator(); err == nil && c.PerWord() > 0 {
+			return attempts / c.PerWord() * c.PerWord()
+		}
+	}
+	return attempts
+}
 
-	generator := func(ctx context.Context) <-chan string {
-		return attacks.RandomGenerator(ctx, config)
+func wordlistGenerator(progress *attacks.WordlistProgress) func(ctx context.Context) <-chan string {
+	return func(ctx context.Context) <-chan string {
+		passwords, err := openWordlist(ctx, progress)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "\nWordlist: %v\n", err)
+			closed := make(chan string)
+			close(closed)
+			return closed
+		}
+		return passwords
 	}
+}
 
-	return c.CrackWithGenerator(ctx, generator)
+func randomConfig() attacks.RandomConfig {
+	return attacks.RandomConfig{
+		Charset:   attacks.ResolveCharset(charset),
+		MinLength: minLength,
+		MaxLength: maxLength,
+		Seed:      randomSeed,
+	}
 }
 
-func crackWithGPU(ctx context.Context, gpuCracker *gpu.GPUCracker, wordlistFile string, updateStatus func(string, uint64, float64, string)) cracker.Result {
+func randomGenerator() func(ctx context.Context) <-chan string {
+	config := randomConfig()
+	start := resumePositions["R"]
+	return func(ctx context.Context) <-chan string {
+		return attacks.RandomKeyspaceGenerator(ctx, config, start)
+	}
+}
+
+func crackWithGPU(ctx context.Context, gpuCracker *gpu.GPUCracker, passwords <-chan string, gate *cracker.Gate, updateStatus func(string, uint64, float64, string)) cracker.Result {
 	start := time.Now()
 	var attempts uint64
 
-	passwords, err := attacks.WordlistGenerator(ctx, wordlistFile)
-	if err != nil {
-		return cracker.Result{Duration: time.Since(start)}
-	}
-
 	batch := make([]string, 0, batchSize)
 
 	for {
+		gate.Wait(ctx)
 		select {
 		case <-ctx.Done():
 			return cracker.Result{
@@ -502,27 +839,6 @@ func crackWithGPU(ctx context.Context, gpuCracker *gpu.GPUCracker, wordlistFile
 	}
 }
 
-func resolveCharset(cs string) string {
-	switch strings.ToLower(cs) {
-	case "lower":
-		return attacks.CharsetLower
-	case "upper":
-		return attacks.CharsetUpper
-	case "digits", "numbers":
-		return attacks.CharsetDigits
-	case "alpha":
-		return attacks.CharsetAlpha
-	case "alnum", "alphanumeric":
-		return attacks.CharsetAlphaNum
-	case "all", "full":
-		return attacks.CharsetAll
-	case "special":
-		return attacks.CharsetSpecial
-	default:
-		return cs
-	}
-}
-
 func formatDuration(d time.Duration) string {
 	if d < time.Minute {
 		return fmt.Sprintf("%ds", int(d.Seconds()))
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/lth/pdfcrack/internal/attacks"
+	"github.com/spf13/cobra"
+)
+
+var (
+	useMarkov       bool
+	markovThreshold int
+	markovStats     string
+	markovOutput    string
+)
+
+func addMarkovFlags(cmd *cobra.Command) {
+	cmd.Flags().IntVar(&markovThreshold, "markov-threshold", 0, "Markov mode: try only the N likeliest characters per position (0 = all)")
+	cmd.Flags().StringVar(&markovStats, "markov-stats", "", "Markov statistics from 'pdfcrack markov train' (default built-in)")
+}
+
+func newMarkovCmd() *cobra.Command {
+	markovCmd := &cobra.Command{
+		Use:   "markov",
+		Short: "Manage Markov statistics",
+	}
+	trainCmd := &cobra.Command{
+		Use:   "train WORDLIST",
+		Short: "Build Markov statistics from a list of passwords",
+		Args:  cobra.ExactArgs(1),
+		Run:   runMarkovTrain,
+	}
+	trainCmd.Flags().StringVarP(&markovOutput, "output", "o", "", "Statistics file to write (required)")
+	trainCmd.MarkFlagRequired("output")
+	markovCmd.AddCommand(trainCmd)
+	return markovCmd
+}
+
+func runMarkovTrain(cmd *cobra.Command, args []string) {
+	in, err := os.Open(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer in.Close()
+
+	stats, words, err := attacks.TrainMarkov(bufio.NewReader(in))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s: %v\n", args[0], err)
+		os.Exit(1)
+	}
+
+	out, err := os.Create(markovOutput)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := stats.Write(out); err != nil {
+		out.Close()
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := out.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Trained on %d passwords, wrote %s\n", words, markovOutput)
+}
+
+func newMarkovKeyspace() (*attacks.MarkovKeyspace, error) {
+	stats, err := attacks.LoadMarkovStats(markovStats)
+	if err != nil {
+		return nil, err
+	}
+	config := incrementalConfig()
+	return attacks.NewMarkovKeyspace(attacks.MarkovConfig{
+		Charset:   config.Charset,
+		MinLength: config.MinLength,
+		MaxLength: config.MaxLength,
+		Threshold: markovThreshold,
+		Stats:     stats,
+	}), nil
+}
+
+func markovGenerator() func(ctx context.Context) <-chan string {
+	start := resumePositions["K"]
+	return func(ctx context.Context) <-chan string {
+		ks, err := newMarkovKeyspace()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "\nMarkov: %v\n", err)
+			closed := make(chan string)
+			close(closed)
+			return closed
+		}
+		return ks.Generator(ctx, start, ks.Size())
+	}
+}
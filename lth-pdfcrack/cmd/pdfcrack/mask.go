@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+
+	"github.com/lth/pdfcrack/internal/attacks"
+	"github.com/spf13/cobra"
+)
+
+var (
+	useMask        bool
+	mask           string
+	customCharsets [4]string
+	increment      bool
+	incrementMin   int
+	incrementMax   int
+
+	useHybrid       bool
+	hybridMaskFirst bool
+)
+
+func addMaskFlags(cmd *cobra.Command) {
+	for i := range customCharsets {
+		n := strconv.Itoa(i + 1)
+		cmd.Flags().StringVarP(&customCharsets[i], "custom-charset"+n, n, "", "User charset for ?"+n+" in masks, e.g. '?l?d' or 'abc123'")
+	}
+	cmd.Flags().BoolVar(&increment, "increment", false, "Grow the mask one position at a time")
+	cmd.Flags().IntVar(&incrementMin, "increment-min", 0, "Shortest mask prefix tried with --increment (default 1)")
+	cmd.Flags().IntVar(&incrementMax, "increment-max", 0, "Longest mask prefix tried with --increment (default the whole mask)")
+}
+
+func maskConfig() attacks.MaskConfig {
+	return attacks.MaskConfig{
+		Mask:         mask,
+		Custom:       customCharsets,
+		Increment:    increment,
+		IncrementMin: incrementMin,
+		IncrementMax: incrementMax,
+	}
+}
+
+func maskGenerator() func(ctx context.Context) <-chan string {
+	start := resumePositions["M"]
+	return func(ctx context.Context) <-chan string {
+		ks, err := attacks.NewMaskKeyspace(maskConfig())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "\nMask: %v\n", err)
+			closed := make(chan string)
+			close(closed)
+			return closed
+		}
+		return attacks.KeyspaceGenerator(ctx, ks, start, ks.Size())
+	}
+}
+
+// customCharsetList returns the -1..-4 charsets without trailing unset ones,
+// the form sessions and distributed jobs store them in.
+func customCharsetList() []string {
+	n := len(customCharsets)
+	for n > 0 && customCharsets[n-1] == "" {
+		n--
+	}
+	if n == 0 {
+		return nil
+	}
+	return append([]string(nil), customCharsets[:n]...)
+}
+
+func newHybrid() (*attacks.Hybrid, error) {
+	return attacks.NewHybrid(wordlist, maskConfig(), hybridMaskFirst)
+}
+
+func hybridGenerator(progress *attacks.WordlistProgress) func(ctx context.Context) <-chan string {
+	start := resumePositions["H"]
+	return func(ctx context.Context) <-chan string {
+		h, err := newHybrid()
+		if err == nil {
+			var ch <-chan string
+			if ch, err = h.Generator(ctx, start, math.MaxUint64, progress); err == nil {
+				return ch
+			}
+		}
+		fmt.Fprintf(os.Stderr, "\nHybrid: %v\n", err)
+		closed := make(chan string)
+		close(closed)
+		return closed
+	}
+}
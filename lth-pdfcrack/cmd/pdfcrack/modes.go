@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/lth/pdfcrack/internal/attacks"
+)
+
+// attackMode is one row of the mode table. The key names the mode in
+// --weights and checkpoints, name in status lines and summaries.
+type attackMode struct {
+	key     string
+	name    string
+	enabled *bool
+	// weight is the mode's share of the workers unless --weights sets one.
+	weight int
+	// setup fills in what status needs to show the mode's progress.
+	setup     func(s *modeStatus)
+	generator func(s *modeStatus) func(ctx context.Context) <-chan string
+	// note replaces the usual warning when the keyspace overflows.
+	note string
+}
+
+// attackModes lists the modes in the order they are shown.
+var attackModes = []attackMode{
+	{key: "V", name: "Harvest", enabled: &useHarvest, weight: 70,
+		setup: func(s *modeStatus) {
+			s.keyspace = uint64(len(harvestCandidates))
+			if harvestVariants != nil {
+				s.keyspace, s.overflow = harvestVariants.Size(), harvestVariants.Overflow()
+			}
+		},
+		generator: func(*modeStatus) func(ctx context.Context) <-chan string { return harvestGenerator() }},
+	{key: "W", name: "Wordlist", enabled: &useWordlist, weight: 70,
+		setup: func(s *modeStatus) {
+			s.wordlist = &attacks.WordlistProgress{}
+			if ruleEngine != nil {
+				s.perWord = ruleEngine.Size()
+			}
+		},
+		generator: func(s *modeStatus) func(ctx context.Context) <-chan string { return wordlistGenerator(s.wordlist) }},
+	{key: "C", name: "Combinator", enabled: &useCombinator, weight: 70,
+		setup: func(s *modeStatus) {
+			s.wordlist = &attacks.WordlistProgress{}
+			if c, err := newCombinator(); err == nil {
+				s.perWord = c.PerWord()
+			}
+		},
+		generator: func(s *modeStatus) func(ctx context.Context) <-chan string { return combinatorGenerator(s.wordlist) }},
+	{key: "H", name: "Hybrid", enabled: &useHybrid, weight: 70,
+		setup: func(s *modeStatus) {
+			s.wordlist = &attacks.WordlistProgress{}
+			s.exact = true
+			if h, err := newHybrid(); err == nil {
+				s.perWord = h.PerWord()
+			}
+		},
+		generator: func(s *modeStatus) func(ctx context.Context) <-chan string { return hybridGenerator(s.wordlist) }},
+	{key: "M", name: "Mask", enabled: &useMask, weight: 70,
+		setup: func(s *modeStatus) {
+			if ks, err := attacks.NewMaskKeyspace(maskConfig()); err == nil {
+				s.keyspace, s.overflow = ks.Size(), ks.Overflow()
+			}
+		},
+		generator: func(*modeStatus) func(ctx context.Context) <-chan string { return maskGenerator() }},
+	{key: "T", name: "Template", enabled: &useTemplate, weight: 70,
+		setup: func(s *modeStatus) {
+			if ks, err := newTemplateKeyspace(); err == nil {
+				s.keyspace = ks.Size()
+			}
+		},
+		generator: func(*modeStatus) func(ctx context.Context) <-chan string { return templateGenerator() }},
+	{key: "D", name: "Pattern", enabled: &usePattern, weight: 70,
+		setup: func(s *modeStatus) {
+			if ks, err := newPatternKeyspace(); err == nil {
+				s.keyspace, s.overflow = ks.Size(), ks.Overflow()
+			}
+		},
+		generator: func(*modeStatus) func(ctx context.Context) <-chan string { return patternGenerator() }},
+	{key: "B", name: "Keyboard", enabled: &useKeyboard, weight: 70,
+		setup: func(s *modeStatus) {
+			if ks, err := newKeyboardKeyspace(); err == nil {
+				s.keyspace, s.overflow = ks.Size(), ks.Overflow()
+				if ruleEngine != nil {
+					// Positions count walks; attempts count candidates.
+					s.offset = mulSaturating(s.offset, ruleEngine.Size())
+					s.keyspace = mulSaturating(s.keyspace, ruleEngine.Size())
+					s.overflow = s.overflow || s.keyspace == math.MaxUint64
+				}
+			}
+		},
+		generator: func(*modeStatus) func(ctx context.Context) <-chan string { return keyboardGenerator() }},
+	{key: "K", name: "Markov", enabled: &useMarkov, weight: 20,
+		setup: func(s *modeStatus) {
+			if ks, err := newMarkovKeyspace(); err == nil {
+				s.keyspace, s.overflow = ks.Size(), ks.Overflow()
+			}
+		},
+		generator: func(*modeStatus) func(ctx context.Context) <-chan string { return markovGenerator() }},
+	{key: "G", name: "PCFG", enabled: &usePCFG, weight: 20,
+		setup: func(s *modeStatus) {
+			if p, err := newPCFG(); err == nil {
+				s.keyspace, s.overflow = p.Size(), p.Overflow()
+			}
+		},
+		generator: func(*modeStatus) func(ctx context.Context) <-chan string { return pcfgGenerator() }},
+	{key: "E", name: "PRINCE", enabled: &usePrince, weight: 20,
+		setup: func(s *modeStatus) {
+			if ks, err := newPrinceKeyspace(); err == nil {
+				s.keyspace, s.overflow = ks.Selected(), ks.Overflow()
+			}
+		},
+		generator: func(*modeStatus) func(ctx context.Context) <-chan string { return princeGenerator() }},
+	{key: "S", name: "Passphrase", enabled: &usePassphrase, weight: 20,
+		setup: func(s *modeStatus) {
+			if ks, err := newPassphraseKeyspace(); err == nil {
+				s.keyspace, s.overflow = ks.Size(), ks.Overflow()
+			}
+		},
+		generator: func(*modeStatus) func(ctx context.Context) <-chan string { return passphraseGenerator() }},
+	{key: "I", name: "Incremental", enabled: &useIncremental, weight: 20,
+		setup: func(s *modeStatus) {
+			s.keyspace, s.overflow = attacks.EstimateCombinationsChecked(incrementalConfig())
+		},
+		generator: func(*modeStatus) func(ctx context.Context) <-chan string { return incrementalGenerator() }},
+	{key: "R", name: "Random", enabled: &useRandom, weight: 10,
+		setup: func(s *modeStatus) {
+			ks := attacks.NewRandomKeyspace(randomConfig())
+			s.keyspace, s.overflow = ks.Size(), ks.Overflow()
+		},
+		generator: func(*modeStatus) func(ctx context.Context) <-chan string { return randomGenerator() },
+		note:      "candidates are drawn independently and may repeat"},
+}
+
+func lookupMode(key string) (attackMode, bool) {
+	for _, m := range attackModes {
+		if m.key == key {
+			return m, true
+		}
+	}
+	return attackMode{}, false
+}
+
+// modeKey maps a mode name such as "Wordlist", or a key such as "w", to the
+// mode's key.
+func modeKey(mode string) string {
+	for _, m := range attackModes {
+		if strings.EqualFold(mode, m.key) || strings.EqualFold(mode, m.name) {
+			return m.key
+		}
+	}
+	return mode
+}
+
+func modeName(key string) string {
+	if m, ok := lookupMode(key); ok {
+		return m.name
+	}
+	return key
+}
+
+func modeEnabled(key string) bool {
+	m, ok := lookupMode(key)
+	return ok && *m.enabled
+}
+
+func anyModeEnabled() bool {
+	for _, m := range attackModes {
+		if *m.enabled {
+			return true
+		}
+	}
+	return false
+}
+
+func modeWeight(key string) int {
+	for name, weight := range modeWeights {
+		if modeKey(name) == key {
+			return weight
+		}
+	}
+	m, _ := lookupMode(key)
+	return m.weight
+}
+
+func checkWeights() error {
+	for name, weight := range modeWeights {
+		if name == "" {
+			return fmt.Errorf("--weights: empty mode name")
+		}
+		if _, ok := lookupMode(modeKey(name)); !ok {
+			keys := make([]string, len(attackModes))
+			for i, m := range attackModes {
+				keys[i] = m.key
+			}
+			return fmt.Errorf("--weights: unknown mode %q (use %s or a mode name)", name, strings.Join(keys, ", "))
+		}
+		if weight <= 0 {
+			return fmt.Errorf("--weights: weight for %s must be positive", name)
+		}
+	}
+	return nil
+}
+
+func weightSummary() string {
+	var parts []string
+	for _, m := range attackModes {
+		if *m.enabled {
+			parts = append(parts, fmt.Sprintf("%s=%d", m.key, modeWeight(m.key)))
+		}
+	}
+	if len(parts) < 2 {
+		return ""
+	}
+	return " (weights " + strings.Join(parts, ",") + ")"
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lth/pdfcrack/internal/cracker"
+	"github.com/lth/pdfcrack/internal/pdf"
+	"github.com/lth/pdfcrack/internal/potfile"
+)
+
+func loadTargets() ([]cracker.Target, error) {
+	var targets []cracker.Target
+
+	for _, path := range pdfFiles {
+		st, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+
+		if !st.IsDir() {
+			encInfo, err := pdf.ExtractEncryptionInfo(path)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			targets = append(targets, cracker.Target{Name: path, Info: encInfo})
+			continue
+		}
+
+		err = filepath.WalkDir(path, func(file string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() || !strings.EqualFold(filepath.Ext(file), ".pdf") {
+				return nil
+			}
+			encInfo, err := pdf.ExtractEncryptionInfo(file)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Skipping %s: %v\n", file, err)
+				return nil
+			}
+			targets = append(targets, cracker.Target{Name: file, Info: encInfo})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if hashFile != "" {
+		hashTargets, err := loadHashFile(hashFile)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, hashTargets...)
+	}
+
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no encrypted PDFs found")
+	}
+	return targets, nil
+}
+
+func loadHashFile(filename string) ([]cracker.Target, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var targets []cracker.Target
+	scanner := bufio.NewScanner(f)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		name, encInfo, err := pdf.ParseHash(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", filename, lineNum, err)
+		}
+		if name == "" {
+			name = fmt.Sprintf("%s:%d", filename, lineNum)
+		}
+		targets = append(targets, cracker.Target{Name: name, Info: encInfo})
+	}
+
+	return targets, scanner.Err()
+}
+
+func runMultiCracker(targets []cracker.Target) {
+	fmt.Printf("Targets: %d\n", len(targets))
+
+	pot := openPotfile()
+	pending := filterPotfile(pot, targets)
+	if len(pending) == 0 {
+		fmt.Println("All targets are in the potfile.")
+		return
+	}
+	set := newTargetSet(pot, pending)
+
+	var modes []string
+	var keys []string
+	statuses := map[string]*modeStatus{}
+	generators := map[string]func(ctx context.Context) <-chan string{}
+	for _, m := range attackModes {
+		if !*m.enabled {
+			continue
+		}
+		modes = append(modes, m.name)
+		keys = append(keys, m.key)
+		statuses[m.key] = newModeStatus(m.key, true)
+		generators[m.key] = m.generator(statuses[m.key])
+	}
+	fmt.Printf("Modes: %s\n", strings.Join(modes, " + "))
+	fmt.Printf("Workers: %d shared%s\n", workers, weightSummary())
+	warnKeyspace()
+	if useGPU {
+		fmt.Println("GPU: not used for multi-target runs")
+	}
+
+	fmt.Println()
+	fmt.Println(controlHelp)
+	fmt.Println()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	gate := cracker.NewGate()
+	ctl := startControl(gate, cancel)
+	defer ctl.Stop()
+
+	var statusMu sync.Mutex
+
+	stopDisplay := make(chan struct{})
+	startTime := time.Now()
+
+	printStatus := func() {
+		statusMu.Lock()
+		defer statusMu.Unlock()
+		fmt.Printf("Elapsed: %s, cracked %d/%d", formatDuration(time.Since(startTime)), set.Unique()-set.Remaining(), set.Unique())
+		if gate.Paused() {
+			fmt.Print(" (paused)")
+		}
+		fmt.Println()
+		for i, key := range keys {
+			s := statuses[key]
+			fmt.Printf("  %s: %d candidates, %s\n", modes[i], s.offset+s.attempts, s.summary())
+		}
+	}
+
+	go func() {
+		ticker := time.NewTicker(500 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stopDisplay:
+				return
+			case key := <-ctl.Keys():
+				ctl.HandleKey(key, printStatus)
+			case <-ticker.C:
+				statusMu.Lock()
+				parts := []string{fmt.Sprintf("Cracked %d/%d", set.Unique()-set.Remaining(), set.Unique())}
+				if gate.Paused() {
+					parts[0] = "PAUSED " + parts[0]
+				}
+				for i, key := range keys {
+					s := statuses[key]
+					parts = append(parts, fmt.Sprintf("%s: %s", modes[i], s.summary()))
+				}
+				statusMu.Unlock()
+				fmt.Printf("%-120s", fmt.Sprintf("\r[%s] %s", formatDuration(time.Since(startTime)), strings.Join(parts, " | ")))
+			}
+		}
+	}()
+
+	sched := cracker.NewScheduler(workers)
+	sched.SetGate(gate)
+	sched.SetProgressCallback(func(mode string, p cracker.Progress) {
+		statusMu.Lock()
+		statuses[modeKey(mode)].attempts = p.Attempts
+		statuses[modeKey(mode)].rate = p.Rate
+		statusMu.Unlock()
+	})
+	for i, key := range keys {
+		sched.AddSource(cracker.Source{Name: modes[i], Weight: modeWeight(key), Generate: generators[key]})
+	}
+	results := sched.CrackSet(ctx, set)
+	close(stopDisplay)
+	ctl.Stop()
+
+	fmt.Println()
+	fmt.Println()
+	fmt.Println("================================")
+
+	printTargetSummary(pending, set)
+
+	if set.Remaining() > 0 && ctl.Checkpoint() {
+		positions := map[string]uint64{}
+		for i, key := range keys {
+			positions[key] = checkpointPosition(key, results[i].Attempts)
+		}
+		saveCheckpoint(positions)
+	} else {
+		clearSession()
+	}
+
+	fmt.Println()
+	fmt.Println("Statistics:")
+	var totalAttempts uint64
+	for i, res := range results {
+		rate := float64(res.Attempts) / res.Duration.Seconds()
+		fmt.Printf("  %s: %d candidates (%.0f/s)\n", modes[i], res.Attempts, rate)
+		totalAttempts += res.Attempts
+	}
+	fmt.Printf("  Total: %d candidates\n", totalAttempts)
+}
+
+func filterPotfile(pot *potfile.Potfile, targets []cracker.Target) []cracker.Target {
+	var pending []cracker.Target
+	known := 0
+	for _, t := range targets {
+		if pot != nil {
+			if password, ok := pot.Lookup(t.Info); ok {
+				fmt.Printf("  %s: %s (potfile)\n", t.Name, password)
+				known++
+				continue
+			}
+		}
+		pending = append(pending, t)
+	}
+	if known > 0 {
+		fmt.Printf("Already cracked: %d\n", known)
+	}
+	return pending
+}
+
+func newTargetSet(pot *potfile.Potfile, targets []cracker.Target) *cracker.TargetSet {
+	set := cracker.NewTargetSet(targets)
+	set.SetCrackedCallback(func(c cracker.Cracked) {
+		fmt.Printf("\r%-120s\n", fmt.Sprintf("[%s] CRACKED %s: %s", formatDuration(c.Duration), c.Target.Name, c.Password))
+		savePotfile(pot, c.Target.Info, c.Password)
+	})
+	return set
+}
+
+func printTargetSummary(pending []cracker.Target, set *cracker.TargetSet) {
+	cracked := map[string]string{}
+	for _, c := range set.Cracked() {
+		cracked[c.Target.Name] = c.Password
+	}
+	names := make([]string, 0, len(pending))
+	for _, t := range pending {
+		names = append(names, t.Name)
+	}
+	sort.Strings(names)
+
+	fmt.Printf("Cracked %d of %d targets:\n", len(cracked), len(pending))
+	for _, name := range names {
+		if password, ok := cracked[name]; ok {
+			fmt.Printf("  %s: %s\n", name, password)
+		} else {
+			fmt.Printf("  %s: not found\n", name)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/lth/pdfcrack/internal/attacks"
+	"github.com/spf13/cobra"
+)
+
+var (
+	usePassphrase    bool
+	phraseList       string
+	phraseMin        int
+	phraseMax        int
+	phraseSeparators []string
+	phraseCases      []string
+	phraseSuffixes   []string
+)
+
+func addPassphraseFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&phraseList, "phrase-list", "eff-large", "Passphrase: word list: eff-large, eff-short or a file, likeliest word first or with counts")
+	cmd.Flags().IntVar(&phraseMin, "phrase-min", 2, "Passphrase: fewest words")
+	cmd.Flags().IntVar(&phraseMax, "phrase-max", 4, "Passphrase: most words")
+	cmd.Flags().StringArrayVar(&phraseSeparators, "phrase-sep", nil, "Passphrase: separator between words (repeatable, \"\" for none; default none, - and space)")
+	cmd.Flags().StringSliceVar(&phraseCases, "phrase-case", []string{attacks.CaseLower, attacks.CaseFirst, attacks.CaseTitle}, "Passphrase: case styles: lower, first, title, upper")
+	cmd.Flags().StringArrayVar(&phraseSuffixes, "phrase-suffix", nil, "Passphrase: mask tried after each passphrase, e.g. '?d?d' or '?s' (repeatable)")
+}
+
+func newPassphraseKeyspace() (*attacks.PassphraseKeyspace, error) {
+	words, err := attacks.LoadPassphraseWords(phraseList)
+	if err != nil {
+		return nil, err
+	}
+	separators := phraseSeparators
+	if separators == nil {
+		separators = []string{"", "-", " "}
+	}
+	return attacks.NewPassphraseKeyspace(attacks.PassphraseConfig{
+		Words:      words,
+		MinWords:   phraseMin,
+		MaxWords:   phraseMax,
+		Separators: separators,
+		Cases:      phraseCases,
+		Suffixes:   phraseSuffixes,
+		Custom:     customCharsets,
+	})
+}
+
+func passphraseGenerator() func(ctx context.Context) <-chan string {
+	start := resumePositions["S"]
+	return func(ctx context.Context) <-chan string {
+		ks, err := newPassphraseKeyspace()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "\nPassphrase: %v\n", err)
+			closed := make(chan string)
+			close(closed)
+			return closed
+		}
+		return attacks.KeyspaceGenerator(ctx, ks, start, ks.Size())
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/lth/pdfcrack/internal/attacks"
+	"github.com/spf13/cobra"
+)
+
+var (
+	usePattern    bool
+	patterns      []string
+	dateFrom      string
+	dateTo        string
+	monthLangs    []string
+	patternNames  []string
+	phonePrefixes []string
+	phoneDigits   int
+	countryCode   string
+	luhnDigits    int
+	luhnPrefix    string
+)
+
+func addPatternFlags(cmd *cobra.Command) {
+	cmd.Flags().StringArrayVar(&patterns, "pattern", nil, "Pattern: dates, phones, luhn or a preset such as name4-ddmm (repeatable; default dates)")
+	cmd.Flags().StringVar(&dateFrom, "date-from", "1940", "Pattern: first date, as YYYY, YYYY-MM or YYYY-MM-DD")
+	cmd.Flags().StringVar(&dateTo, "date-to", "", "Pattern: last date (default today)")
+	cmd.Flags().StringSliceVar(&monthLangs, "months", []string{"en"}, "Pattern: languages of month names: en, de, fr, es, it, nl, pt")
+	cmd.Flags().StringArrayVar(&patternNames, "name", nil, "Pattern: name used by the presets (repeatable)")
+	cmd.Flags().StringArrayVar(&phonePrefixes, "phone-prefix", nil, "Pattern: leading digits of phone numbers, e.g. 06 (repeatable)")
+	cmd.Flags().IntVar(&phoneDigits, "phone-digits", 10, "Pattern: digits in a national phone number")
+	cmd.Flags().StringVar(&countryCode, "country-code", "", "Pattern: also try phone numbers as +CC, 00CC and CC")
+	cmd.Flags().IntVar(&luhnDigits, "luhn-digits", 10, "Pattern: digits in Luhn-checked numbers")
+	cmd.Flags().StringVar(&luhnPrefix, "luhn-prefix", "", "Pattern: leading digits of Luhn-checked numbers")
+}
+
+func newPatternKeyspace() (*attacks.PatternKeyspace, error) {
+	from, err := attacks.ParseDateBound(dateFrom, false)
+	if err != nil {
+		return nil, err
+	}
+	to := time.Now()
+	if dateTo != "" {
+		if to, err = attacks.ParseDateBound(dateTo, true); err != nil {
+			return nil, err
+		}
+	}
+	return attacks.NewPatternKeyspace(attacks.PatternConfig{
+		Patterns:      patterns,
+		From:          from,
+		To:            time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC),
+		Months:        monthLangs,
+		Names:         patternNames,
+		PhonePrefixes: phonePrefixes,
+		PhoneDigits:   phoneDigits,
+		CountryCode:   countryCode,
+		LuhnDigits:    luhnDigits,
+		LuhnPrefix:    luhnPrefix,
+	})
+}
+
+func patternGenerator() func(ctx context.Context) <-chan string {
+	start := resumePositions["D"]
+	return func(ctx context.Context) <-chan string {
+		ks, err := newPatternKeyspace()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "\nPattern: %v\n", err)
+			closed := make(chan string)
+			close(closed)
+			return closed
+		}
+		return attacks.KeyspaceGenerator(ctx, ks, start, ks.Size())
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/lth/pdfcrack/internal/attacks"
+	"github.com/spf13/cobra"
+)
+
+var (
+	usePCFG     bool
+	pcfgGrammar string
+	pcfgOutput  string
+)
+
+func addPCFGFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&pcfgGrammar, "pcfg-grammar", "", "PCFG grammar from 'pdfcrack pcfg train' (default built-in)")
+}
+
+func newPCFGCmd() *cobra.Command {
+	pcfgCmd := &cobra.Command{
+		Use:   "pcfg",
+		Short: "Manage PCFG grammars",
+	}
+	trainCmd := &cobra.Command{
+		Use:   "train CORPUS",
+		Short: "Learn a password grammar from a list of passwords",
+		Args:  cobra.ExactArgs(1),
+		Run:   runPCFGTrain,
+	}
+	trainCmd.Flags().StringVarP(&pcfgOutput, "output", "o", "", "Grammar file to write (required)")
+	trainCmd.MarkFlagRequired("output")
+	pcfgCmd.AddCommand(trainCmd)
+	return pcfgCmd
+}
+
+func runPCFGTrain(cmd *cobra.Command, args []string) {
+	in, err := os.Open(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer in.Close()
+
+	g, words, err := attacks.TrainPCFG(bufio.NewReader(in))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s: %v\n", args[0], err)
+		os.Exit(1)
+	}
+
+	out, err := os.Create(pcfgOutput)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := g.Write(out); err != nil {
+		out.Close()
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := out.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Trained on %d passwords (%d base structures), wrote %s\n", words, len(g.Structures), pcfgOutput)
+}
+
+func newPCFG() (*attacks.PCFG, error) {
+	g, err := attacks.LoadPCFGGrammar(pcfgGrammar)
+	if err != nil {
+		return nil, err
+	}
+	return attacks.NewPCFG(g), nil
+}
+
+func pcfgGenerator() func(ctx context.Context) <-chan string {
+	start := resumePositions["G"]
+	return func(ctx context.Context) <-chan string {
+		p, err := newPCFG()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "\nPCFG: %v\n", err)
+			closed := make(chan string)
+			close(closed)
+			return closed
+		}
+		return p.Generator(ctx, start)
+	}
+}
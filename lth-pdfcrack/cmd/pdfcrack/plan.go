@@ -0,0 +1,261 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lth/pdfcrack/internal/attacks"
+	"github.com/lth/pdfcrack/internal/cracker"
+	"github.com/lth/pdfcrack/internal/plan"
+)
+
+var (
+	planFile  string
+	planStage int
+)
+
+type stageResult struct {
+	stage    plan.Stage
+	attempts uint64
+	duration time.Duration
+	cracked  int
+	outcome  string
+}
+
+func runPlan(targets []cracker.Target) {
+	p, err := plan.Load(planFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: plan %s: %v\n", planFile, err)
+		os.Exit(1)
+	}
+	if planStage >= len(p.Stages) {
+		fmt.Fprintf(os.Stderr, "Error: plan %s has %d stages, checkpoint is at stage %d\n", planFile, len(p.Stages), planStage+1)
+		os.Exit(1)
+	}
+
+	significant := cracker.SignificantLength(targets)
+	for i := range p.Stages {
+		if err := fitStageLengths(&p.Stages[i], significant); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: plan %s: %s: %v\n", planFile, p.Stages[i].Name, err)
+			os.Exit(1)
+		}
+	}
+
+	if len(targets) == 1 {
+		fmt.Printf("File: %s\n", targets[0].Name)
+		fmt.Printf("Encryption: %s\n", targets[0].Info.String())
+	} else {
+		fmt.Printf("Targets: %d\n", len(targets))
+	}
+	name := p.Name
+	if name == "" {
+		name = planFile
+	}
+	fmt.Printf("Plan: %s (%d stages)\n", name, len(p.Stages))
+	fmt.Printf("Workers: %d\n", workers)
+
+	pot := openPotfile()
+	pending := filterPotfile(pot, targets)
+	if len(pending) == 0 {
+		fmt.Println("All targets are in the potfile.")
+		return
+	}
+	set := newTargetSet(pot, pending)
+
+	fmt.Println()
+	fmt.Println(controlHelp)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	gate := cracker.NewGate()
+	ctl := startControl(gate, cancel)
+	defer ctl.Stop()
+
+	startTime := time.Now()
+	var results []stageResult
+
+	for i := planStage; i < len(p.Stages); i++ {
+		stage := p.Stages[i]
+		start := uint64(0)
+		if i == planStage {
+			start = resumePositions["P"]
+		}
+
+		fmt.Println()
+		fmt.Printf("Stage %d/%d: %s (%s)", i+1, len(p.Stages), stage.Name, stage.String())
+		var budgets []string
+		if stage.MaxTime > 0 {
+			budgets = append(budgets, formatDuration(stage.MaxTime))
+		}
+		if stage.MaxAttempts > 0 {
+			budgets = append(budgets, fmt.Sprintf("%d attempts", stage.MaxAttempts))
+		}
+		if len(budgets) > 0 {
+			fmt.Printf(", budget %s", strings.Join(budgets, ", "))
+		}
+		fmt.Println()
+
+		res, err := runStage(ctx, stage, start, set, gate, ctl)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Stage %d: %v\n", i+1, err)
+			res.outcome = "error"
+		}
+		results = append(results, res)
+		fmt.Printf("\r%-120s\n", fmt.Sprintf("Stage %d/%d: %s after %d attempts in %s, cracked %d",
+			i+1, len(p.Stages), res.outcome, res.attempts, formatDuration(res.duration), res.cracked))
+
+		if ctl.Checkpoint() {
+			savePlanCheckpoint(i, start+res.attempts/stage.PerWord())
+			break
+		}
+		if set.Remaining() == 0 || (stage.StopWhen == plan.StopAny && res.cracked > 0) {
+			break
+		}
+	}
+	ctl.Stop()
+
+	fmt.Println()
+	fmt.Println("================================")
+	if len(pending) == 1 {
+		if cracked := set.Cracked(); len(cracked) > 0 {
+			fmt.Printf("PASSWORD FOUND: %s\n", cracked[0].Password)
+		} else {
+			fmt.Println("Password not found.")
+		}
+	} else {
+		printTargetSummary(pending, set)
+	}
+	if !ctl.Checkpoint() {
+		clearSession()
+	}
+
+	fmt.Println()
+	fmt.Println("Stages:")
+	var totalAttempts uint64
+	for _, r := range results {
+		fmt.Printf("  %-24s %-14s %12d attempts %8s  cracked %d\n", r.stage.Name, r.outcome, r.attempts, formatDuration(r.duration), r.cracked)
+		totalAttempts += r.attempts
+	}
+	fmt.Printf("  Total: %d attempts in %s\n", totalAttempts, formatDuration(time.Since(startTime)))
+}
+
+// fitStageLengths applies fitLengths to the stage's own length settings.
+func fitStageLengths(stage *plan.Stage, significant int) error {
+	limits := lengthLimits{minLength: stage.MinLength, maxLength: stage.MaxLength}
+	switch stage.Attack {
+	case plan.AttackIncremental, plan.AttackRandom, plan.AttackMarkov:
+		limits.lengths = true
+	case plan.AttackMask:
+		config := attacks.MaskConfig{
+			Mask:         stage.Mask,
+			Increment:    stage.Increment,
+			IncrementMin: stage.IncrementMin,
+			IncrementMax: stage.IncrementMax,
+		}
+		copy(config.Custom[:], stage.CustomCharsets)
+		limits.mask = &config
+	}
+	if err := fitLengths(&limits, significant); err != nil {
+		return err
+	}
+	stage.MaxLength = limits.maxLength
+	if limits.mask != nil {
+		stage.IncrementMax = limits.mask.IncrementMax
+	}
+	return nil
+}
+
+func runStage(ctx context.Context, stage plan.Stage, start uint64, set *cracker.TargetSet, gate *cracker.Gate, ctl *runControl) (stageResult, error) {
+	res := stageResult{stage: stage}
+
+	status := &modeStatus{active: true, offset: start * stage.PerWord()}
+	if perLine := stage.CandidatesPerLine(); perLine > 0 {
+		status.wordlist = &attacks.WordlistProgress{}
+		status.perWord = perLine
+		status.exact = stage.IsHybrid()
+	} else if size, ok := stage.Keyspace(); ok {
+		status.keyspace = size
+	}
+
+	gen, err := stage.Generator(start, status.wordlist)
+	if err != nil {
+		return res, err
+	}
+
+	stageCtx := ctx
+	if stage.MaxTime > 0 {
+		var cancel context.CancelFunc
+		stageCtx, cancel = context.WithTimeout(ctx, stage.MaxTime)
+		defer cancel()
+	}
+
+	var statusMu sync.Mutex
+	stageStart := time.Now()
+	printStatus := func() {
+		statusMu.Lock()
+		defer statusMu.Unlock()
+		fmt.Printf("Stage %s: %d attempts, %s, current %q\n", stage.Name, status.offset+status.attempts, status.summary(), status.current)
+	}
+
+	stopDisplay := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(500 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stopDisplay:
+				return
+			case key := <-ctl.Keys():
+				ctl.HandleKey(key, printStatus)
+			case <-ticker.C:
+				statusMu.Lock()
+				line := fmt.Sprintf("\r[%s] ", formatDuration(time.Since(stageStart)))
+				if gate.Paused() {
+					line += "PAUSED "
+				}
+				line += fmt.Sprintf("%s: %s [%s]", stage.Name, status.summary(), truncate(status.current, 10))
+				statusMu.Unlock()
+				fmt.Printf("%-120s", line)
+			}
+		}
+	}()
+
+	sched := cracker.NewScheduler(workers)
+	sched.SetGate(gate)
+	sched.SetProgressCallback(func(_ string, p cracker.Progress) {
+		statusMu.Lock()
+		status.attempts = p.Attempts
+		status.rate = p.Rate
+		status.current = p.Current
+		statusMu.Unlock()
+	})
+	sched.AddSource(cracker.Source{Name: stage.Name, Weight: 1, Generate: gen})
+
+	remaining := set.Remaining()
+	results := sched.CrackSet(stageCtx, set)
+	close(stopDisplay)
+
+	res.attempts = results[0].Attempts
+	res.duration = results[0].Duration
+	res.cracked = remaining - set.Remaining()
+
+	switch {
+	case set.Remaining() == 0:
+		res.outcome = "cracked"
+	case ctl.Checkpoint():
+		res.outcome = "stopped"
+	case errors.Is(stageCtx.Err(), context.DeadlineExceeded):
+		res.outcome = "time budget"
+	case stage.MaxAttempts > 0 && start*stage.PerWord()+res.attempts >= stage.MaxAttempts:
+		res.outcome = "attempt budget"
+	default:
+		res.outcome = "exhausted"
+	}
+	return res, nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/lth/pdfcrack/internal/pdf"
+	"github.com/lth/pdfcrack/internal/potfile"
+	"github.com/spf13/cobra"
+)
+
+func openPotfile() *potfile.Potfile {
+	if noPotfile {
+		return nil
+	}
+
+	path := potfilePath
+	if path == "" {
+		var err error
+		path, err = potfile.DefaultPath()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: potfile disabled: %v\n", err)
+			return nil
+		}
+	}
+
+	pot, err := potfile.Open(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: potfile disabled: %v\n", err)
+		return nil
+	}
+	return pot
+}
+
+func savePotfile(pot *potfile.Potfile, encInfo *pdf.EncryptionInfo, password string) {
+	if pot == nil {
+		return
+	}
+	if err := pot.Add(encInfo, password); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not update potfile: %v\n", err)
+	}
+}
+
+func runShow(cmd *cobra.Command, args []string) {
+	pot := openPotfile()
+	if pot == nil {
+		os.Exit(1)
+	}
+
+	for _, file := range args {
+		encInfo, err := pdf.ExtractEncryptionInfo(file)
+		if err != nil {
+			fmt.Printf("%s: error: %v\n", file, err)
+			continue
+		}
+
+		if password, ok := pot.Lookup(encInfo); ok {
+			fmt.Printf("%s:%s\n", file, password)
+		} else {
+			fmt.Printf("%s: not cracked\n", file)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/lth/pdfcrack/internal/attacks"
+	"github.com/spf13/cobra"
+)
+
+var (
+	usePrince     bool
+	princeElemMin int
+	princeElemMax int
+	princeSkip    uint64
+	princeLimit   uint64
+)
+
+func addPrinceFlags(cmd *cobra.Command) {
+	cmd.Flags().IntVar(&princeElemMin, "elem-cnt-min", 1, "PRINCE: minimum number of words per candidate")
+	cmd.Flags().IntVar(&princeElemMax, "elem-cnt-max", 8, "PRINCE: maximum number of words per candidate")
+	cmd.Flags().Uint64Var(&princeSkip, "skip", 0, "PRINCE: skip the first N candidates")
+	cmd.Flags().Uint64Var(&princeLimit, "limit", 0, "PRINCE: stop after N candidates (0 = no limit)")
+}
+
+func newPrinceKeyspace() (*attacks.PrinceKeyspace, error) {
+	if wordlist == "" {
+		return nil, fmt.Errorf("PRINCE attack needs -w <wordlist_file>")
+	}
+	if princeElemMin < 1 || princeElemMax < 1 {
+		return nil, fmt.Errorf("--elem-cnt-min and --elem-cnt-max must be at least 1")
+	}
+	return attacks.NewPrinceKeyspace(attacks.PrinceConfig{
+		Wordlist:    wordlist,
+		MinLength:   minLength,
+		MaxLength:   maxLength,
+		MinElements: princeElemMin,
+		MaxElements: princeElemMax,
+		Skip:        princeSkip,
+		Limit:       princeLimit,
+	})
+}
+
+func princeGenerator() func(ctx context.Context) <-chan string {
+	start := resumePositions["E"]
+	return func(ctx context.Context) <-chan string {
+		ks, err := newPrinceKeyspace()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "\nPRINCE: %v\n", err)
+			closed := make(chan string)
+			close(closed)
+			return closed
+		}
+		return ks.Generator(start)(ctx)
+	}
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/lth/pdfcrack/internal/attacks"
+	"github.com/lth/pdfcrack/internal/session"
+)
+
+const controlHelp = "Keys: [p]ause [r]esume [s]tatus [q]uit with checkpoint. Ctrl+C also checkpoints."
+
+var resumePositions = map[string]uint64{}
+
+// resumeWordlistPositions is the line reached in each wordlist source, and
+// wordlistProgress the running wordlist attack's progress, which splits its
+// position across sources when checkpointing.
+var (
+	resumeWordlistPositions map[string]uint64
+	wordlistProgress        *attacks.WordlistProgress
+)
+
+func sessionFile() string {
+	path, err := session.Path(sessionName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	return path
+}
+
+func applySession() {
+	path := sessionFile()
+	s, err := session.Load(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: cannot restore session %q: %v\n", sessionName, err)
+		os.Exit(1)
+	}
+
+	pdfFiles = s.Files
+	hashFile = s.HashFile
+	noPotfile = noPotfile || s.NoPotfile
+	useWordlist = s.UseWordlist
+	useHarvest = s.UseHarvest
+	harvestMax = s.HarvestMax
+	useIncremental = s.UseIncremental
+	useRandom = s.UseRandom
+	randomSeed = s.RandomSeed
+	useMask = s.UseMask
+	useHybrid = s.UseHybrid
+	useCombinator = s.UseCombinator
+	useMarkov = s.UseMarkov
+	markovThreshold = s.MarkovThreshold
+	markovStats = s.MarkovStats
+	usePCFG = s.UsePCFG
+	pcfgGrammar = s.PCFGGrammar
+	usePrince = s.UsePrince
+	princeElemMin = s.PrinceElemMin
+	princeElemMax = s.PrinceElemMax
+	princeSkip = s.PrinceSkip
+	princeLimit = s.PrinceLimit
+	useTemplate = s.UseTemplate
+	passwordTemplate = s.Template
+	usePattern = s.UsePattern
+	patterns = s.Patterns
+	dateFrom = s.DateFrom
+	dateTo = s.DateTo
+	monthLangs = s.Months
+	patternNames = s.Names
+	phonePrefixes = s.PhonePrefixes
+	phoneDigits = s.PhoneDigits
+	countryCode = s.CountryCode
+	luhnDigits = s.LuhnDigits
+	luhnPrefix = s.LuhnPrefix
+	useKeyboard = s.UseKeyboard
+	kbLayouts = s.KbLayouts
+	kbMin = s.KbMin
+	kbMax = s.KbMax
+	kbTurns = s.KbTurns
+	kbCopies = s.KbCopies
+	kbShift = s.KbShift
+	usePassphrase = s.UsePassphrase
+	phraseList = s.PhraseList
+	phraseMin = s.PhraseMin
+	phraseMax = s.PhraseMax
+	phraseSeparators = s.PhraseSeps
+	phraseCases = s.PhraseCases
+	phraseSuffixes = s.PhraseSuffixes
+	leftList = s.Left
+	middleList = s.Middle
+	rightList = s.Right
+	separators = s.Separators
+	leftRuleFiles = s.LeftRules
+	rightRuleFiles = s.RightRules
+	hybridMaskFirst = s.HybridMaskFirst
+	mask = s.Mask
+	copy(customCharsets[:], s.CustomCharsets)
+	increment = s.Increment
+	incrementMin = s.IncrementMin
+	incrementMax = s.IncrementMax
+	wordlistArgs = s.Wordlists
+	if len(wordlistArgs) == 0 && s.Wordlist != "" {
+		wordlistArgs = []string{s.Wordlist}
+	}
+	resumeWordlistPositions = s.WordlistPositions
+	wordlistEncoding = s.Encoding
+	dedupeWords = s.Dedupe
+	wordlistShards = s.Shards
+	useMmap = s.Mmap
+	toggleCase = s.ToggleCase
+	leetSpec = s.Leet
+	accentMode = s.Accents
+	variantsMax = s.VariantsMax
+	charset = s.Charset
+	minLength = s.MinLength
+	maxLength = s.MaxLength
+	if s.Workers > 0 {
+		workers = s.Workers
+	}
+	if len(s.Weights) > 0 {
+		modeWeights = s.Weights
+	}
+	ruleFiles = s.Rules
+	planFile = s.Plan
+	planStage = s.PlanStage
+	resumePositions = s.Positions
+
+	fmt.Printf("Restoring session %q from %s\n", sessionName, s.Updated.Format("2006-01-02 15:04:05"))
+}
+
+// saveCheckpoint records how far each mode got. Positions passed in are
+// counted from where this run started, so earlier progress is added back.
+func saveCheckpoint(positions map[string]uint64) {
+	s := newSession()
+	for key, pos := range positions {
+		s.Positions[key] = resumePositions[key] + pos
+	}
+	if pos, ok := s.Positions["W"]; ok && wordlistProgress != nil {
+		s.WordlistPositions = wordlistProgress.SourcePositions(pos)
+	}
+	writeSession(s)
+}
+
+// savePlanCheckpoint records the plan stage that was running and the
+// absolute position within it.
+func savePlanCheckpoint(stage int, position uint64) {
+	s := newSession()
+	s.Plan = planFile
+	s.PlanStage = stage
+	s.Positions["P"] = position
+	writeSession(s)
+}
+
+func newSession() *session.Session {
+	return &session.Session{
+		Files:           pdfFiles,
+		HashFile:        hashFile,
+		NoPotfile:       noPotfile,
+		UseWordlist:     useWordlist,
+		UseHarvest:      useHarvest,
+		HarvestMax:      harvestMax,
+		UseIncremental:  useIncremental,
+		UseRandom:       useRandom,
+		RandomSeed:      randomSeed,
+		UseMask:         useMask,
+		UseHybrid:       useHybrid,
+		HybridMaskFirst: hybridMaskFirst,
+		UseCombinator:   useCombinator,
+		UseMarkov:       useMarkov,
+		MarkovThreshold: markovThreshold,
+		MarkovStats:     markovStats,
+		UsePCFG:         usePCFG,
+		PCFGGrammar:     pcfgGrammar,
+		UsePrince:       usePrince,
+		PrinceElemMin:   princeElemMin,
+		PrinceElemMax:   princeElemMax,
+		PrinceSkip:      princeSkip,
+		PrinceLimit:     princeLimit,
+		UseTemplate:     useTemplate,
+		Template:        passwordTemplate,
+		UsePattern:      usePattern,
+		Patterns:        patterns,
+		DateFrom:        dateFrom,
+		DateTo:          dateTo,
+		Months:          monthLangs,
+		Names:           patternNames,
+		PhonePrefixes:   phonePrefixes,
+		PhoneDigits:     phoneDigits,
+		CountryCode:     countryCode,
+		LuhnDigits:      luhnDigits,
+		LuhnPrefix:      luhnPrefix,
+		UseKeyboard:     useKeyboard,
+		KbLayouts:       kbLayouts,
+		KbMin:           kbMin,
+		KbMax:           kbMax,
+		KbTurns:         kbTurns,
+		KbCopies:        kbCopies,
+		KbShift:         kbShift,
+		UsePassphrase:   usePassphrase,
+		PhraseList:      phraseList,
+		PhraseMin:       phraseMin,
+		PhraseMax:       phraseMax,
+		PhraseSeps:      phraseSeparators,
+		PhraseCases:     phraseCases,
+		PhraseSuffixes:  phraseSuffixes,
+		Left:            leftList,
+		Middle:          middleList,
+		Right:           rightList,
+		Separators:      separators,
+		LeftRules:       leftRuleFiles,
+		RightRules:      rightRuleFiles,
+		Mask:            mask,
+		CustomCharsets:  customCharsetList(),
+		Increment:       increment,
+		IncrementMin:    incrementMin,
+		IncrementMax:    incrementMax,
+		Wordlist:        wordlist,
+		Wordlists:       wordlistArgs,
+		Encoding:        wordlistEncoding,
+		Dedupe:          dedupeWords,
+		Shards:          wordlistShards,
+		Mmap:            useMmap,
+		ToggleCase:      toggleCase,
+		Leet:            leetSpec,
+		Accents:         accentMode,
+		VariantsMax:     variantsMax,
+		Charset:         charset,
+		MinLength:       minLength,
+		MaxLength:       maxLength,
+		Workers:         workers,
+		Weights:         modeWeights,
+		Rules:           ruleFiles,
+		Positions:       make(map[string]uint64),
+	}
+}
+
+func writeSession(s *session.Session) {
+	path := sessionFile()
+	if err := s.Save(path); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not save checkpoint: %v\n", err)
+		return
+	}
+	fmt.Printf("Checkpoint saved to %s\n", path)
+	fmt.Printf("Resume with: pdfcrack --restore --session %s\n", sessionName)
+}
+
+func clearSession() {
+	if !restoreSession {
+		return
+	}
+	if err := session.Remove(sessionFile()); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not remove session: %v\n", err)
+	}
+}
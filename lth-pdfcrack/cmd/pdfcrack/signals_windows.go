@@ -0,0 +1,12 @@
+//go:build windows
+// +build windows
+
+package main
+
+import "os"
+
+// Windows has no SIGUSR1/SIGUSR2; pausing is only available from the keyboard.
+var (
+	pauseSignal  os.Signal
+	resumeSignal os.Signal
+)
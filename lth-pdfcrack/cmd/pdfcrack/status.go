@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/lth/pdfcrack/internal/attacks"
+)
+
+type modeStatus struct {
+	attempts uint64
+	rate     float64
+	current  string
+	active   bool
+
+	// offset counts candidates tried before a restored checkpoint.
+	offset   uint64
+	keyspace uint64
+	overflow bool
+	wordlist *attacks.WordlistProgress
+	// perWord is the number of candidates rules or a hybrid mask make from
+	// each wordlist line.
+	perWord uint64
+	// exact is set when every line yields exactly perWord candidates, so
+	// progress can count candidates instead of bytes read. The reader runs
+	// ahead of the workers, which matters once each line is a whole mask.
+	exact bool
+}
+
+func newModeStatus(key string, active bool) *modeStatus {
+	s := &modeStatus{active: active, offset: resumePositions[key]}
+	if m, ok := lookupMode(key); ok && active {
+		m.setup(s)
+	}
+	return s
+}
+
+func warnKeyspace() {
+	if v, err := newVariants(); err == nil && v != nil && useWordlist {
+		if total, ok := countWordlistVariants(v); ok {
+			printKeyspace("wordlist variants", total, total == math.MaxUint64)
+		}
+	}
+	for _, m := range attackModes {
+		if !*m.enabled {
+			continue
+		}
+		s := newModeStatus(m.key, true)
+		if s.wordlist != nil || (s.keyspace == 0 && !s.overflow) {
+			// Wordlist modes count lines as they read them.
+			continue
+		}
+		if s.overflow && m.note != "" {
+			fmt.Fprintf(os.Stderr, "Warning: %s keyspace exceeds 2^64 candidates; %s\n", strings.ToLower(m.name), m.note)
+			continue
+		}
+		printKeyspace(strings.ToLower(m.name), s.keyspace, s.overflow)
+	}
+	if useRandom {
+		fmt.Printf("Random seed: %d\n", randomSeed)
+	}
+}
+
+func printKeyspace(mode string, total uint64, overflow bool) {
+	if overflow {
+		fmt.Fprintf(os.Stderr, "Warning: %s keyspace exceeds 2^64 candidates; progress and ETA are not available\n", mode)
+		return
+	}
+	fmt.Printf("Keyspace: %d candidates (%s)\n", total, mode)
+}
+
+// progress reports the fraction of the mode's candidates that have been
+// tried, how many remain and how long they should take at the current rate.
+// ok is false for modes without a known end, such as a wordlist on stdin.
+func (s *modeStatus) progress() (fraction float64, remaining uint64, eta time.Duration, ok bool) {
+	switch {
+	case s.wordlist != nil && s.exact:
+		total := mulSaturating(s.wordlist.Lines()+s.wordlist.RemainingLines(), s.perWord)
+		if total == 0 {
+			return 0, 0, 0, false
+		}
+		done := s.offset + s.attempts
+		if done > total {
+			done = total
+		}
+		fraction = float64(done) / float64(total)
+		remaining = total - done
+	case s.wordlist != nil:
+		if s.wordlist.Size() <= 0 {
+			return 0, 0, 0, false
+		}
+		fraction = s.wordlist.Fraction()
+		remaining = s.wordlist.RemainingLines()
+		if s.perWord > 1 {
+			remaining = mulSaturating(remaining, s.perWord)
+		}
+	case s.keyspace > 0 && !s.overflow:
+		done := s.offset + s.attempts
+		if done > s.keyspace {
+			done = s.keyspace
+		}
+		fraction = float64(done) / float64(s.keyspace)
+		remaining = s.keyspace - done
+	default:
+		return 0, 0, 0, false
+	}
+
+	eta = -1
+	if s.rate > 0 {
+		secs := float64(remaining) / s.rate
+		if secs < float64(math.MaxInt64/int64(time.Second)) {
+			eta = time.Duration(secs * float64(time.Second))
+		}
+	}
+	return fraction, remaining, eta, true
+}
+
+func (s *modeStatus) summary() string {
+	fraction, remaining, eta, ok := s.progress()
+	if !ok {
+		return fmt.Sprintf("%s @ %s/s", formatCount(s.offset+s.attempts), formatCount(uint64(s.rate)))
+	}
+	return fmt.Sprintf("%.1f%% %s left ETA %s @ %s/s", fraction*100, formatCount(remaining), formatETA(eta), formatCount(uint64(s.rate)))
+}
+
+func mulSaturating(a, b uint64) uint64 {
+	hi, lo := bits.Mul64(a, b)
+	if hi != 0 {
+		return math.MaxUint64
+	}
+	return lo
+}
+
+func formatETA(eta time.Duration) string {
+	switch {
+	case eta < 0:
+		return "?"
+	case eta > 100*365*24*time.Hour:
+		return ">100y"
+	case eta > 48*time.Hour:
+		return fmt.Sprintf("%dd%02dh", int(eta.Hours())/24, int(eta.Hours())%24)
+	default:
+		return formatDuration(eta)
+	}
+}
+
+func formatCount(n uint64) string {
+	const units = "kMGTPE"
+	if n < 1000 {
+		return fmt.Sprintf("%d", n)
+	}
+	f := float64(n)
+	i := -1
+	for f >= 1000 && i < len(units)-1 {
+		f /= 1000
+		i++
+	}
+	return fmt.Sprintf("%.1f%c", f, units[i])
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/lth/pdfcrack/internal/attacks"
+	"github.com/spf13/cobra"
+)
+
+var (
+	useTemplate      bool
+	passwordTemplate string
+)
+
+func addTemplateFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&passwordTemplate, "template", "", "Template: known fragments with unknown parts in braces, e.g. 'Rex{?a,1-4}{?d?d}{?s}'")
+}
+
+func newTemplateKeyspace() (*attacks.TemplateKeyspace, error) {
+	if passwordTemplate == "" {
+		return nil, fmt.Errorf("template attack needs --template, e.g. 'Rex{?a,1-4}{?d?d}{?s}'")
+	}
+	return attacks.NewTemplateKeyspace(passwordTemplate, customCharsets)
+}
+
+func templateGenerator() func(ctx context.Context) <-chan string {
+	start := resumePositions["T"]
+	return func(ctx context.Context) <-chan string {
+		ks, err := newTemplateKeyspace()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "\nTemplate: %v\n", err)
+			closed := make(chan string)
+			close(closed)
+			return closed
+		}
+		return attacks.KeyspaceGenerator(ctx, ks, start, ks.Size())
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/lth/pdfcrack/internal/attacks"
+	"github.com/spf13/cobra"
+)
+
+var (
+	toggleCase  string
+	leetSpec    string
+	accentMode  string
+	variantsMax uint64
+
+	// variantProgress maps wordlist candidates back to lines for
+	// checkpoints while variants are on.
+	variantProgress *attacks.VariantProgress
+)
+
+func addVariantFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&toggleCase, "toggle-case", "", "Variants: how many letters of a word may change case, or all")
+	cmd.Flags().StringVar(&leetSpec, "leet", "", "Variants: leetspeak table: basic, full or substitutions such as a:4@,e:3")
+	cmd.Flags().StringVar(&accentMode, "accents", "", "Variants: strip, add or both")
+	cmd.Flags().Uint64Var(&variantsMax, "variants-max", 1000, "Variants: most variants of each word, fewest changes first (0 = all)")
+}
+
+func variantsEnabled() bool {
+	return toggleCase != "" || leetSpec != "" || accentMode != ""
+}
+
+// newVariants returns nil when no variant flag is set.
+func newVariants() (*attacks.Variants, error) {
+	if !variantsEnabled() {
+		return nil, nil
+	}
+	config := attacks.VariantConfig{MaxVariants: variantsMax}
+	switch toggleCase {
+	case "":
+	case "all":
+		config.MaxToggles = attacks.AllToggles
+	default:
+		n, err := strconv.Atoi(toggleCase)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("--toggle-case takes a number of letters or all, not %q", toggleCase)
+		}
+		config.MaxToggles = n
+	}
+	if leetSpec != "" {
+		var err error
+		if config.Leet, err = attacks.LeetTable(leetSpec); err != nil {
+			return nil, err
+		}
+	}
+	switch strings.ToLower(accentMode) {
+	case "":
+	case "strip":
+		config.StripAccents = true
+	case "add":
+		config.AddAccents = true
+	case "both":
+		config.StripAccents, config.AddAccents = true, true
+	default:
+		return nil, fmt.Errorf("--accents takes strip, add or both, not %q", accentMode)
+	}
+	return attacks.NewVariants(config), nil
+}
+
+// countWordlistVariants reads the wordlists through the rules to count every
+// variant, for the keyspace line. A wordlist on stdin cannot be counted.
+func countWordlistVariants(v *attacks.Variants) (total uint64, ok bool) {
+	sources, err := attacks.ExpandWordlists(wordlistArgs)
+	if err != nil {
+		return 0, false
+	}
+	for _, source := range sources {
+		if source == attacks.Stdin {
+			return 0, false
+		}
+	}
+	norm, err := newNormalizer()
+	if err != nil {
+		return 0, false
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	words, err := attacks.WordlistSourcesGenerator(ctx, sources, nil, nil, norm, attacks.ShardConfig{})
+	if err != nil {
+		return 0, false
+	}
+	for word := range words {
+		if ruleEngine == nil {
+			total = addSaturating(total, v.Count(word))
+			continue
+		}
+		for i := uint64(0); i < ruleEngine.Size(); i++ {
+			if candidate, ok := ruleEngine.Apply(word, i); ok {
+				total = addSaturating(total, v.Count(candidate))
+			}
+		}
+	}
+	return total, true
+}
+
+func addSaturating(a, b uint64) uint64 {
+	if a+b < a {
+		return math.MaxUint64
+	}
+	return a + b
+}
@@ -0,0 +1,309 @@
+package attacks
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"math/bits"
+	"os"
+
+	"github.com/lth/pdfcrack/internal/attacks/rules"
+)
+
+// maxInMemoryList is the largest inner combinator list kept in memory, in
+// bytes. Bigger lists are read from disk again for every outer word.
+var maxInMemoryList int64 = 64 << 20
+
+type CombinatorConfig struct {
+	Left   string
+	Middle string
+	Right  string
+	// Separators are placed between the words; every separator is tried.
+	// None means the words are joined directly.
+	Separators []string
+	LeftRules  *rules.Engine
+	RightRules *rules.Engine
+}
+
+// Combinator joins a word from each list as left+sep+middle+sep+right, or
+// left+sep+right without a middle list. The left list is streamed; the
+// others are loaded when they are small enough and re-read otherwise.
+//
+// Candidates are numbered in mixed radix over (left line, left rule,
+// separator, middle line, right line, right rule), last changing fastest.
+// Candidates rejected by a rule keep their number, so positions stay
+// stable across runs.
+type Combinator struct {
+	config CombinatorConfig
+	middle *innerList
+	right  *innerList
+	seps   []string
+	// inner is the number of candidates per left word after left rules.
+	inner uint64
+}
+
+func NewCombinator(config CombinatorConfig) (*Combinator, error) {
+	if config.Left == "" || config.Right == "" {
+		return nil, fmt.Errorf("combinator needs a left and a right wordlist")
+	}
+	if _, err := os.Stat(config.Left); err != nil {
+		return nil, err
+	}
+
+	c := &Combinator{config: config, seps: config.Separators}
+	if len(c.seps) == 0 {
+		c.seps = []string{""}
+	}
+
+	var err error
+	if c.right, err = openInnerList(config.Right); err != nil {
+		return nil, err
+	}
+	c.middle = &innerList{lines: []string{""}, count: 1}
+	if config.Middle != "" {
+		if c.middle, err = openInnerList(config.Middle); err != nil {
+			return nil, err
+		}
+	}
+
+	inner, ok := mulChecked(uint64(len(c.seps)), c.middle.count, c.right.count, ruleCount(config.RightRules))
+	if _, perWordOK := mulChecked(inner, ruleCount(config.LeftRules)); !ok || !perWordOK {
+		return nil, fmt.Errorf("combinator keyspace exceeds 2^64 candidates")
+	}
+	c.inner = inner
+	return c, nil
+}
+
+// PerWord is the number of candidates made from each left line.
+func (c *Combinator) PerWord() uint64 {
+	n, _ := mulChecked(ruleCount(c.config.LeftRules), c.inner)
+	return n
+}
+
+// Keyspace counts the left list and returns the number of positions.
+func (c *Combinator) Keyspace() (uint64, error) {
+	lines, err := CountLines(c.config.Left)
+	if err != nil {
+		return 0, err
+	}
+	size, ok := mulChecked(lines, c.PerWord())
+	if !ok {
+		return 0, fmt.Errorf("combinator keyspace exceeds 2^64 candidates")
+	}
+	return size, nil
+}
+
+// Generator produces the candidates at positions [start, end), reporting the
+// left list's read position through progress if it is non-nil. The left
+// line is only counted as read once its candidates have been handed out.
+func (c *Combinator) Generator(ctx context.Context, start, end uint64, progress *WordlistProgress) (<-chan string, error) {
+	f, err := os.Open(c.config.Left)
+	if err != nil {
+		return nil, err
+	}
+	if progress == nil {
+		progress = &WordlistProgress{}
+	}
+	if st, err := f.Stat(); err == nil {
+		progress.size = st.Size()
+	}
+
+	leftRules := ruleCount(c.config.LeftRules)
+	rightRules := ruleCount(c.config.RightRules)
+	perLine := c.PerWord()
+
+	ch := make(chan string, 1000)
+	if perLine == 0 {
+		f.Close()
+		close(ch)
+		return ch, nil
+	}
+
+	go func() {
+		defer close(ch)
+		defer f.Close()
+
+		scanner := bufio.NewScanner(f)
+		buf := make([]byte, 0, 64*1024)
+		scanner.Buffer(buf, 1024*1024)
+
+		// d holds the starting digit of each loop; they reset to zero once
+		// the loop that owns them has wrapped.
+		d := [6]uint64{start / perLine}
+		rem := start % perLine
+		d[1], rem = rem/c.inner, rem%c.inner
+		block := c.middle.count * c.right.count * rightRules
+		d[2], rem = rem/block, rem%block
+		block = c.right.count * rightRules
+		d[3], rem = rem/block, rem%block
+		d[4], d[5] = rem/rightRules, rem%rightRules
+
+		emit := func(pos uint64, candidate string) bool {
+			if pos >= end {
+				return false
+			}
+			select {
+			case <-ctx.Done():
+				return false
+			case ch <- candidate:
+				return true
+			}
+		}
+
+		var line uint64
+		for scanner.Scan() {
+			read := int64(len(scanner.Bytes()) + 1)
+			line++
+			if line <= d[0] {
+				progress.offset.Add(read)
+				progress.lines.Add(1)
+				continue
+			}
+			word := cleanLine(scanner.Text(), line == 1)
+
+			for lr := d[1]; lr < leftRules; lr++ {
+				blockStart := ((line-1)*leftRules + lr) * c.inner
+				if blockStart >= end {
+					return
+				}
+				left, ok := applyRule(c.config.LeftRules, word, lr)
+				if !ok {
+					d[2], d[3], d[4], d[5] = 0, 0, 0, 0
+					continue
+				}
+
+				pos := blockStart + ((d[2]*c.middle.count+d[3])*c.right.count+d[4])*rightRules + d[5]
+				for s := d[2]; s < uint64(len(c.seps)); s++ {
+					sep := c.seps[s]
+					stopped := !c.middle.each(ctx, d[3], func(middle string) bool {
+						prefix := left + sep
+						if c.config.Middle != "" {
+							prefix += middle + sep
+						}
+						ok := c.right.each(ctx, d[4], func(right string) bool {
+							for rr := d[5]; rr < rightRules; rr++ {
+								r, ok := applyRule(c.config.RightRules, right, rr)
+								if ok && !emit(pos, prefix+r) {
+									return false
+								}
+								pos++
+							}
+							d[5] = 0
+							return true
+						})
+						d[4] = 0
+						return ok
+					})
+					d[3] = 0
+					if stopped {
+						return
+					}
+				}
+				d[2] = 0
+			}
+			d[1] = 0
+			progress.offset.Add(read)
+			progress.lines.Add(1)
+		}
+		progress.offset.Store(progress.size)
+	}()
+
+	return ch, nil
+}
+
+// innerList is a middle or right list, in memory or re-read on each pass.
+type innerList struct {
+	path  string
+	lines []string
+	count uint64
+}
+
+func openInnerList(path string) (*innerList, error) {
+	st, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if st.Size() > maxInMemoryList {
+		count, err := CountLines(path)
+		if err != nil {
+			return nil, err
+		}
+		return &innerList{path: path, count: count}, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	l := &innerList{}
+	scanner := bufio.NewScanner(f)
+	buf := make([]byte, 0, 64*1024)
+	scanner.Buffer(buf, 1024*1024)
+	for scanner.Scan() {
+		l.lines = append(l.lines, cleanLine(scanner.Text(), len(l.lines) == 0))
+	}
+	l.count = uint64(len(l.lines))
+	return l, scanner.Err()
+}
+
+// each calls fn for every line from index from on. It returns false if fn
+// did or the context was cancelled.
+func (l *innerList) each(ctx context.Context, from uint64, fn func(string) bool) bool {
+	if l.path == "" {
+		for _, line := range l.lines[from:] {
+			if !fn(line) {
+				return false
+			}
+		}
+		return ctx.Err() == nil
+	}
+
+	f, err := os.Open(l.path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	buf := make([]byte, 0, 64*1024)
+	scanner.Buffer(buf, 1024*1024)
+	var i uint64
+	for scanner.Scan() {
+		i++
+		if i <= from {
+			continue
+		}
+		if !fn(cleanLine(scanner.Text(), i == 1)) {
+			return false
+		}
+	}
+	return ctx.Err() == nil
+}
+
+func ruleCount(e *rules.Engine) uint64 {
+	if e == nil {
+		return 1
+	}
+	return e.Size()
+}
+
+func applyRule(e *rules.Engine, word string, index uint64) (string, bool) {
+	if e == nil {
+		return word, true
+	}
+	return e.Apply(word, index)
+}
+
+func mulChecked(factors ...uint64) (uint64, bool) {
+	product := uint64(1)
+	for _, f := range factors {
+		hi, lo := bits.Mul64(product, f)
+		if hi != 0 {
+			return 0, false
+		}
+		product = lo
+	}
+	return product, true
+}
@@ -0,0 +1,86 @@
+package attacks
+
+import (
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lth/pdfcrack/internal/attacks/rules"
+)
+
+func TestCombinatorGenerator(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	left := write("left.txt", "blue\nred\n")
+	right := write("right.txt", "horse\ncar\n")
+	middle := write("middle.txt", "big\n")
+
+	ruleSet, _ := rules.Read(strings.NewReader(":\nc\n<3\n"))
+	capitalize := rules.NewEngine(ruleSet)
+
+	tests := []struct {
+		name       string
+		config     CombinatorConfig
+		start, end uint64
+		want       string
+	}{
+		{"plain", CombinatorConfig{Left: left, Right: right}, 0, math.MaxUint64,
+			"bluehorse,bluecar,redhorse,redcar"},
+		{"separators", CombinatorConfig{Left: left, Right: right, Separators: []string{"", "_"}}, 3, 6,
+			"blue_car,redhorse,redcar"},
+		{"middle", CombinatorConfig{Left: left, Middle: middle, Right: right, Separators: []string{"-"}}, 0, math.MaxUint64,
+			"blue-big-horse,blue-big-car,red-big-horse,red-big-car"},
+		// Rule <3 rejects "horse" on the right and "blue" on the left, but
+		// the rejected candidates keep their positions: 6 per left rule.
+		{"rules", CombinatorConfig{Left: left, Right: right, LeftRules: capitalize, RightRules: capitalize}, 10, 23,
+			"BlueCar,Bluecar,redhorse,redHorse,redcar,redCar"},
+	}
+
+	for _, tt := range tests {
+		c, err := NewCombinator(tt.config)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		ch, err := c.Generator(context.Background(), tt.start, tt.end, nil)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		var got []string
+		for candidate := range ch {
+			got = append(got, candidate)
+		}
+		if strings.Join(got, ",") != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, strings.Join(got, ","), tt.want)
+		}
+	}
+
+	// The same candidates when the inner lists are streamed from disk.
+	defer func(limit int64) { maxInMemoryList = limit }(maxInMemoryList)
+	maxInMemoryList = 0
+	c, err := NewCombinator(tests[2].config)
+	if err != nil || c.middle.path == "" || c.right.path == "" {
+		t.Fatalf("inner lists not streamed: %v", err)
+	}
+	ch, _ := c.Generator(context.Background(), 1, math.MaxUint64, nil)
+	var got []string
+	for candidate := range ch {
+		got = append(got, candidate)
+	}
+	if strings.Join(got, ",") != "blue-big-car,red-big-horse,red-big-car" {
+		t.Errorf("streamed: got %s", strings.Join(got, ","))
+	}
+
+	c, _ = NewCombinator(CombinatorConfig{Left: left, Right: right, Separators: []string{"", "_"}, RightRules: capitalize})
+	if size, err := c.Keyspace(); err != nil || size != 24 {
+		t.Errorf("Keyspace() = %d, %v, want 24", size, err)
+	}
+}
@@ -0,0 +1,91 @@
+package attacks
+
+import (
+	"context"
+	"fmt"
+	"math/bits"
+)
+
+// Hybrid joins wordlist lines with mask candidates: word then mask
+// (hashcat -a 6) or, with MaskFirst, mask then word (-a 7). Candidate i of
+// word w is at position w*Mask.Size()+i, so a position names both the line
+// to resume from and where to start within it.
+type Hybrid struct {
+	Wordlist  string
+	Mask      *MaskKeyspace
+	MaskFirst bool
+}
+
+func NewHybrid(wordlist string, mask MaskConfig, maskFirst bool) (*Hybrid, error) {
+	ks, err := NewMaskKeyspace(mask)
+	if err != nil {
+		return nil, err
+	}
+	if ks.Overflow() {
+		return nil, fmt.Errorf("mask keyspace exceeds 2^64 candidates")
+	}
+	return &Hybrid{Wordlist: wordlist, Mask: ks, MaskFirst: maskFirst}, nil
+}
+
+// PerWord is the number of candidates made from each line.
+func (h *Hybrid) PerWord() uint64 {
+	return h.Mask.Size()
+}
+
+func (h *Hybrid) Combine(word string, index uint64) string {
+	if h.MaskFirst {
+		return h.Mask.At(index) + word
+	}
+	return word + h.Mask.At(index)
+}
+
+// Keyspace counts the wordlist and returns lines times mask candidates.
+func (h *Hybrid) Keyspace() (uint64, error) {
+	lines, err := CountLines(h.Wordlist)
+	if err != nil {
+		return 0, err
+	}
+	hi, lo := bits.Mul64(lines, h.PerWord())
+	if hi != 0 {
+		return 0, fmt.Errorf("hybrid keyspace exceeds 2^64 candidates")
+	}
+	return lo, nil
+}
+
+// Generator produces the candidates at positions [start, end). Pass
+// math.MaxUint64 as end to run to the end of the wordlist. progress, if
+// non-nil, follows the read position in the wordlist.
+func (h *Hybrid) Generator(ctx context.Context, start, end uint64, progress *WordlistProgress) (<-chan string, error) {
+	size := h.PerWord()
+	ctx, cancel := context.WithCancel(ctx)
+	words, err := WordlistGeneratorFrom(ctx, h.Wordlist, start/size, progress)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	ch := make(chan string, 1000)
+
+	go func() {
+		defer close(ch)
+		// Stops the wordlist reader when end is reached before the file ends.
+		defer cancel()
+
+		pos := start
+		for word := range words {
+			for i := pos % size; i < size && pos < end; i++ {
+				select {
+				case <-ctx.Done():
+					return
+				case ch <- h.Combine(word, i):
+				}
+				pos++
+			}
+			if pos >= end {
+				return
+			}
+		}
+	}()
+
+	return ch, nil
+}
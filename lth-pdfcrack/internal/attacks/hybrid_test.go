@@ -0,0 +1,53 @@
+package attacks
+
+import (
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHybridGenerator(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "words.txt")
+	if err := os.WriteFile(path, []byte("acme\nfoo\nbar\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	mask := MaskConfig{Mask: "?1?1", Custom: [4]string{"01"}}
+
+	tests := []struct {
+		maskFirst  bool
+		start, end uint64
+		want       string
+	}{
+		{false, 0, math.MaxUint64, "acme00,acme01,acme10,acme11,foo00,foo01,foo10,foo11,bar00,bar01,bar10,bar11"},
+		{true, 0, 3, "00acme,01acme,10acme"},
+		{false, 6, 9, "foo10,foo11,bar00"},
+		{false, 11, math.MaxUint64, "bar11"},
+		{false, 12, math.MaxUint64, ""},
+	}
+
+	for _, tt := range tests {
+		h, err := NewHybrid(path, mask, tt.maskFirst)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ch, err := h.Generator(context.Background(), tt.start, tt.end, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for c := range ch {
+			got = append(got, c)
+		}
+		if strings.Join(got, ",") != tt.want {
+			t.Errorf("maskFirst %v [%d, %d) = %s, want %s", tt.maskFirst, tt.start, tt.end, strings.Join(got, ","), tt.want)
+		}
+	}
+
+	h, _ := NewHybrid(path, mask, false)
+	if size, err := h.Keyspace(); err != nil || size != 12 {
+		t.Errorf("Keyspace() = %d, %v, want 12", size, err)
+	}
+}
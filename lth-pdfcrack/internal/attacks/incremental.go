@@ -2,6 +2,9 @@ package attacks
 
 import (
 	"context"
+	"math"
+	"math/bits"
+	"strings"
 )
 
 type IncrementalConfig struct {
@@ -20,6 +23,29 @@ var (
 	CharsetAll     = CharsetAlphaNum + CharsetSpecial
 )
 
+// ResolveCharset expands a named charset such as "digits" or "alnum"; any
+// other string is used as the charset itself.
+func ResolveCharset(cs string) string {
+	switch strings.ToLower(cs) {
+	case "lower":
+		return CharsetLower
+	case "upper":
+		return CharsetUpper
+	case "digits", "numbers":
+		return CharsetDigits
+	case "alpha":
+		return CharsetAlpha
+	case "alnum", "alphanumeric":
+		return CharsetAlphaNum
+	case "all", "full":
+		return CharsetAll
+	case "special":
+		return CharsetSpecial
+	default:
+		return cs
+	}
+}
+
 func IncrementalGenerator(ctx context.Context, config IncrementalConfig) <-chan string {
 	ch := make(chan string, 10000)
 	
@@ -94,21 +120,63 @@ func generateLength(ctx context.Context, ch chan<- string, charset []byte, lengt
 }
 
 func EstimateCombinations(config IncrementalConfig) uint64 {
+	total, _ := EstimateCombinationsChecked(config)
+	return total
+}
+
+// EstimateCombinationsChecked applies the same length limits as
+// IncrementalGenerator. When the keyspace does not fit in a uint64 it
+// returns math.MaxUint64 and overflow=true.
+func EstimateCombinationsChecked(config IncrementalConfig) (total uint64, overflow bool) {
 	charset := config.Charset
 	if charset == "" {
 		charset = CharsetAlphaNum
 	}
 	
-	var total uint64
-	base := uint64(len(charset))
+	counts, overflow := lengthCounts(len(charset), config.MinLength, config.MaxLength)
+	if overflow {
+		return math.MaxUint64, true
+	}
+	for _, count := range counts {
+		total += count
+	}
+	return total, false
+}
+
+func normalizeLengths(minLen, maxLen int) (int, int) {
+	if minLen < 1 {
+		minLen = 1
+	}
+	if maxLen < minLen {
+		maxLen = minLen
+	}
+	if maxLen > 16 {
+		maxLen = 16
+	}
+	return minLen, maxLen
+}
+
+func lengthCounts(charsetLen, minLen, maxLen int) ([]uint64, bool) {
+	minLen, maxLen = normalizeLengths(minLen, maxLen)
 	
-	for length := config.MinLength; length <= config.MaxLength; length++ {
-		combinations := uint64(1)
+	var counts []uint64
+	var total uint64
+	base := uint64(charsetLen)
+	for length := minLen; length <= maxLen; length++ {
+		count := uint64(1)
 		for i := 0; i < length; i++ {
-			combinations *= base
+			hi, lo := bits.Mul64(count, base)
+			if hi != 0 {
+				return nil, true
+			}
+			count = lo
 		}
-		total += combinations
+		if total+count < total {
+			return nil, true
+		}
+		total += count
+		counts = append(counts, count)
 	}
 	
-	return total
+	return counts, false
 }
@@ -2,6 +2,8 @@ package attacks
 
 import (
 	"context"
+	"math"
+	"strings"
 	"testing"
 	"time"
 )
@@ -56,6 +58,57 @@ func TestEstimateCombinations(t *testing.T) {
 			t.Errorf("EstimateCombinations(%v) = %d, want %d", tt.config, result, tt.expected)
 		}
 	}
+
+	if _, overflow := EstimateCombinationsChecked(IncrementalConfig{Charset: CharsetAlphaNum, MinLength: 1, MaxLength: 10}); overflow {
+		t.Error("alnum 1-10 reported as overflowing")
+	}
+	total, overflow := EstimateCombinationsChecked(IncrementalConfig{Charset: CharsetAll, MinLength: 1, MaxLength: 16})
+	if !overflow || total != math.MaxUint64 {
+		t.Errorf("all 1-16 = %d, overflow %v, want saturated overflow", total, overflow)
+	}
+	if !NewIncrementalKeyspace(IncrementalConfig{Charset: CharsetAll, MinLength: 12, MaxLength: 12}).Overflow() {
+		t.Error("91^12 keyspace not flagged as overflowing")
+	}
+}
+
+// keyspaceAll lists every candidate of a small keyspace in index order.
+func keyspaceAll(t *testing.T, ks Keyspace) []string {
+	t.Helper()
+	if ks.Size() > 1<<20 {
+		t.Fatalf("keyspace of %d candidates is too large to list", ks.Size())
+	}
+	all := make([]string, ks.Size())
+	for i := range all {
+		all[i] = ks.At(uint64(i))
+	}
+	return all
+}
+
+func TestIncrementalKeyspaceMatchesGenerator(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	config := IncrementalConfig{Charset: "abc", MinLength: 1, MaxLength: 3}
+	ks := NewIncrementalKeyspace(config)
+	if ks.Size() != EstimateCombinations(config) {
+		t.Fatalf("Size() = %d, want %d", ks.Size(), EstimateCombinations(config))
+	}
+
+	var i uint64
+	for pwd := range IncrementalGenerator(ctx, config) {
+		if got := ks.At(i); got != pwd {
+			t.Fatalf("At(%d) = %q, want %q", i, got, pwd)
+		}
+		i++
+	}
+
+	var got []string
+	for pwd := range KeyspaceGenerator(ctx, ks, 2, 5) {
+		got = append(got, pwd)
+	}
+	if strings.Join(got, ",") != "c,aa,ab" {
+		t.Errorf("KeyspaceGenerator(2, 5) = %v", got)
+	}
 }
 
 func BenchmarkIncrementalGenerator(b *testing.B) {
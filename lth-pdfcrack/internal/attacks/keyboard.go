@@ -0,0 +1,616 @@
+package attacks
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Shift styles accepted in KeyboardConfig.Shift.
+const (
+	ShiftNone   = "none"
+	ShiftFirst  = "first"
+	ShiftAll    = "all"
+	ShiftCopies = "copies"
+)
+
+// KeyboardLayout is the printable keys of a keyboard, row by row from the
+// top.
+type KeyboardLayout struct {
+	Name string
+	Rows []KeyRow
+}
+
+// KeyRow is one row of keys and what they type with Shift held. Offset is
+// how far the row's first key is indented from the top row's, in key
+// widths; it decides which keys of the rows above and below are diagonal
+// neighbours.
+type KeyRow struct {
+	Offset  float64
+	Keys    string
+	Shifted string
+}
+
+var keyboardLayouts = map[string]*KeyboardLayout{
+	"us": {Name: "us", Rows: []KeyRow{
+		{0, "`1234567890-=", "~!@#$%^&*()_+"},
+		{1.5, `qwertyuiop[]\`, "QWERTYUIOP{}|"},
+		{1.75, "asdfghjkl;'", `ASDFGHJKL:"`},
+		{2.25, "zxcvbnm,./", "ZXCVBNM<>?"},
+	}},
+	"uk": {Name: "uk", Rows: []KeyRow{
+		{0, "`1234567890-=", `¬!"£$%^&*()_+`},
+		{1.5, "qwertyuiop[]", "QWERTYUIOP{}"},
+		{1.75, "asdfghjkl;'#", "ASDFGHJKL:@~"},
+		{1.25, `\zxcvbnm,./`, "|ZXCVBNM<>?"},
+	}},
+	"azerty": {Name: "azerty", Rows: []KeyRow{
+		{1, `&é"'(-è_çà)=`, "1234567890°+"},
+		{1.5, "azertyuiop^$", "AZERTYUIOP¨£"},
+		{1.75, "qsdfghjklmù*", "QSDFGHJKLM%µ"},
+		{1.25, "<wxcvbn,;:!", ">WXCVBN?./§"},
+	}},
+	"qwertz": {Name: "qwertz", Rows: []KeyRow{
+		{0, "^1234567890ß´", "°!\"§$%&/()=?`"},
+		{1.5, "qwertzuiopü+", "QWERTZUIOPÜ*"},
+		{1.75, "asdfghjklöä#", "ASDFGHJKLÖÄ'"},
+		{1.25, "<yxcvbnm,.-", ">YXCVBNM;:_"},
+	}},
+}
+
+// KeyboardLayouts returns the names of the built-in layouts.
+func KeyboardLayouts() []string {
+	names := make([]string, 0, len(keyboardLayouts))
+	for name := range keyboardLayouts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadKeyboardLayout reads a layout file, or returns the built-in layout of
+// that name.
+func LoadKeyboardLayout(name string) (*KeyboardLayout, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		if layout, ok := keyboardLayouts[strings.ToLower(name)]; ok {
+			return layout, nil
+		}
+		return nil, fmt.Errorf("unknown keyboard layout %q (use %s or a layout file)", name, strings.Join(KeyboardLayouts(), ", "))
+	}
+	defer f.Close()
+	layout, err := ReadKeyboardLayout(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	layout.Name = name
+	return layout, nil
+}
+
+// ReadKeyboardLayout parses a layout: one row per line, top row first, as
+// the row's offset in key widths, its keys and their shifted keys, e.g.
+//
+//	1.5 qwertyuiop[] QWERTYUIOP{}
+//
+// Blank lines and lines starting with # are skipped.
+func ReadKeyboardLayout(r io.Reader) (*KeyboardLayout, error) {
+	layout := &KeyboardLayout{}
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("line %d: want offset, keys and shifted keys", line)
+		}
+		offset, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil || offset < 0 {
+			return nil, fmt.Errorf("line %d: bad offset %q", line, fields[0])
+		}
+		layout.Rows = append(layout.Rows, KeyRow{Offset: offset, Keys: fields[1], Shifted: fields[2]})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if _, err := newKeyboard(layout); err != nil {
+		return nil, err
+	}
+	return layout, nil
+}
+
+// Walk directions, in the order they are tried.
+const (
+	dirRight = iota
+	dirDownRight
+	dirDownLeft
+	dirLeft
+	dirUpRight
+	dirUpLeft
+	numDirections
+	noDirection = numDirections
+)
+
+var oppositeDirection = [numDirections]int{dirLeft, dirUpLeft, dirUpRight, dirRight, dirDownLeft, dirDownRight}
+
+// maxWalkKeys bounds the keys of a layout so a key index fits in a byte of
+// the memo key.
+const maxWalkKeys = 255
+
+// keyboard is a layout as a graph: each key and its neighbour in each
+// direction, or -1 at the edge.
+type keyboard struct {
+	keys    []rune
+	shifted []rune
+	next    [][numDirections]int
+	// dist holds the fewest steps from one key to another, counting every
+	// direction, or -1 when there is no way.
+	dist [][]int
+	// memo caches walkCount. It is filled while the keyspace is sized and
+	// only read afterwards.
+	memo map[uint64]uint64
+}
+
+func newKeyboard(layout *KeyboardLayout) (*keyboard, error) {
+	if len(layout.Rows) == 0 {
+		return nil, fmt.Errorf("keyboard layout has no rows")
+	}
+	type position struct{ row, x int }
+	b := &keyboard{memo: make(map[uint64]uint64)}
+	var positions []position
+	rows := make([][]int, len(layout.Rows))
+	for r, row := range layout.Rows {
+		keys, shifted := []rune(row.Keys), []rune(row.Shifted)
+		if len(keys) != len(shifted) {
+			return nil, fmt.Errorf("row %d has %d keys but %d shifted keys", r+1, len(keys), len(shifted))
+		}
+		// Positions are in quarter keys, at the centre of each key.
+		offset := int(math.Round(row.Offset * 4))
+		for i := range keys {
+			rows[r] = append(rows[r], len(b.keys))
+			positions = append(positions, position{r, offset + 4*i + 2})
+			b.keys = append(b.keys, keys[i])
+			b.shifted = append(b.shifted, shifted[i])
+		}
+	}
+	if len(b.keys) > maxWalkKeys {
+		return nil, fmt.Errorf("keyboard layout has more than %d keys", maxWalkKeys)
+	}
+
+	// nearest finds the key of row r whose centre is lo to hi quarter keys
+	// right of x.
+	nearest := func(r, x, lo, hi int) int {
+		if r < 0 || r >= len(rows) {
+			return -1
+		}
+		for _, k := range rows[r] {
+			if dx := positions[k].x - x; dx >= lo && dx <= hi {
+				return k
+			}
+		}
+		return -1
+	}
+	b.next = make([][numDirections]int, len(b.keys))
+	for k, p := range positions {
+		b.next[k] = [numDirections]int{
+			dirRight:     nearest(p.row, p.x, 4, 4),
+			dirDownRight: nearest(p.row+1, p.x, 0, 3),
+			dirDownLeft:  nearest(p.row+1, p.x, -3, -1),
+			dirLeft:      nearest(p.row, p.x, -4, -4),
+			dirUpRight:   nearest(p.row-1, p.x, 0, 3),
+			dirUpLeft:    nearest(p.row-1, p.x, -3, -1),
+		}
+	}
+	b.dist = make([][]int, len(b.keys))
+	for from := range b.keys {
+		dist := make([]int, len(b.keys))
+		for k := range dist {
+			dist[k] = -1
+		}
+		dist[from] = 0
+		for queue := []int{from}; len(queue) > 0; queue = queue[1:] {
+			k := queue[0]
+			for _, n := range b.next[k] {
+				if n >= 0 && dist[n] < 0 {
+					dist[n] = dist[k] + 1
+					queue = append(queue, n)
+				}
+			}
+		}
+		b.dist[from] = dist
+	}
+	return b, nil
+}
+
+// move steps every key of cur in direction d into next, and reports false
+// when one of them would leave the keyboard.
+func (b *keyboard) move(cur []int, d int, next []int) bool {
+	for j, k := range cur {
+		if next[j] = b.next[k][d]; next[j] < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// turnsAfter returns the turns left after stepping in direction d having
+// last moved in dir. Doubling straight back is not a walk.
+func turnsAfter(dir, d, turns int) (int, bool) {
+	switch {
+	case dir == noDirection || d == dir:
+		return turns, true
+	case d == oppositeDirection[dir] || turns == 0:
+		return 0, false
+	}
+	return turns - 1, true
+}
+
+// walkCount counts the ways to take steps more steps from the keys in cur,
+// one per copy of the walk, all moving alike, having last moved in dir and
+// changing direction exactly turns more times.
+func (b *keyboard) walkCount(cur []int, dir, steps, turns int) uint64 {
+	if steps == 0 {
+		if turns == 0 {
+			return 1
+		}
+		return 0
+	}
+	if turns > steps {
+		return 0
+	}
+	key := walkKey(cur, dir, steps, turns)
+	if n, ok := b.memo[key]; ok {
+		return n
+	}
+	var n uint64
+	next := make([]int, len(cur))
+	for d := 0; d < numDirections; d++ {
+		left, ok := turnsAfter(dir, d, turns)
+		if ok && b.move(cur, d, next) {
+			n = addSaturating64(n, b.walkCount(next, d, steps-1, left))
+		}
+	}
+	b.memo[key] = n
+	return n
+}
+
+// walkKey packs a walkCount state into 48 bits.
+func walkKey(cur []int, dir, steps, turns int) uint64 {
+	key := uint64(dir) | uint64(steps)<<3 | uint64(turns)<<9
+	for j, k := range cur {
+		key |= uint64(k+1) << (16 + 8*j)
+	}
+	return key
+}
+
+// KeyboardConfig describes keyboard walks such as "qwerty", "1qaz2wsx" and
+// "zxcvbn".
+type KeyboardConfig struct {
+	Layouts []*KeyboardLayout
+	// MinLength and MaxLength bound the keys pressed in the whole walk.
+	MinLength int
+	MaxLength int
+	// MaxTurns is how many times a walk may change direction.
+	MaxTurns int
+	// MaxCopies is how many times the walk may be repeated side by side,
+	// as "1qaz" then "2wsx"; 1 means a single walk.
+	MaxCopies int
+	// Shift lists the shift styles: none, first (the first key), all, and
+	// copies (every other copy). Empty means none.
+	Shift []string
+}
+
+// KeyboardKeyspace numbers keyboard walks. Walks with fewer turns and
+// copies come first, shorter before longer, each in every layout. Copies
+// that also read as a single walk, as "1q" then "2w" does, are numbered
+// only as the single walk.
+type KeyboardKeyspace struct {
+	parts    []*walkPart
+	size     uint64
+	overflow bool
+}
+
+// walkPart is the walks of one shape: its layout, keys per copy, turns,
+// copies and the direction from one copy's start to the next.
+type walkPart struct {
+	board  *keyboard
+	keys   int
+	turns  int
+	copies int
+	offset int
+	shift  string
+	// seamTurns is how many turns joining the copies end to end may add
+	// for the result to be one of the single walks, or -1 when copies never
+	// repeat a single walk. Such walks are left to the single walk.
+	seamTurns int
+	// seamMemo caches seamCount for the walks from seamStart only, as a
+	// memo over every start would grow large; walks are sized, and At is
+	// called, in order from one goroutine.
+	seamMemo  map[uint64]uint64
+	seamStart int
+	// starts holds the walks from each start key.
+	starts []uint64
+	size   uint64
+}
+
+// maxWalkCopies bounds the copies of a walk, which share the memo key.
+const maxWalkCopies = 4
+
+func NewKeyboardKeyspace(config KeyboardConfig) (*KeyboardKeyspace, error) {
+	if config.MinLength < 1 || config.MaxLength < config.MinLength || config.MaxLength > 32 {
+		return nil, fmt.Errorf("keyboard walks need lengths from 1 to 32")
+	}
+	if config.MaxTurns < 0 || config.MaxTurns > 31 {
+		return nil, fmt.Errorf("keyboard walks allow 0 to 31 turns")
+	}
+	if config.MaxCopies < 1 || config.MaxCopies > maxWalkCopies {
+		return nil, fmt.Errorf("keyboard walks allow 1 to %d copies", maxWalkCopies)
+	}
+	if len(config.Layouts) == 0 {
+		return nil, fmt.Errorf("keyboard walks need a layout")
+	}
+	shifts := config.Shift
+	if len(shifts) == 0 {
+		shifts = []string{ShiftNone}
+	}
+	for _, s := range shifts {
+		switch s {
+		case ShiftNone, ShiftFirst, ShiftAll, ShiftCopies:
+		default:
+			return nil, fmt.Errorf("unknown shift style %q (use none, first, all or copies)", s)
+		}
+	}
+	boards := make([]*keyboard, len(config.Layouts))
+	for i, layout := range config.Layouts {
+		var err error
+		if boards[i], err = newKeyboard(layout); err != nil {
+			return nil, err
+		}
+	}
+
+	ks := &KeyboardKeyspace{}
+	for _, shift := range shifts {
+		for copies := 1; copies <= config.MaxCopies; copies++ {
+			if shift == ShiftCopies && copies == 1 {
+				continue
+			}
+			for turns := 0; turns <= config.MaxTurns; turns++ {
+				for length := config.MinLength; length <= config.MaxLength; length++ {
+					keys := length / copies
+					if length%copies != 0 || copies > 1 && keys < 2 {
+						continue
+					}
+					offsets := []int{noDirection}
+					if copies > 1 {
+						offsets = []int{dirRight, dirDownRight, dirDownLeft, dirLeft, dirUpRight, dirUpLeft}
+					}
+					for _, offset := range offsets {
+						for _, board := range boards {
+							part := &walkPart{board: board, keys: keys, turns: turns, copies: copies, offset: offset, shift: shift, seamTurns: -1, seamStart: -1}
+							// Copies joined end to end turn at least as often as
+							// they do apart, so only spare turns can make them
+							// a single walk.
+							if copies > 1 && shift != ShiftCopies && config.MaxTurns >= copies*turns {
+								part.seamTurns = config.MaxTurns - copies*turns
+								part.seamMemo = make(map[uint64]uint64)
+							}
+							part.init()
+							if part.size == 0 {
+								continue
+							}
+							ks.parts = append(ks.parts, part)
+							if ks.size+part.size < ks.size {
+								ks.size = math.MaxUint64
+								ks.overflow = true
+							} else if !ks.overflow {
+								ks.size += part.size
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	return ks, nil
+}
+
+func (ks *KeyboardKeyspace) Size() uint64 {
+	return ks.size
+}
+
+// Overflow reports more than 2^64 walks; Size then saturates.
+func (ks *KeyboardKeyspace) Overflow() bool {
+	return ks.overflow
+}
+
+func (ks *KeyboardKeyspace) At(index uint64) string {
+	for _, part := range ks.parts {
+		if index < part.size {
+			return part.at(index)
+		}
+		index -= part.size
+	}
+	return ""
+}
+
+// copyStarts returns the start key of each copy when the first starts at
+// key, or nil when a copy would start off the keyboard.
+func (p *walkPart) copyStarts(key int) []int {
+	cur := []int{key}
+	for j := 1; j < p.copies; j++ {
+		next := p.board.next[cur[j-1]][p.offset]
+		if next < 0 {
+			return nil
+		}
+		cur = append(cur, next)
+	}
+	return cur
+}
+
+// directions lists the directions a step may take. A straight walk repeated
+// along its own line would be a longer straight walk, so its first step
+// must leave that line.
+func (p *walkPart) directions(first bool) []int {
+	var dirs []int
+	for d := 0; d < numDirections; d++ {
+		if first && p.copies > 1 && p.turns == 0 && (d == p.offset || d == oppositeDirection[p.offset]) {
+			continue
+		}
+		dirs = append(dirs, d)
+	}
+	return dirs
+}
+
+// walkCount is the board's walkCount less the walks that seamCount finds
+// repeat a single walk. starts and first are the keys and direction the
+// copies started with.
+func (p *walkPart) walkCount(starts []int, first int, cur []int, dir, steps, turns int) uint64 {
+	n := p.board.walkCount(cur, dir, steps, turns)
+	if p.seamTurns < 0 || n == math.MaxUint64 {
+		return n
+	}
+	return n - p.seamCount(starts, first, cur, dir, steps, turns)
+}
+
+// seamCount counts the walks among walkCount's after which each copy can
+// step on to the start of the next, so that the copies read as one walk of
+// at most MaxTurns turns: "1q" then "2w" is also the single walk "1q2w".
+func (p *walkPart) seamCount(starts []int, first int, cur []int, dir, steps, turns int) uint64 {
+	if steps == 0 {
+		if turns == 0 && p.seamed(starts, first, cur, dir) {
+			return 1
+		}
+		return 0
+	}
+	// The first copy must end next to where the second starts.
+	if d := p.board.dist[cur[0]][starts[1]]; turns > steps || d < 0 || d > steps+1 {
+		return 0
+	}
+	if starts[0] != p.seamStart {
+		clear(p.seamMemo)
+		p.seamStart = starts[0]
+	}
+	key := walkKey(cur, dir, steps, turns) | uint64(first)<<48
+	if n, ok := p.seamMemo[key]; ok {
+		return n
+	}
+	var n uint64
+	next := make([]int, len(cur))
+	for d := 0; d < numDirections; d++ {
+		left, ok := turnsAfter(dir, d, turns)
+		if ok && p.board.move(cur, d, next) {
+			n = addSaturating64(n, p.seamCount(starts, first, next, d, steps-1, left))
+		}
+	}
+	p.seamMemo[key] = n
+	return n
+}
+
+// seamed reports whether copies that started at starts moving in direction
+// first and ended at cur moving in direction dir join into one walk within
+// the part's spare turns.
+func (p *walkPart) seamed(starts []int, first int, cur []int, dir int) bool {
+	turns := p.seamTurns
+	for j := 0; j+1 < len(cur); j++ {
+		e := 0
+		for e < numDirections && p.board.next[cur[j]][e] != starts[j+1] {
+			e++
+		}
+		if e == numDirections {
+			return false
+		}
+		var ok bool
+		if turns, ok = turnsAfter(dir, e, turns); !ok {
+			return false
+		}
+		if turns, ok = turnsAfter(e, first, turns); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *walkPart) init() {
+	p.starts = make([]uint64, len(p.board.keys))
+	for key := range p.board.keys {
+		cur := p.copyStarts(key)
+		if cur == nil {
+			continue
+		}
+		var n uint64
+		if p.keys == 1 {
+			n = p.board.walkCount(cur, noDirection, 0, p.turns)
+		} else {
+			next := make([]int, len(cur))
+			for _, d := range p.directions(true) {
+				if p.board.move(cur, d, next) {
+					n = addSaturating64(n, p.walkCount(cur, d, next, d, p.keys-2, p.turns))
+				}
+			}
+		}
+		p.starts[key] = n
+		p.size = addSaturating64(p.size, n)
+	}
+}
+
+func (p *walkPart) at(index uint64) string {
+	b := p.board
+	var cur []int
+	for key, n := range p.starts {
+		if index < n {
+			cur = p.copyStarts(key)
+			break
+		}
+		index -= n
+	}
+	walk := [][]int{cur}
+
+	firstDir := noDirection
+	dir, turns := noDirection, p.turns
+	for step := 1; step < p.keys; step++ {
+		next := make([]int, len(cur))
+		for _, d := range p.directions(step == 1) {
+			left, ok := turnsAfter(dir, d, turns)
+			if !ok || !b.move(cur, d, next) {
+				continue
+			}
+			first := d
+			if step > 1 {
+				first = firstDir
+			}
+			n := p.walkCount(walk[0], first, next, d, p.keys-1-step, left)
+			if index < n {
+				cur, dir, turns = next, d, left
+				if step == 1 {
+					firstDir = d
+				}
+				break
+			}
+			index -= n
+		}
+		walk = append(walk, cur)
+	}
+
+	var sb strings.Builder
+	for j := 0; j < p.copies; j++ {
+		for i, keys := range walk {
+			shifted := p.shift == ShiftAll ||
+				p.shift == ShiftFirst && i == 0 && j == 0 ||
+				p.shift == ShiftCopies && j%2 == 1
+			if shifted {
+				sb.WriteRune(b.shifted[keys[j]])
+			} else {
+				sb.WriteRune(b.keys[keys[j]])
+			}
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,98 @@
+package attacks
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestKeyboardWalks(t *testing.T) {
+	us, err := LoadKeyboardLayout("us")
+	if err != nil {
+		t.Fatal(err)
+	}
+	qwertz, err := LoadKeyboardLayout("QWERTZ")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ks, err := NewKeyboardKeyspace(KeyboardConfig{Layouts: []*KeyboardLayout{us}, MinLength: 2, MaxLength: 8, MaxTurns: 2, MaxCopies: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// With two turns "1q2w" is a single walk as well as two copies of "1q";
+	// it must come once.
+	all := keyspaceAll(t, ks)
+	seen := make(map[string]bool)
+	for _, w := range all {
+		if seen[w] {
+			t.Errorf("%q repeats", w)
+		}
+		seen[w] = true
+	}
+	for _, want := range []string{"qwerty", "ytrewq", "1qaz", "zaq1", "1qaz2wsx", "zxcvbn", "asdf", "1q2w", "qazwsx", "qaws", "4esz"} {
+		if !seen[want] {
+			t.Errorf("missing %q", want)
+		}
+	}
+	for _, unwanted := range []string{"q", "qwq", "q1qa", "1q2w3e"} {
+		if seen[unwanted] {
+			t.Errorf("unexpected %q", unwanted)
+		}
+	}
+	index := make(map[string]int)
+	for i, w := range all {
+		index[w] = i
+	}
+	if index["qwertyui"] > index["1qaz2wsx"] {
+		t.Error("repeated walks come before single ones")
+	}
+
+	ks, err = NewKeyboardKeyspace(KeyboardConfig{Layouts: []*KeyboardLayout{us}, MinLength: 4, MaxLength: 4, MaxTurns: 1, MaxCopies: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	turns := keyspaceAll(t, ks)
+	if got := strings.Join(turns, ","); !strings.Contains(got, "qwsx") || strings.Contains(got, "qwsz") {
+		t.Errorf("one-turn walks = %q", got)
+	}
+
+	ks, err = NewKeyboardKeyspace(KeyboardConfig{
+		Layouts:   []*KeyboardLayout{qwertz},
+		MinLength: 6, MaxLength: 6, MaxCopies: 2,
+		Shift: []string{ShiftFirst, ShiftCopies},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	shifted := keyspaceAll(t, ks)
+	got := strings.Join(shifted, ",")
+	for _, want := range []string{"Qwertz", "!23456", `1qa"WS`} {
+		if !strings.Contains(","+got+",", ","+want+",") {
+			t.Errorf("missing %q", want)
+		}
+	}
+}
+
+func TestKeyboardLayoutFile(t *testing.T) {
+	layout, err := ReadKeyboardLayout(strings.NewReader("# keypad\n0 789 789\n\n0 456 456\n0 123 123\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ks, err := NewKeyboardKeyspace(KeyboardConfig{Layouts: []*KeyboardLayout{layout}, MinLength: 3, MaxLength: 3, MaxCopies: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	all := keyspaceAll(t, ks)
+	if got := strings.Join(all, ","); !strings.HasPrefix(got, "789,741,") || !strings.Contains(got, ",963,") {
+		t.Errorf("keypad walks = %q", got)
+	}
+
+	for _, bad := range []string{"", "0 abc ABCD", "x abc ABC", "0 abc"} {
+		if _, err := ReadKeyboardLayout(strings.NewReader(bad)); err == nil {
+			t.Errorf("%q: no error", bad)
+		}
+	}
+	if _, err := NewKeyboardKeyspace(KeyboardConfig{Layouts: []*KeyboardLayout{layout}, MinLength: 3, MaxLength: 3, MaxCopies: 1, Shift: []string{"caps"}}); err == nil {
+		t.Error("unknown shift style accepted")
+	}
+}
@@ -0,0 +1,148 @@
+package attacks
+
+import (
+	"bufio"
+	"context"
+	"math"
+)
+
+type Keyspace interface {
+	Size() uint64
+	At(index uint64) string
+}
+
+type IncrementalKeyspace struct {
+	charset  []byte
+	minLen   int
+	counts   []uint64
+	size     uint64
+	overflow bool
+}
+
+func NewIncrementalKeyspace(config IncrementalConfig) *IncrementalKeyspace {
+	charset := []byte(config.Charset)
+	if len(charset) == 0 {
+		charset = []byte(CharsetAlphaNum)
+	}
+
+	minLen, maxLen := normalizeLengths(config.MinLength, config.MaxLength)
+	ks := &IncrementalKeyspace{
+		charset: charset,
+		minLen:  minLen,
+	}
+
+	ks.counts, ks.overflow = lengthCounts(len(charset), minLen, maxLen)
+	if ks.overflow {
+		ks.size = math.MaxUint64
+		return ks
+	}
+	for _, count := range ks.counts {
+		ks.size += count
+	}
+
+	return ks
+}
+
+// Overflow reports that the keyspace has more than 2^64 candidates, in which
+// case Size saturates and At must not be used.
+func (ks *IncrementalKeyspace) Overflow() bool {
+	return ks.overflow
+}
+
+func (ks *IncrementalKeyspace) Size() uint64 {
+	return ks.size
+}
+
+func (ks *IncrementalKeyspace) At(index uint64) string {
+	length := ks.minLen
+	for _, count := range ks.counts {
+		if index < count {
+			break
+		}
+		index -= count
+		length++
+	}
+
+	base := uint64(len(ks.charset))
+	password := make([]byte, length)
+	for pos := length - 1; pos >= 0; pos-- {
+		password[pos] = ks.charset[index%base]
+		index /= base
+	}
+	return string(password)
+}
+
+func KeyspaceGenerator(ctx context.Context, ks Keyspace, start, end uint64) <-chan string {
+	ch := make(chan string, 10000)
+
+	go func() {
+		defer close(ch)
+
+		if end > ks.Size() {
+			end = ks.Size()
+		}
+
+		for i := start; i < end; i++ {
+			select {
+			case <-ctx.Done():
+				return
+			case ch <- ks.At(i):
+			}
+		}
+	}()
+
+	return ch
+}
+
+func WordlistRangeGenerator(ctx context.Context, filename string, start, end uint64) (<-chan string, error) {
+	f, err := openWordlistFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan string, 1000)
+
+	go func() {
+		defer close(ch)
+		defer f.Close()
+
+		scanner := bufio.NewScanner(f)
+		buf := make([]byte, 0, 64*1024)
+		scanner.Buffer(buf, 1024*1024)
+
+		var line uint64
+		for scanner.Scan() {
+			if line >= end {
+				return
+			}
+			if line >= start {
+				select {
+				case <-ctx.Done():
+					return
+				case ch <- cleanLine(scanner.Text(), line == 0):
+				}
+			}
+			line++
+		}
+	}()
+
+	return ch, nil
+}
+
+func CountLines(filename string) (uint64, error) {
+	f, err := openWordlistFile(filename)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	buf := make([]byte, 0, 64*1024)
+	scanner.Buffer(buf, 1024*1024)
+
+	var lines uint64
+	for scanner.Scan() {
+		lines++
+	}
+	return lines, scanner.Err()
+}
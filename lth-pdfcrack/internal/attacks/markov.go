@@ -0,0 +1,393 @@
+package attacks
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	_ "embed"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"math/bits"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// MarkovPositions is the number of positions with their own statistics.
+// Later positions use the statistics of the last one.
+const MarkovPositions = 16
+
+const markovMagic = "PDFCRACK-MARKOV1"
+
+//go:embed markov.txt
+var markovCorpus string
+
+// MarkovStats counts, for each position, how often each character appears
+// there (Root) and how often it follows each previous character (Chain).
+type MarkovStats struct {
+	Root  [MarkovPositions][256]uint32
+	Chain [MarkovPositions][256][256]uint32
+}
+
+// Add counts the characters of word.
+func (s *MarkovStats) Add(word string) {
+	for p := 0; p < len(word) && p < MarkovPositions; p++ {
+		c := word[p]
+		s.Root[p][c] = addSaturating(s.Root[p][c])
+		if p > 0 {
+			prev := word[p-1]
+			s.Chain[p][prev][c] = addSaturating(s.Chain[p][prev][c])
+		}
+	}
+}
+
+func addSaturating(n uint32) uint32 {
+	if n == math.MaxUint32 {
+		return n
+	}
+	return n + 1
+}
+
+// TrainMarkov builds statistics from a wordlist, one password per line.
+func TrainMarkov(r io.Reader) (*MarkovStats, uint64, error) {
+	s := &MarkovStats{}
+	scanner := bufio.NewScanner(r)
+	buf := make([]byte, 0, 64*1024)
+	scanner.Buffer(buf, 1024*1024)
+
+	var words uint64
+	for scanner.Scan() {
+		word := strings.TrimRight(scanner.Text(), "\r")
+		if word == "" {
+			continue
+		}
+		s.Add(word)
+		words++
+	}
+	return s, words, scanner.Err()
+}
+
+// Write stores the statistics gzip-compressed; most counts are zero.
+func (s *MarkovStats) Write(w io.Writer) error {
+	zw := gzip.NewWriter(w)
+	if _, err := io.WriteString(zw, markovMagic); err != nil {
+		return err
+	}
+	if err := binary.Write(zw, binary.LittleEndian, s); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func ReadMarkovStats(r io.Reader) (*MarkovStats, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("not a markov statistics file: %w", err)
+	}
+	defer zr.Close()
+
+	magic := make([]byte, len(markovMagic))
+	if _, err := io.ReadFull(zr, magic); err != nil || string(magic) != markovMagic {
+		return nil, fmt.Errorf("not a markov statistics file")
+	}
+	s := &MarkovStats{}
+	if err := binary.Read(zr, binary.LittleEndian, s); err != nil {
+		return nil, fmt.Errorf("truncated markov statistics: %w", err)
+	}
+	return s, nil
+}
+
+// LoadMarkovStats reads a statistics file, or returns the built-in
+// statistics when path is empty.
+func LoadMarkovStats(path string) (*MarkovStats, error) {
+	if path == "" {
+		return DefaultMarkovStats(), nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	s, err := ReadMarkovStats(bufio.NewReader(f))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return s, nil
+}
+
+var (
+	defaultMarkovOnce  sync.Once
+	defaultMarkovStats *MarkovStats
+)
+
+// DefaultMarkovStats is trained from a small built-in list of common
+// passwords.
+func DefaultMarkovStats() *MarkovStats {
+	defaultMarkovOnce.Do(func() {
+		defaultMarkovStats, _, _ = TrainMarkov(strings.NewReader(markovCorpus))
+	})
+	return defaultMarkovStats
+}
+
+type MarkovConfig struct {
+	Charset   string
+	MinLength int
+	MaxLength int
+	// Threshold keeps only the most likely characters at each position.
+	// Zero keeps the whole charset, so the full keyspace is covered.
+	Threshold int
+	Stats     *MarkovStats
+}
+
+// MarkovKeyspace orders candidates by likelihood. At each position the
+// charset is ranked by how often each character follows the previous one,
+// and a candidate is identified by its ranks. Within a length, candidates
+// with a lower rank sum come first, so the most likely character at every
+// position is tried before anything else and the least likely last. Every
+// candidate still has an index, so runs can be split and resumed.
+type MarkovKeyspace struct {
+	minLen int
+	maxLen int
+	base   int
+	// root[p] and chain[p][prev] list the charset from most to least
+	// likely.
+	root  [MarkovPositions][]byte
+	chain [MarkovPositions][256][]byte
+	// ways[k][s] is the number of rank vectors of length k summing to s,
+	// and cum[n][s] the number for length n with a sum below s.
+	ways     [][]uint64
+	cum      [][]uint64
+	counts   []uint64
+	size     uint64
+	overflow bool
+}
+
+func NewMarkovKeyspace(config MarkovConfig) *MarkovKeyspace {
+	charset := dedupeBytes(config.Charset)
+	if len(charset) == 0 {
+		charset = []byte(CharsetAlphaNum)
+	}
+	stats := config.Stats
+	if stats == nil {
+		stats = DefaultMarkovStats()
+	}
+
+	minLen, maxLen := normalizeLengths(config.MinLength, config.MaxLength)
+	ks := &MarkovKeyspace{minLen: minLen, maxLen: maxLen, base: len(charset)}
+	if config.Threshold > 0 && config.Threshold < ks.base {
+		ks.base = config.Threshold
+	}
+
+	for p := 0; p < MarkovPositions; p++ {
+		ks.root[p] = rankCharset(charset, stats.Root[p][:], ks.base)
+		if p == 0 {
+			continue
+		}
+		for _, prev := range charset {
+			ks.chain[p][prev] = rankCharset(charset, stats.Chain[p][prev][:], ks.base)
+		}
+	}
+
+	maxSum := maxLen * (ks.base - 1)
+	ks.ways = make([][]uint64, maxLen+1)
+	for k := range ks.ways {
+		ks.ways[k] = make([]uint64, maxSum+1)
+	}
+	ks.ways[0][0] = 1
+	for k := 1; k <= maxLen; k++ {
+		for s := 0; s <= k*(ks.base-1); s++ {
+			var n uint64
+			for r := 0; r < ks.base && r <= s; r++ {
+				n = addSaturating64(n, ks.ways[k-1][s-r])
+			}
+			ks.ways[k][s] = n
+		}
+	}
+
+	ks.cum = make([][]uint64, maxLen+1)
+	for n := minLen; n <= maxLen; n++ {
+		top := n * (ks.base - 1)
+		ks.cum[n] = make([]uint64, top+2)
+		for s := 0; s <= top; s++ {
+			ks.cum[n][s+1] = addSaturating64(ks.cum[n][s], ks.ways[n][s])
+		}
+		count := ks.cum[n][top+1]
+		if count == math.MaxUint64 {
+			ks.overflow = true
+		}
+		ks.counts = append(ks.counts, count)
+		ks.size = addSaturating64(ks.size, count)
+		if ks.size == math.MaxUint64 {
+			ks.overflow = true
+		}
+	}
+	return ks
+}
+
+func addSaturating64(a, b uint64) uint64 {
+	sum, carry := bits.Add64(a, b, 0)
+	if carry != 0 {
+		return math.MaxUint64
+	}
+	return sum
+}
+
+func dedupeBytes(s string) []byte {
+	var out []byte
+	var seen [256]bool
+	for i := 0; i < len(s); i++ {
+		if !seen[s[i]] {
+			seen[s[i]] = true
+			out = append(out, s[i])
+		}
+	}
+	return out
+}
+
+// rankCharset sorts charset by count, most frequent first, keeping charset
+// order for ties, and keeps the first n.
+func rankCharset(charset []byte, counts []uint32, n int) []byte {
+	ranked := append([]byte(nil), charset...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return counts[ranked[i]] > counts[ranked[j]]
+	})
+	return ranked[:n]
+}
+
+func (ks *MarkovKeyspace) Size() uint64 {
+	return ks.size
+}
+
+// Overflow reports that the keyspace has more than 2^64 candidates.
+func (ks *MarkovKeyspace) Overflow() bool {
+	return ks.overflow
+}
+
+func (ks *MarkovKeyspace) At(index uint64) string {
+	return ks.candidate(ks.ranksAt(index))
+}
+
+// ranksAt decodes index into a rank for each position.
+func (ks *MarkovKeyspace) ranksAt(index uint64) []int {
+	n := ks.minLen
+	for _, count := range ks.counts {
+		if index < count || n == ks.maxLen {
+			break
+		}
+		index -= count
+		n++
+	}
+
+	// The first sum whose cumulative count passes index.
+	cum := ks.cum[n]
+	s := sort.Search(len(cum)-1, func(s int) bool { return cum[s+1] > index })
+	index -= cum[s]
+
+	ranks := make([]int, n)
+	for p := 0; p < n; p++ {
+		left := n - p - 1
+		for r := 0; r < ks.base && r <= s; r++ {
+			c := ks.ways[left][s-r]
+			if index < c {
+				ranks[p] = r
+				s -= r
+				break
+			}
+			index -= c
+		}
+	}
+	return ranks
+}
+
+// nextRanks advances ranks to the next candidate in keyspace order and
+// returns false after the last one.
+func (ks *MarkovKeyspace) nextRanks(ranks []int) ([]int, bool) {
+	n := len(ranks)
+	top := ks.base - 1
+	tail := 0
+	// Find the rightmost position that can grow by one while the positions
+	// after it give one back.
+	for i := n - 1; i >= 0; i-- {
+		if i < n-1 && ranks[i] < top && tail > 0 {
+			ranks[i]++
+			fillLowest(ranks[i+1:], tail-1, top)
+			return ranks, true
+		}
+		tail += ranks[i]
+	}
+
+	// Same length, next sum.
+	if sum := tail + 1; sum <= n*top {
+		fillLowest(ranks, sum, top)
+		return ranks, true
+	}
+	if n < ks.maxLen {
+		return make([]int, n+1), true
+	}
+	return nil, false
+}
+
+// fillLowest sets ranks to the first vector in order that sums to sum: the
+// weight is pushed as far right as it goes.
+func fillLowest(ranks []int, sum, top int) {
+	for j := range ranks {
+		room := (len(ranks) - j - 1) * top
+		ranks[j] = 0
+		if sum > room {
+			ranks[j] = sum - room
+		}
+		sum -= ranks[j]
+	}
+}
+
+func (ks *MarkovKeyspace) candidate(ranks []int) string {
+	password := make([]byte, len(ranks))
+	for p, r := range ranks {
+		switch {
+		case p == 0:
+			password[p] = ks.root[0][r]
+		case p < MarkovPositions:
+			password[p] = ks.chain[p][password[p-1]][r]
+		default:
+			password[p] = ks.chain[MarkovPositions-1][password[p-1]][r]
+		}
+	}
+	return string(password)
+}
+
+// Generator produces the candidates at positions [start, end). It decodes
+// start once and then steps through the ranks, which is much cheaper than
+// decoding every index.
+func (ks *MarkovKeyspace) Generator(ctx context.Context, start, end uint64) <-chan string {
+	ch := make(chan string, 10000)
+
+	go func() {
+		defer close(ch)
+
+		if end > ks.size {
+			end = ks.size
+		}
+		if start >= end {
+			return
+		}
+
+		ranks := ks.ranksAt(start)
+		for i := start; i < end; i++ {
+			select {
+			case <-ctx.Done():
+				return
+			case ch <- ks.candidate(ranks):
+			}
+			var ok bool
+			if ranks, ok = ks.nextRanks(ranks); !ok {
+				return
+			}
+		}
+	}()
+
+	return ch
+}
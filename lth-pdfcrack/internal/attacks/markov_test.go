@@ -0,0 +1,80 @@
+package attacks
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestMarkovKeyspace(t *testing.T) {
+	stats, words, err := TrainMarkov(strings.NewReader("pass\npass\npast\r\n\nsale\n"))
+	if err != nil || words != 4 {
+		t.Fatalf("TrainMarkov = %d words, %v", words, err)
+	}
+
+	ks := NewMarkovKeyspace(MarkovConfig{Charset: CharsetLower, MinLength: 4, MaxLength: 4, Stats: stats})
+	if ks.Size() != 26*26*26*26 {
+		t.Fatalf("Size() = %d", ks.Size())
+	}
+	if got := ks.At(0); got != "pass" {
+		t.Errorf("At(0) = %q, want pass", got)
+	}
+
+	// The generator steps through the same order At decodes and covers
+	// every candidate exactly once.
+	seen := make(map[string]bool)
+	var i uint64
+	for c := range ks.Generator(context.Background(), 0, ks.Size()) {
+		if i%997 == 0 && c != ks.At(i) {
+			t.Fatalf("candidate %d = %q, At = %q", i, c, ks.At(i))
+		}
+		if seen[c] {
+			t.Fatalf("duplicate candidate %q", c)
+		}
+		seen[c] = true
+		i++
+	}
+	if i != ks.Size() {
+		t.Errorf("generated %d candidates, want %d", i, ks.Size())
+	}
+	if !seen["zzzz"] {
+		t.Error("zzzz missing from the full keyspace")
+	}
+}
+
+func TestMarkovThreshold(t *testing.T) {
+	stats, _, _ := TrainMarkov(strings.NewReader("ab\nab\nba\n"))
+	ks := NewMarkovKeyspace(MarkovConfig{Charset: "abc", MinLength: 1, MaxLength: 2, Threshold: 2, Stats: stats})
+	if ks.Size() != 2+4 {
+		t.Fatalf("Size() = %d, want 6", ks.Size())
+	}
+
+	var got []string
+	for c := range ks.Generator(context.Background(), 1, ks.Size()) {
+		got = append(got, c)
+	}
+	// "a" starts more words; "b" is likelier after "a" and "a" after "b".
+	want := "b,ab,aa,ba,bb"
+	if strings.Join(got, ",") != want {
+		t.Errorf("candidates = %s, want %s", strings.Join(got, ","), want)
+	}
+}
+
+func TestMarkovStatsRoundTrip(t *testing.T) {
+	stats, _, _ := TrainMarkov(strings.NewReader("hello\nworld\n"))
+	var buf bytes.Buffer
+	if err := stats.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadMarkovStats(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *got != *stats {
+		t.Error("statistics changed in a write/read round trip")
+	}
+	if _, err := ReadMarkovStats(strings.NewReader("junk")); err == nil {
+		t.Error("junk accepted as statistics")
+	}
+}
@@ -0,0 +1,221 @@
+package attacks
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+)
+
+// CharsetMaskSpecial is hashcat's ?s: printable ASCII symbols and space.
+const CharsetMaskSpecial = " !\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~"
+
+type MaskConfig struct {
+	Mask string
+	// Custom holds the user charsets referenced as ?1 to ?4.
+	Custom [4]string
+	// Increment tries every prefix of the mask from IncrementMin to
+	// IncrementMax positions, shortest first. Zero means 1 and the full mask.
+	Increment    bool
+	IncrementMin int
+	IncrementMax int
+}
+
+func builtinMaskCharset(c byte) (string, bool) {
+	switch c {
+	case 'l':
+		return CharsetLower, true
+	case 'u':
+		return CharsetUpper, true
+	case 'd':
+		return CharsetDigits, true
+	case 's':
+		return CharsetMaskSpecial, true
+	case 'a':
+		return CharsetLower + CharsetUpper + CharsetDigits + CharsetMaskSpecial, true
+	case 'h':
+		return "0123456789abcdef", true
+	case 'H':
+		return "0123456789ABCDEF", true
+	case 'b':
+		all := make([]byte, 256)
+		for i := range all {
+			all[i] = byte(i)
+		}
+		return string(all), true
+	}
+	return "", false
+}
+
+// expandCustomCharset resolves built-in placeholders inside a user charset,
+// so -1 '?l?d' means lowercase letters and digits. Duplicates are dropped.
+func expandCustomCharset(spec string) (string, error) {
+	var out []byte
+	seen := make(map[byte]bool)
+	add := func(s string) {
+		for i := 0; i < len(s); i++ {
+			if !seen[s[i]] {
+				seen[s[i]] = true
+				out = append(out, s[i])
+			}
+		}
+	}
+
+	for i := 0; i < len(spec); i++ {
+		if spec[i] != '?' {
+			add(spec[i : i+1])
+			continue
+		}
+		if i+1 >= len(spec) {
+			return "", fmt.Errorf("charset %q ends with '?'", spec)
+		}
+		i++
+		if spec[i] == '?' {
+			add("?")
+			continue
+		}
+		cs, ok := builtinMaskCharset(spec[i])
+		if !ok {
+			return "", fmt.Errorf("unknown placeholder ?%c in charset %q", spec[i], spec)
+		}
+		add(cs)
+	}
+	return string(out), nil
+}
+
+// ParseMask returns the charset for each position of mask. Placeholders are
+// ?l ?u ?d ?s ?a ?h ?H ?b and ?1 to ?4; ?? is a literal '?' and any other
+// character stands for itself.
+func ParseMask(mask string, custom [4]string) ([]string, error) {
+	var positions []string
+	for i := 0; i < len(mask); i++ {
+		if mask[i] != '?' {
+			positions = append(positions, mask[i:i+1])
+			continue
+		}
+		if i+1 >= len(mask) {
+			return nil, fmt.Errorf("mask %q ends with '?'", mask)
+		}
+		i++
+		c := mask[i]
+		switch {
+		case c == '?':
+			positions = append(positions, "?")
+		case c >= '1' && c <= '4':
+			spec := custom[c-'1']
+			if spec == "" {
+				return nil, fmt.Errorf("mask uses ?%c but custom charset %c is not set", c, c)
+			}
+			cs, err := expandCustomCharset(spec)
+			if err != nil {
+				return nil, err
+			}
+			positions = append(positions, cs)
+		default:
+			cs, ok := builtinMaskCharset(c)
+			if !ok {
+				return nil, fmt.Errorf("unknown placeholder ?%c in mask %q", c, mask)
+			}
+			positions = append(positions, cs)
+		}
+	}
+	if len(positions) == 0 {
+		return nil, fmt.Errorf("empty mask")
+	}
+	return positions, nil
+}
+
+// MaskKeyspace indexes every candidate of a mask, including the shorter
+// masks of an increment run, so a mask can be split into chunks and resumed
+// at any position. Within one length the last position changes fastest.
+type MaskKeyspace struct {
+	positions [][]byte
+	minLen    int
+	counts    []uint64
+	size      uint64
+	overflow  bool
+}
+
+func NewMaskKeyspace(config MaskConfig) (*MaskKeyspace, error) {
+	charsets, err := ParseMask(config.Mask, config.Custom)
+	if err != nil {
+		return nil, err
+	}
+
+	minLen, maxLen := len(charsets), len(charsets)
+	if config.Increment {
+		minLen = 1
+		if config.IncrementMin > 0 {
+			minLen = config.IncrementMin
+		}
+		if config.IncrementMax > 0 && config.IncrementMax < maxLen {
+			maxLen = config.IncrementMax
+		}
+		if minLen > maxLen {
+			return nil, fmt.Errorf("increment range %d-%d does not fit a mask of %d positions", minLen, maxLen, len(charsets))
+		}
+	}
+	return newPositionsKeyspace(charsets, minLen, maxLen), nil
+}
+
+// newPositionsKeyspace indexes the prefixes of charsets from minLen to maxLen
+// positions long.
+func newPositionsKeyspace(charsets []string, minLen, maxLen int) *MaskKeyspace {
+	ks := &MaskKeyspace{minLen: minLen}
+	for _, cs := range charsets {
+		ks.positions = append(ks.positions, []byte(cs))
+	}
+
+	for length := ks.minLen; length <= maxLen; length++ {
+		// Counts saturate rather than wrap, which keeps At correct for
+		// every index even when the whole keyspace does not fit.
+		count := uint64(1)
+		for _, cs := range ks.positions[:length] {
+			hi, lo := bits.Mul64(count, uint64(len(cs)))
+			if hi != 0 {
+				count = math.MaxUint64
+				ks.overflow = true
+				break
+			}
+			count = lo
+		}
+		ks.counts = append(ks.counts, count)
+		if ks.size+count < ks.size {
+			ks.size = math.MaxUint64
+			ks.overflow = true
+		} else {
+			ks.size += count
+		}
+	}
+
+	return ks
+}
+
+func (ks *MaskKeyspace) Size() uint64 {
+	return ks.size
+}
+
+// Overflow reports that the mask has more than 2^64 candidates. Size then
+// saturates, but At still works for every index below it.
+func (ks *MaskKeyspace) Overflow() bool {
+	return ks.overflow
+}
+
+func (ks *MaskKeyspace) At(index uint64) string {
+	length := ks.minLen
+	for _, count := range ks.counts {
+		if index < count {
+			break
+		}
+		index -= count
+		length++
+	}
+
+	password := make([]byte, length)
+	for pos := length - 1; pos >= 0; pos-- {
+		cs := ks.positions[pos]
+		base := uint64(len(cs))
+		password[pos] = cs[index%base]
+		index /= base
+	}
+	return string(password)
+}
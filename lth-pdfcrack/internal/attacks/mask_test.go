@@ -0,0 +1,110 @@
+package attacks
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestParseMask(t *testing.T) {
+	tests := []struct {
+		mask    string
+		custom  [4]string
+		lengths []int
+		wantErr string
+	}{
+		{"?u?l?l?l?d?d?d?d", [4]string{}, []int{26, 26, 26, 26, 10, 10, 10, 10}, ""},
+		{"Summer?d?d", [4]string{}, []int{1, 1, 1, 1, 1, 1, 10, 10}, ""},
+		{"?s?a?h?H?b", [4]string{}, []int{33, 95, 16, 16, 256}, ""},
+		{"a??b", [4]string{}, []int{1, 1, 1}, ""},
+		{"?1?2", [4]string{"?l?d", "abca"}, []int{36, 3}, ""},
+		{"?3", [4]string{}, nil, "custom charset 3 is not set"},
+		{"?x", [4]string{}, nil, "unknown placeholder"},
+		{"abc?", [4]string{}, nil, "ends with"},
+		{"", [4]string{}, nil, "empty mask"},
+		{"?1", [4]string{"?z"}, nil, "unknown placeholder"},
+	}
+
+	for _, tt := range tests {
+		positions, err := ParseMask(tt.mask, tt.custom)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseMask(%q) error = %v, want %q", tt.mask, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseMask(%q): %v", tt.mask, err)
+			continue
+		}
+		if len(positions) != len(tt.lengths) {
+			t.Errorf("ParseMask(%q) has %d positions, want %d", tt.mask, len(positions), len(tt.lengths))
+			continue
+		}
+		for i, cs := range positions {
+			if len(cs) != tt.lengths[i] {
+				t.Errorf("ParseMask(%q) position %d has %d chars, want %d", tt.mask, i, len(cs), tt.lengths[i])
+			}
+		}
+	}
+}
+
+func TestMaskKeyspace(t *testing.T) {
+	tests := []struct {
+		config MaskConfig
+		size   uint64
+		checks map[uint64]string
+	}{
+		{MaskConfig{Mask: "Summer?d?d"}, 100, map[uint64]string{0: "Summer00", 23: "Summer23", 99: "Summer99"}},
+		{MaskConfig{Mask: "?1?d", Custom: [4]string{"ab"}}, 20, map[uint64]string{0: "a0", 10: "b0", 19: "b9"}},
+		{MaskConfig{Mask: "?d?d?d", Increment: true}, 1110, map[uint64]string{0: "0", 9: "9", 10: "00", 109: "99", 110: "000", 1109: "999"}},
+		{MaskConfig{Mask: "?d?d?d?d", Increment: true, IncrementMin: 2, IncrementMax: 3}, 1100, map[uint64]string{0: "00", 100: "000"}},
+	}
+
+	for _, tt := range tests {
+		ks, err := NewMaskKeyspace(tt.config)
+		if err != nil {
+			t.Fatalf("%+v: %v", tt.config, err)
+		}
+		if ks.Size() != tt.size {
+			t.Errorf("%+v: Size() = %d, want %d", tt.config, ks.Size(), tt.size)
+		}
+		for index, want := range tt.checks {
+			if got := ks.At(index); got != want {
+				t.Errorf("%+v: At(%d) = %q, want %q", tt.config, index, got, want)
+			}
+		}
+	}
+
+	if ks, _ := NewMaskKeyspace(MaskConfig{Mask: "?b?b?b?b?b?b?b?b?b"}); !ks.Overflow() {
+		t.Error("?b x9 keyspace not flagged as overflowing")
+	}
+	if _, err := NewMaskKeyspace(MaskConfig{Mask: "?d?d", Increment: true, IncrementMin: 3}); err == nil {
+		t.Error("increment minimum longer than the mask accepted")
+	}
+
+	ks, _ := NewMaskKeyspace(MaskConfig{Mask: "?d?d"})
+	var got []string
+	for p := range KeyspaceGenerator(context.Background(), ks, 95, ks.Size()) {
+		got = append(got, p)
+	}
+	if strings.Join(got, ",") != "95,96,97,98,99" {
+		t.Errorf("resumed mask = %v", got)
+	}
+}
+
+func TestMaskKeyspaceOverflowAt(t *testing.T) {
+	ks, err := NewMaskKeyspace(MaskConfig{Mask: "?a?a?a?a?a?a?a?a?a?a?d"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ks.Overflow() {
+		t.Fatal("95^10*10 keyspace not flagged as overflowing")
+	}
+	if got := ks.At(0); got != "aaaaaaaaaa0" {
+		t.Errorf("At(0) = %q", got)
+	}
+	if got := ks.At(13); got != "aaaaaaaaab3" {
+		t.Errorf("At(13) = %q", got)
+	}
+}
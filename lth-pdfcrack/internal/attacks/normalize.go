@@ -0,0 +1,167 @@
+package attacks
+
+import (
+	"encoding/hex"
+	"fmt"
+	"hash/maphash"
+	"strings"
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+// Wordlist encodings accepted by NewNormalizer.
+const (
+	EncodingUTF8   = "utf-8"
+	EncodingLatin1 = "latin1"
+	EncodingCP1252 = "cp1252"
+)
+
+// dedupeFilterBytes is the memory given to the duplicate filter. With five
+// hashes, about one unique word in 200,000 is wrongly dropped after 10
+// million words, and one in 2,500 after 25 million.
+const dedupeFilterBytes = 64 << 20
+
+const dedupeHashes = 5
+
+type NormalizeConfig struct {
+	// Encoding is the wordlist's character set; lines are converted from
+	// it to UTF-8. Empty means UTF-8, which is passed through as is.
+	Encoding string
+	// Dedupe drops lines seen before, using a fixed-size Bloom filter, so a
+	// few unique lines may be dropped too.
+	Dedupe bool
+}
+
+// Normalizer cleans wordlist lines: it strips the CR that Windows line
+// endings leave behind and a leading UTF-8 byte order mark, decodes
+// hashcat's $HEX[...] notation, converts the encoding, and optionally drops
+// duplicates.
+type Normalizer struct {
+	decode func(string) string
+	seen   *bloomFilter
+}
+
+func NewNormalizer(config NormalizeConfig) (*Normalizer, error) {
+	n := &Normalizer{}
+	switch strings.ToLower(config.Encoding) {
+	case "", EncodingUTF8, "utf8":
+	case EncodingLatin1, "iso-8859-1", "latin-1":
+		n.decode = decodeLatin1
+	case EncodingCP1252, "windows-1252":
+		n.decode = decodeCP1252
+	default:
+		return nil, fmt.Errorf("unknown wordlist encoding %q (use utf-8, latin1 or cp1252)", config.Encoding)
+	}
+	if config.Dedupe {
+		n.seen = newBloomFilter(dedupeFilterBytes*8, dedupeHashes)
+	}
+	return n, nil
+}
+
+// Line returns the candidate for a raw line; first marks the first line of a
+// file, where a byte order mark may be. ok is false for a duplicate.
+func (n *Normalizer) Line(line string, first bool) (word string, ok bool) {
+	word = cleanLine(line, first)
+	if n == nil {
+		return word, true
+	}
+	if n.decode != nil {
+		word = n.decode(word)
+	}
+	if n.seen != nil && !n.seen.add(word) {
+		return "", false
+	}
+	return word, true
+}
+
+// cleanLine is the part of normalization every reader applies.
+func cleanLine(line string, first bool) string {
+	line = strings.TrimSuffix(line, "\r")
+	if first {
+		line = strings.TrimPrefix(line, "\uFEFF")
+	}
+	if strings.HasPrefix(line, "$HEX[") && strings.HasSuffix(line, "]") {
+		if b, err := hex.DecodeString(line[5 : len(line)-1]); err == nil {
+			return string(b)
+		}
+	}
+	return line
+}
+
+func decodeLatin1(s string) string {
+	return decodeSingleByte(s, func(b byte) rune { return rune(b) })
+}
+
+// cp1252High maps 0x80-0x9F; the five undefined bytes keep their Latin-1
+// code points, as browsers do.
+var cp1252High = [32]rune{
+	'€', 0x81, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0x8D, 'Ž', 0x8F,
+	0x90, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0x9D, 'ž', 'Ÿ',
+}
+
+func decodeCP1252(s string) string {
+	return decodeSingleByte(s, func(b byte) rune {
+		if b >= 0x80 && b < 0xA0 {
+			return cp1252High[b-0x80]
+		}
+		return rune(b)
+	})
+}
+
+func decodeSingleByte(s string, toRune func(byte) rune) string {
+	ascii := true
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return s
+	}
+	var b strings.Builder
+	b.Grow(len(s) + len(s)/2)
+	for i := 0; i < len(s); i++ {
+		b.WriteRune(toRune(s[i]))
+	}
+	return b.String()
+}
+
+type bloomFilter struct {
+	bits   []uint64
+	m      uint64
+	hashes int
+	seed   maphash.Seed
+}
+
+func newBloomFilter(bits uint64, hashes int) *bloomFilter {
+	return &bloomFilter{
+		bits:   make([]uint64, (bits+63)/64),
+		m:      bits,
+		hashes: hashes,
+		seed:   maphash.MakeSeed(),
+	}
+}
+
+// add records s and reports whether it was new. It is safe for concurrent
+// use, as the shards of a wordlist share one filter.
+func (f *bloomFilter) add(s string) bool {
+	h := maphash.String(f.seed, s)
+	h1, h2 := h, h>>32|1
+	added := false
+	for i := 0; i < f.hashes; i++ {
+		bit := (h1 + uint64(i)*h2) % f.m
+		word, mask := &f.bits[bit/64], uint64(1)<<(bit%64)
+		for {
+			old := atomic.LoadUint64(word)
+			if old&mask != 0 {
+				break
+			}
+			if atomic.CompareAndSwapUint64(word, old, old|mask) {
+				added = true
+				break
+			}
+		}
+	}
+	return added
+}
@@ -0,0 +1,52 @@
+package attacks
+
+import "testing"
+
+func TestNormalizer(t *testing.T) {
+	tests := []struct {
+		config NormalizeConfig
+		line   string
+		first  bool
+		want   string
+	}{
+		{NormalizeConfig{}, "password\r", false, "password"},
+		{NormalizeConfig{}, "\uFEFFpassword", true, "password"},
+		{NormalizeConfig{}, "\uFEFFpassword", false, "\uFEFFpassword"},
+		{NormalizeConfig{}, "$HEX[70617373]", false, "pass"},
+		{NormalizeConfig{}, "$HEX[zz]", false, "$HEX[zz]"},
+		{NormalizeConfig{}, "caf\xe9", false, "caf\xe9"},
+		{NormalizeConfig{Encoding: "latin1"}, "caf\xe9\r", false, "café"},
+		{NormalizeConfig{Encoding: "latin1"}, "$HEX[636166e9]", false, "café"},
+		{NormalizeConfig{Encoding: "cp1252"}, "\x80uro\x99", false, "€uro™"},
+		{NormalizeConfig{Encoding: "latin1"}, "\x80", false, "\u0080"},
+	}
+	for _, tt := range tests {
+		n, err := NewNormalizer(tt.config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, ok := n.Line(tt.line, tt.first); !ok || got != tt.want {
+			t.Errorf("%+v: Line(%q) = %q, %v; want %q", tt.config, tt.line, got, ok, tt.want)
+		}
+	}
+
+	if _, err := NewNormalizer(NormalizeConfig{Encoding: "ebcdic"}); err == nil {
+		t.Error("expected an error for an unknown encoding")
+	}
+}
+
+func TestNormalizerDedupe(t *testing.T) {
+	n, err := NewNormalizer(NormalizeConfig{Dedupe: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, line := range []string{"a", "b\r", "a", "b", "c", "$HEX[61]"} {
+		if word, ok := n.Line(line, false); ok {
+			got = append(got, word)
+		}
+	}
+	if len(got) != 3 || got[0] != "a" || got[1] != "b" || got[2] != "c" {
+		t.Errorf("deduplicated = %q, want [a b c]", got)
+	}
+}
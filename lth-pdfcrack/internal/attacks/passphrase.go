@@ -0,0 +1,392 @@
+package attacks
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// The EFF diceware lists (https://www.eff.org/dice), CC BY 3.0 US, in their
+// original "rolls<TAB>word" form.
+var (
+	//go:embed eff_large.txt
+	effLarge string
+	//go:embed eff_short.txt
+	effShort string
+)
+
+var passphraseLists = map[string]string{
+	"eff-large": effLarge,
+	"eff-short": effShort,
+}
+
+// Case styles accepted in PassphraseConfig.Cases.
+const (
+	CaseLower = "lower"
+	CaseFirst = "first"
+	CaseTitle = "title"
+	CaseUpper = "upper"
+)
+
+// maxPhraseWords bounds the words of a passphrase.
+const maxPhraseWords = 16
+
+// PassphraseLists returns the names of the built-in word lists.
+func PassphraseLists() []string {
+	names := make([]string, 0, len(passphraseLists))
+	for name := range passphraseLists {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadPassphraseWords reads a word list file, or returns the built-in list
+// of that name.
+func LoadPassphraseWords(name string) ([]string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		if list, ok := passphraseLists[strings.ToLower(name)]; ok {
+			return ReadPassphraseWords(strings.NewReader(list))
+		}
+		return nil, fmt.Errorf("unknown passphrase list %q (use %s or a word list file)", name, strings.Join(PassphraseLists(), ", "))
+	}
+	defer f.Close()
+	words, err := ReadPassphraseWords(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return words, nil
+}
+
+// ReadPassphraseWords parses a word list, likeliest word first. Each line
+// holds a word, optionally with a number before or after it. Numbers made of
+// dice digits of one length, as in diceware lists, are ignored; other
+// numbers are counts, and the words are sorted by them, most frequent first.
+// Without counts the lines are taken to be in rank order. Blank lines and
+// lines starting with # are skipped.
+func ReadPassphraseWords(r io.Reader) ([]string, error) {
+	type entry struct {
+		word   string
+		number string
+	}
+	var entries []entry
+	seen := make(map[string]bool)
+	dice, diceLen := true, 0
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		var e entry
+		switch fields := strings.Fields(text); {
+		case len(fields) == 1:
+			e.word = fields[0]
+		case len(fields) == 2 && isDigits(fields[0]):
+			e.number, e.word = fields[0], fields[1]
+		case len(fields) == 2 && isDigits(fields[1]):
+			e.word, e.number = fields[0], fields[1]
+		default:
+			return nil, fmt.Errorf("line %d: want a word, optionally with a count or dice rolls", line)
+		}
+		if e.number != "" {
+			if diceLen == 0 {
+				diceLen = len(e.number)
+			}
+			if strings.Trim(e.number, "123456") != "" || len(e.number) != diceLen {
+				dice = false
+			}
+		}
+		if !seen[e.word] {
+			seen[e.word] = true
+			entries = append(entries, e)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no words")
+	}
+
+	if !dice {
+		counts := make([]uint64, len(entries))
+		for i, e := range entries {
+			// ParseUint saturates counts too large for it; a line
+			// without a count counts as 0.
+			counts[i], _ = strconv.ParseUint(e.number, 10, 64)
+		}
+		order := make([]int, len(entries))
+		for i := range order {
+			order[i] = i
+		}
+		sort.SliceStable(order, func(a, b int) bool { return counts[order[a]] > counts[order[b]] })
+		sorted := make([]entry, len(entries))
+		for i, j := range order {
+			sorted[i] = entries[j]
+		}
+		entries = sorted
+	}
+	words := make([]string, len(entries))
+	for i, e := range entries {
+		words[i] = e.word
+	}
+	return words, nil
+}
+
+// PassphraseConfig describes passphrases: words from a list joined by a
+// separator, in a case style, with an optional suffix.
+type PassphraseConfig struct {
+	// Words is the word list, likeliest first.
+	Words []string
+	// MinWords and MaxWords bound the words in a passphrase.
+	MinWords int
+	MaxWords int
+	// Separators go between the words. Empty means none.
+	Separators []string
+	// Cases lists the case styles: lower, first (the first letter of the
+	// passphrase), title (the first letter of each word) and upper. Empty
+	// means lower.
+	Cases []string
+	// Suffixes are masks such as "?d?d" or "?s" tried after each
+	// passphrase, which is also tried without one.
+	Suffixes []string
+	Custom   [4]string
+}
+
+// PassphraseKeyspace numbers passphrases. Shorter passphrases come first.
+// Within one word count, passphrases are ordered by their least likely
+// word, so that all the phrases made of the first n words of the list come
+// before any using word n+1. Each phrase is tried with every separator and
+// case style, first without a suffix and then with each suffix, before the
+// next.
+type PassphraseKeyspace struct {
+	words    []string
+	suffixes []*MaskKeyspace
+	// perStyle is the number of suffixes, counting none.
+	perStyle uint64
+	parts    []*phrasePart
+	size     uint64
+	overflow bool
+}
+
+// phrasePart is the passphrases of one word count.
+type phrasePart struct {
+	count int
+	seps  []string
+	cases []string
+	size  uint64
+}
+
+func NewPassphraseKeyspace(config PassphraseConfig) (*PassphraseKeyspace, error) {
+	if config.MinWords < 1 || config.MaxWords < config.MinWords || config.MaxWords > maxPhraseWords {
+		return nil, fmt.Errorf("passphrases need 1 to %d words", maxPhraseWords)
+	}
+	if len(config.Words) == 0 {
+		return nil, fmt.Errorf("passphrases need a word list")
+	}
+	seps := config.Separators
+	if len(seps) == 0 {
+		seps = []string{""}
+	}
+	cases := config.Cases
+	if len(cases) == 0 {
+		cases = []string{CaseLower}
+	}
+	for _, c := range cases {
+		switch c {
+		case CaseLower, CaseFirst, CaseTitle, CaseUpper:
+		default:
+			return nil, fmt.Errorf("unknown case style %q (use lower, first, title or upper)", c)
+		}
+	}
+
+	ks := &PassphraseKeyspace{words: config.Words, perStyle: 1}
+	for _, mask := range config.Suffixes {
+		if mask == "" {
+			return nil, fmt.Errorf("empty suffix mask; passphrases are always tried without a suffix")
+		}
+		suffix, err := NewMaskKeyspace(MaskConfig{Mask: mask, Custom: config.Custom})
+		if err != nil {
+			return nil, err
+		}
+		if suffix.Overflow() || ks.perStyle+suffix.Size() < ks.perStyle {
+			return nil, fmt.Errorf("suffix mask %q is too large", mask)
+		}
+		ks.suffixes = append(ks.suffixes, suffix)
+		ks.perStyle += suffix.Size()
+	}
+	if _, ok := mulChecked(uint64(len(seps)), uint64(len(cases)), ks.perStyle); !ok {
+		return nil, fmt.Errorf("suffix masks are too large")
+	}
+
+	n := uint64(len(config.Words))
+	for count := config.MinWords; count <= config.MaxWords; count++ {
+		part := &phrasePart{count: count, seps: seps, cases: cases}
+		if count == 1 {
+			// One word has no separator, and first and title agree.
+			part.seps = seps[:1]
+			part.cases = singleWordCases(cases)
+		}
+		phrases, ok := powChecked(n, count)
+		if ok {
+			part.size, ok = mulChecked(phrases, uint64(len(part.seps)), uint64(len(part.cases)), ks.perStyle)
+		}
+		if !ok {
+			part.size = math.MaxUint64
+			ks.overflow = true
+		}
+		ks.parts = append(ks.parts, part)
+		ks.size = addSaturating64(ks.size, part.size)
+		if ks.size == math.MaxUint64 {
+			ks.overflow = true
+		}
+	}
+	return ks, nil
+}
+
+func singleWordCases(cases []string) []string {
+	var out []string
+	seen := make(map[string]bool)
+	for _, c := range cases {
+		if c == CaseTitle {
+			c = CaseFirst
+		}
+		if !seen[c] {
+			seen[c] = true
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func (ks *PassphraseKeyspace) Size() uint64 {
+	return ks.size
+}
+
+// Overflow reports more than 2^64 passphrases; Size then saturates.
+func (ks *PassphraseKeyspace) Overflow() bool {
+	return ks.overflow
+}
+
+func (ks *PassphraseKeyspace) At(index uint64) string {
+	for _, part := range ks.parts {
+		if index < part.size {
+			return ks.phrase(part, index)
+		}
+		index -= part.size
+	}
+	return ""
+}
+
+func (ks *PassphraseKeyspace) phrase(p *phrasePart, index uint64) string {
+	styles := uint64(len(p.seps) * len(p.cases))
+	perPhrase := styles * ks.perStyle
+	picked := shellTuple(index/perPhrase, p.count)
+	index %= perPhrase
+
+	style := p.cases[index%uint64(len(p.cases))]
+	sep := p.seps[index%styles/uint64(len(p.cases))]
+	var suffix string
+	if n := index / styles; n > 0 {
+		n--
+		for _, s := range ks.suffixes {
+			if n < s.Size() {
+				suffix = s.At(n)
+				break
+			}
+			n -= s.Size()
+		}
+	}
+
+	phrase := make([]string, p.count)
+	for i, w := range picked {
+		phrase[i] = applyCase(ks.words[w], style, i == 0)
+	}
+	return strings.Join(phrase, sep) + suffix
+}
+
+func applyCase(word, style string, first bool) string {
+	switch style {
+	case CaseUpper:
+		return strings.ToUpper(word)
+	case CaseTitle:
+		return upperFirst(word)
+	case CaseFirst:
+		if first {
+			return upperFirst(word)
+		}
+		return word
+	}
+	return strings.ToLower(word)
+}
+
+func upperFirst(word string) string {
+	r, size := utf8.DecodeRuneInString(word)
+	return string(unicode.ToUpper(r)) + word[size:]
+}
+
+// shellTuple returns the index-th way to pick k words from a list. Picks are in
+// shells by their largest word index s, each shell holding the (s+1)^k-s^k
+// picks that use word s; within a shell, picks are ordered by the first
+// position holding s, then by the other positions, last fastest.
+func shellTuple(index uint64, k int) []uint64 {
+	// The shell is the smallest s with (s+1)^k > index.
+	s := uint64(math.Pow(float64(index), 1/float64(k)))
+	for s > 0 && powAbove(s, k, index) {
+		s--
+	}
+	for !powAbove(s+1, k, index) {
+		s++
+	}
+	below, _ := powChecked(s, k)
+	index -= below
+
+	first := 0
+	for ; first < k-1; first++ {
+		before, _ := powChecked(s, first)
+		after, ok := powChecked(s+1, k-1-first)
+		n, fits := mulChecked(before, after)
+		if !ok || !fits || index < n {
+			break
+		}
+		index -= n
+	}
+
+	tuple := make([]uint64, k)
+	tuple[first] = s
+	for pos := k - 1; pos > first; pos-- {
+		tuple[pos] = index % (s + 1)
+		index /= s + 1
+	}
+	for pos := first - 1; pos >= 0; pos-- {
+		tuple[pos] = index % s
+		index /= s
+	}
+	return tuple
+}
+
+// powAbove reports whether base^k > x.
+func powAbove(base uint64, k int, x uint64) bool {
+	p, ok := powChecked(base, k)
+	return !ok || p > x
+}
+
+func powChecked(base uint64, k int) (uint64, bool) {
+	factors := make([]uint64, k)
+	for i := range factors {
+		factors[i] = base
+	}
+	return mulChecked(factors...)
+}
@@ -0,0 +1,114 @@
+package attacks
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPassphrases(t *testing.T) {
+	words := []string{"red", "blue", "green", "gold"}
+	ks, err := NewPassphraseKeyspace(PassphraseConfig{
+		Words:      words,
+		MinWords:   1,
+		MaxWords:   3,
+		Separators: []string{"", "-"},
+		Cases:      []string{CaseLower, CaseFirst, CaseTitle},
+		Suffixes:   []string{"?d"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// One word: 4 words x 2 cases, plain and with a digit; two and three
+	// words: 4^n x 2 separators x 3 cases, plain and with a digit.
+	if want := uint64(4*2*11 + 16*6*11 + 64*6*11); ks.Size() != want {
+		t.Fatalf("Size = %d, want %d", ks.Size(), want)
+	}
+	seen := make(map[string]bool)
+	for i := uint64(0); i < ks.Size(); i++ {
+		p := ks.At(i)
+		if seen[p] {
+			t.Errorf("%q repeats", p)
+		}
+		seen[p] = true
+	}
+	for _, want := range []string{"red", "Gold", "gold7", "Blue-green", "GoldRedBlue", "green-gold-red0"} {
+		if !seen[want] {
+			t.Errorf("missing %q", want)
+		}
+	}
+	if got := []string{ks.At(0), ks.At(1), ks.At(2)}; strings.Join(got, ",") != "red,Red,red0" {
+		t.Errorf("first passphrases = %q", got)
+	}
+
+	// Phrases of the first n words come before any with a later word.
+	ks, err = NewPassphraseKeyspace(PassphraseConfig{Words: words, MinWords: 3, MaxWords: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rank := map[string]int{"red": 0, "blue": 1, "green": 2, "gold": 3}
+	worst := 0
+	for i := uint64(0); i < ks.Size(); i++ {
+		p := ks.At(i)
+		w := 0
+		for r, n := range rank {
+			if strings.Contains(p, r) {
+				w = max(w, n)
+			}
+		}
+		if w < worst {
+			t.Fatalf("%q at %d comes after a phrase using a rarer word", p, i)
+		}
+		worst = w
+	}
+
+	for _, bad := range []PassphraseConfig{
+		{Words: words, MinWords: 0, MaxWords: 2},
+		{Words: words, MinWords: 3, MaxWords: 2},
+		{MinWords: 1, MaxWords: 2},
+		{Words: words, MinWords: 1, MaxWords: 2, Cases: []string{"camel"}},
+		{Words: words, MinWords: 1, MaxWords: 2, Suffixes: []string{""}},
+	} {
+		if _, err := NewPassphraseKeyspace(bad); err == nil {
+			t.Errorf("%+v: no error", bad)
+		}
+	}
+}
+
+func TestPassphraseWords(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"plain", "# ranked\nthe\nof\n\nand\nthe\n", "the,of,and"},
+		{"dice", "11111\tabacus\n11112\tabdomen\n66666\tzoom\n", "abacus,abdomen,zoom"},
+		{"counts after", "apple 3\nbanana 10\ncherry 3\n", "banana,apple,cherry"},
+		{"counts before", "   4 alpha\n  12 beta\n1111 gamma\n", "gamma,beta,alpha"},
+	}
+	for _, tt := range tests {
+		words, err := ReadPassphraseWords(strings.NewReader(tt.in))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got := strings.Join(words, ","); got != tt.want {
+			t.Errorf("%s: words = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"", "# nothing\n", "one two three\n", "two words\n"} {
+		if _, err := ReadPassphraseWords(strings.NewReader(bad)); err == nil {
+			t.Errorf("%q: no error", bad)
+		}
+	}
+
+	for name, want := range map[string]int{"eff-large": 7776, "EFF-short": 1296} {
+		words, err := LoadPassphraseWords(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(words) != want {
+			t.Errorf("%s: %d words, want %d", name, len(words), want)
+		}
+	}
+}
@@ -0,0 +1,561 @@
+package attacks
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Pattern kinds accepted in PatternConfig.Patterns besides the presets.
+const (
+	PatternDates  = "dates"
+	PatternPhones = "phones"
+	PatternLuhn   = "luhn"
+)
+
+// PatternConfig describes a pattern run: dates, phone numbers, check-digit
+// IDs and preset name-and-date schemes, one after another in the order given.
+type PatternConfig struct {
+	// Patterns lists the kinds to generate; empty means dates.
+	Patterns []string
+	// From and To bound the dates, both included.
+	From, To time.Time
+	// Months lists the languages of spelled-out months, e.g. "en", "fr".
+	Months []string
+	// Names feed the presets, e.g. the account holder's first or last name.
+	Names []string
+
+	// PhonePrefixes start the national numbers, e.g. "06"; PhoneDigits is
+	// their full length. With a CountryCode, international forms follow.
+	PhonePrefixes []string
+	PhoneDigits   int
+	CountryCode   string
+
+	// LuhnDigits is the length of Luhn-valid numbers, check digit included,
+	// and LuhnPrefix their fixed start.
+	LuhnDigits int
+	LuhnPrefix string
+}
+
+// patternPresets are password schemes common on bank and payroll
+// statements, as sequences of slots.
+var patternPresets = map[string][]string{
+	"name4-ddmm":     {"name4", "ddmm"},
+	"ddmm-name4":     {"ddmm", "name4"},
+	"name4-ddmmyy":   {"name4", "ddmmyy"},
+	"name4-ddmmyyyy": {"name4", "ddmmyyyy"},
+	"name4-yyyy":     {"name4", "yyyy"},
+	"name-ddmmyyyy":  {"name", "ddmmyyyy"},
+	"name-yyyy":      {"name", "yyyy"},
+}
+
+// PatternPresets returns the preset names in order.
+func PatternPresets() []string {
+	names := make([]string, 0, len(patternPresets))
+	for name := range patternPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseDateBound reads YYYY, YYYY-MM or YYYY-MM-DD. A partial date stands
+// for its first day, or its last when end is set.
+func ParseDateBound(s string, end bool) (time.Time, error) {
+	for _, layout := range []string{"2006-01-02", "2006-01", "2006"} {
+		t, err := time.Parse(layout, s)
+		if err != nil {
+			continue
+		}
+		if end {
+			switch layout {
+			case "2006-01":
+				t = t.AddDate(0, 1, -1)
+			case "2006":
+				t = t.AddDate(1, 0, -1)
+			}
+		}
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q (use YYYY, YYYY-MM or YYYY-MM-DD)", s)
+}
+
+// PatternKeyspace numbers the candidates of every pattern in turn.
+type PatternKeyspace struct {
+	parts    []Keyspace
+	size     uint64
+	overflow bool
+}
+
+func NewPatternKeyspace(config PatternConfig) (*PatternKeyspace, error) {
+	patterns := config.Patterns
+	if len(patterns) == 0 {
+		patterns = []string{PatternDates}
+	}
+	if config.To.Before(config.From) {
+		return nil, fmt.Errorf("date range ends before it starts")
+	}
+
+	ks := &PatternKeyspace{}
+	for _, p := range patterns {
+		parts, err := patternParts(strings.ToLower(p), config)
+		if err != nil {
+			return nil, err
+		}
+		ks.parts = append(ks.parts, parts...)
+	}
+	for _, part := range ks.parts {
+		if ks.size+part.Size() < ks.size {
+			ks.size = math.MaxUint64
+			ks.overflow = true
+			break
+		}
+		ks.size += part.Size()
+	}
+	return ks, nil
+}
+
+func patternParts(pattern string, config PatternConfig) ([]Keyspace, error) {
+	switch pattern {
+	case PatternDates:
+		layouts, err := dateLayouts(config.Months)
+		if err != nil {
+			return nil, err
+		}
+		return []Keyspace{&dateKeyspace{from: config.From, days: dayCount(config.From, config.To), layouts: layouts}}, nil
+	case PatternPhones:
+		return phoneParts(config)
+	case PatternLuhn:
+		width := config.LuhnDigits - 1 - len(config.LuhnPrefix)
+		if width < 0 || !isDigits(config.LuhnPrefix) {
+			return nil, fmt.Errorf("Luhn numbers of %d digits cannot start with %q", config.LuhnDigits, config.LuhnPrefix)
+		}
+		if width > 18 {
+			return nil, fmt.Errorf("Luhn numbers are limited to %d free digits", 18)
+		}
+		return []Keyspace{&luhnKeyspace{prefix: config.LuhnPrefix, width: width, size: pow10(width)}}, nil
+	}
+
+	slots, ok := patternPresets[pattern]
+	if !ok {
+		return nil, fmt.Errorf("unknown pattern %q (use dates, phones, luhn or one of %s)", pattern, strings.Join(PatternPresets(), ", "))
+	}
+	product := &productKeyspace{}
+	for _, slot := range slots {
+		values := slotValues(slot, config)
+		if len(values) == 0 {
+			return nil, fmt.Errorf("pattern %s needs at least one name", pattern)
+		}
+		product.slots = append(product.slots, valueList(values))
+	}
+	product.init()
+	return []Keyspace{product}, nil
+}
+
+func (ks *PatternKeyspace) Size() uint64 {
+	return ks.size
+}
+
+// Overflow reports more than 2^64 candidates; Size then saturates.
+func (ks *PatternKeyspace) Overflow() bool {
+	return ks.overflow
+}
+
+func (ks *PatternKeyspace) At(index uint64) string {
+	for _, part := range ks.parts {
+		if index < part.Size() {
+			return part.At(index)
+		}
+		index -= part.Size()
+	}
+	return ""
+}
+
+// dateKeyspace writes every day of a range in each layout, one layout after
+// another, so the most common layout covers the whole range first.
+type dateKeyspace struct {
+	from    time.Time
+	days    uint64
+	layouts []func(time.Time) string
+}
+
+func (ks *dateKeyspace) Size() uint64 {
+	return ks.days * uint64(len(ks.layouts))
+}
+
+func (ks *dateKeyspace) At(index uint64) string {
+	layout := ks.layouts[index/ks.days]
+	return layout(ks.from.AddDate(0, 0, int(index%ks.days)))
+}
+
+func dayCount(from, to time.Time) uint64 {
+	return uint64(to.Sub(from).Hours()/24) + 1
+}
+
+// dateLayouts lists numeric layouts, with and without separators, then
+// spelled-out months in each language.
+func dateLayouts(languages []string) ([]func(time.Time) string, error) {
+	var layouts []func(time.Time) string
+	for _, sep := range []string{"", ".", "-", "/"} {
+		for _, order := range []string{"dmY", "dmy", "Ymd", "mdY", "mdy", "ymd"} {
+			layouts = append(layouts, numericLayout(order, sep))
+		}
+	}
+
+	if len(languages) == 0 {
+		languages = []string{"en"}
+	}
+	for _, lang := range languages {
+		names, ok := monthNames[strings.ToLower(lang)]
+		if !ok {
+			return nil, fmt.Errorf("no month names for %q (use %s)", lang, strings.Join(monthLanguages(), ", "))
+		}
+		for _, full := range []bool{false, true} {
+			for _, title := range []bool{true, false} {
+				for _, longYear := range []bool{true, false} {
+					layouts = append(layouts, namedLayout(names, full, title, longYear))
+				}
+			}
+		}
+	}
+	return layouts, nil
+}
+
+func numericLayout(order, sep string) func(time.Time) string {
+	return func(t time.Time) string {
+		parts := make([]string, len(order))
+		for i, c := range order {
+			switch c {
+			case 'd':
+				parts[i] = twoDigits(t.Day())
+			case 'm':
+				parts[i] = twoDigits(int(t.Month()))
+			case 'Y':
+				parts[i] = strconv.Itoa(t.Year())
+			case 'y':
+				parts[i] = twoDigits(t.Year() % 100)
+			}
+		}
+		return strings.Join(parts, sep)
+	}
+}
+
+// namedLayout writes a day, a month name and a year, e.g. 07Mar1985.
+func namedLayout(names monthNameSet, full, title, longYear bool) func(time.Time) string {
+	return func(t time.Time) string {
+		month := names.short[t.Month()-1]
+		if full {
+			month = names.full[t.Month()-1]
+		}
+		if title {
+			month = titleCase(month)
+		}
+		year := twoDigits(t.Year() % 100)
+		if longYear {
+			year = strconv.Itoa(t.Year())
+		}
+		return twoDigits(t.Day()) + month + year
+	}
+}
+
+func twoDigits(n int) string {
+	return string([]byte{byte('0' + n/10%10), byte('0' + n%10)})
+}
+
+func titleCase(s string) string {
+	r := []rune(s)
+	if len(r) > 0 {
+		r[0] = unicode.ToUpper(r[0])
+	}
+	return string(r)
+}
+
+type monthNameSet struct {
+	short, full [12]string
+}
+
+var monthNames = map[string]monthNameSet{
+	"en": {
+		[12]string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"},
+		[12]string{"january", "february", "march", "april", "may", "june", "july", "august", "september", "october", "november", "december"},
+	},
+	"de": {
+		[12]string{"jan", "feb", "mär", "apr", "mai", "jun", "jul", "aug", "sep", "okt", "nov", "dez"},
+		[12]string{"januar", "februar", "märz", "april", "mai", "juni", "juli", "august", "september", "oktober", "november", "dezember"},
+	},
+	"fr": {
+		[12]string{"janv", "févr", "mars", "avr", "mai", "juin", "juil", "août", "sept", "oct", "nov", "déc"},
+		[12]string{"janvier", "février", "mars", "avril", "mai", "juin", "juillet", "août", "septembre", "octobre", "novembre", "décembre"},
+	},
+	"es": {
+		[12]string{"ene", "feb", "mar", "abr", "may", "jun", "jul", "ago", "sep", "oct", "nov", "dic"},
+		[12]string{"enero", "febrero", "marzo", "abril", "mayo", "junio", "julio", "agosto", "septiembre", "octubre", "noviembre", "diciembre"},
+	},
+	"it": {
+		[12]string{"gen", "feb", "mar", "apr", "mag", "giu", "lug", "ago", "set", "ott", "nov", "dic"},
+		[12]string{"gennaio", "febbraio", "marzo", "aprile", "maggio", "giugno", "luglio", "agosto", "settembre", "ottobre", "novembre", "dicembre"},
+	},
+	"nl": {
+		[12]string{"jan", "feb", "mrt", "apr", "mei", "jun", "jul", "aug", "sep", "okt", "nov", "dec"},
+		[12]string{"januari", "februari", "maart", "april", "mei", "juni", "juli", "augustus", "september", "oktober", "november", "december"},
+	},
+	"pt": {
+		[12]string{"jan", "fev", "mar", "abr", "mai", "jun", "jul", "ago", "set", "out", "nov", "dez"},
+		[12]string{"janeiro", "fevereiro", "março", "abril", "maio", "junho", "julho", "agosto", "setembro", "outubro", "novembro", "dezembro"},
+	},
+}
+
+func monthLanguages() []string {
+	langs := make([]string, 0, len(monthNames))
+	for lang := range monthNames {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// phoneKeyspace is head and prefix followed by every number of width
+// digits, written in one layout.
+type phoneKeyspace struct {
+	head   string
+	prefix string
+	width  int
+	size   uint64
+	layout func(head, prefix, rest string) string
+}
+
+func (ks *phoneKeyspace) Size() uint64 {
+	return ks.size
+}
+
+func (ks *phoneKeyspace) At(index uint64) string {
+	return ks.layout(ks.head, ks.prefix, padDigits(index, ks.width))
+}
+
+// phoneLayouts lists the numbers run together, then with each separator
+// after the prefix and between pairs of digits, as 06-12345678 and
+// 06 12 34 56 78.
+func phoneLayouts() []func(head, prefix, rest string) string {
+	layouts := []func(head, prefix, rest string) string{phoneLayout("", false)}
+	for _, sep := range []string{"-", " ", "."} {
+		layouts = append(layouts, phoneLayout(sep, false), phoneLayout(sep, true))
+	}
+	return layouts
+}
+
+// phoneLayout joins the head, prefix and rest with sep, or with pairs the
+// head and the number cut into pairs from its end.
+func phoneLayout(sep string, pairs bool) func(head, prefix, rest string) string {
+	return func(head, prefix, rest string) string {
+		groups := []string{head, prefix, rest}
+		if pairs {
+			number := prefix + rest
+			groups = []string{head, number[:len(number)%2]}
+			for i := len(number) % 2; i < len(number); i += 2 {
+				groups = append(groups, number[i:i+2])
+			}
+		}
+		var out []string
+		for _, g := range groups {
+			if g != "" {
+				out = append(out, g)
+			}
+		}
+		return strings.Join(out, sep)
+	}
+}
+
+// phoneParts writes national numbers for each prefix, then the same numbers
+// as +CC, 00CC and CC followed by the number without its trunk 0, in each
+// of phoneLayouts in turn.
+func phoneParts(config PatternConfig) ([]Keyspace, error) {
+	if len(config.PhonePrefixes) == 0 {
+		return nil, fmt.Errorf("phone patterns need at least one prefix, e.g. 06")
+	}
+	if !isDigits(config.CountryCode) {
+		return nil, fmt.Errorf("invalid country code %q", config.CountryCode)
+	}
+	heads := []string{""}
+	if config.CountryCode != "" {
+		heads = append(heads, "+"+config.CountryCode, "00"+config.CountryCode, config.CountryCode)
+	}
+
+	var parts []Keyspace
+	for _, layout := range phoneLayouts() {
+		for i, head := range heads {
+			for _, prefix := range config.PhonePrefixes {
+				width := config.PhoneDigits - len(prefix)
+				if !isDigits(prefix) || width < 0 || width > 18 {
+					return nil, fmt.Errorf("phone numbers of %d digits cannot start with %q", config.PhoneDigits, prefix)
+				}
+				if i > 0 {
+					prefix = strings.TrimPrefix(prefix, "0")
+				}
+				parts = append(parts, &phoneKeyspace{head: head, prefix: prefix, width: width, size: pow10(width), layout: layout})
+			}
+		}
+	}
+	return parts, nil
+}
+
+// luhnKeyspace is prefix followed by every number of width digits and the
+// Luhn check digit, as on card, IMEI and many account and employee numbers.
+type luhnKeyspace struct {
+	prefix string
+	width  int
+	size   uint64
+}
+
+func (ks *luhnKeyspace) Size() uint64 {
+	return ks.size
+}
+
+func (ks *luhnKeyspace) At(index uint64) string {
+	body := ks.prefix + padDigits(index, ks.width)
+	return body + string(rune('0'+LuhnCheckDigit(body)))
+}
+
+// LuhnCheckDigit is the digit that makes body followed by it pass the Luhn
+// check.
+func LuhnCheckDigit(body string) int {
+	sum := 0
+	for i := len(body) - 1; i >= 0; i-- {
+		d := int(body[i] - '0')
+		if (len(body)-1-i)%2 == 0 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return (10 - sum%10) % 10
+}
+
+// productKeyspace joins one candidate from each slot, the last slot changing
+// fastest.
+type productKeyspace struct {
+	slots    []Keyspace
+	size     uint64
+	overflow bool
+}
+
+func (ks *productKeyspace) init() {
+	ks.size = 1
+	for _, slot := range ks.slots {
+		hi, lo := bits.Mul64(ks.size, slot.Size())
+		if hi != 0 {
+			ks.size, ks.overflow = math.MaxUint64, true
+			return
+		}
+		ks.size = lo
+	}
+}
+
+func (ks *productKeyspace) Size() uint64 {
+	return ks.size
+}
+
+func (ks *productKeyspace) At(index uint64) string {
+	parts := make([]string, len(ks.slots))
+	for i := len(ks.slots) - 1; i >= 0; i-- {
+		n := ks.slots[i].Size()
+		parts[i] = ks.slots[i].At(index % n)
+		index /= n
+	}
+	return strings.Join(parts, "")
+}
+
+// valueList is a keyspace of fixed strings.
+type valueList []string
+
+func (l valueList) Size() uint64 {
+	return uint64(len(l))
+}
+
+func (l valueList) At(index uint64) string {
+	return l[index]
+}
+
+// slotValues lists what a preset slot can hold: names as given, in upper
+// and in lower case, or dates from the range.
+func slotValues(slot string, config PatternConfig) []string {
+	var values []string
+	seen := make(map[string]bool)
+	add := func(v string) {
+		if !seen[v] {
+			seen[v] = true
+			values = append(values, v)
+		}
+	}
+
+	switch slot {
+	case "name", "name4":
+		for _, name := range config.Names {
+			name = strings.Join(strings.Fields(name), "")
+			if slot == "name4" {
+				if r := []rune(name); len(r) > 4 {
+					name = string(r[:4])
+				}
+			}
+			if name == "" {
+				continue
+			}
+			add(name)
+			add(strings.ToUpper(name))
+			add(strings.ToLower(name))
+			add(titleCase(strings.ToLower(name)))
+		}
+	case "ddmm":
+		// Any leap year gives every day of the year once.
+		for t := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC); t.Year() == 2000; t = t.AddDate(0, 0, 1) {
+			add(twoDigits(t.Day()) + twoDigits(int(t.Month())))
+		}
+	case "ddmmyy", "ddmmyyyy":
+		layout := numericLayout("dmy", "")
+		if slot == "ddmmyyyy" {
+			layout = numericLayout("dmY", "")
+		}
+		for t := config.From; !t.After(config.To); t = t.AddDate(0, 0, 1) {
+			add(layout(t))
+		}
+	case "yyyy":
+		for y := config.From.Year(); y <= config.To.Year(); y++ {
+			add(strconv.Itoa(y))
+		}
+	}
+	return values
+}
+
+func padDigits(n uint64, width int) string {
+	if width == 0 {
+		return ""
+	}
+	s := strconv.FormatUint(n, 10)
+	if len(s) < width {
+		s = strings.Repeat("0", width-len(s)) + s
+	}
+	return s
+}
+
+func pow10(n int) uint64 {
+	p := uint64(1)
+	for i := 0; i < n; i++ {
+		p *= 10
+	}
+	return p
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
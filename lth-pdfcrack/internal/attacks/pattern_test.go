@@ -0,0 +1,109 @@
+package attacks
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestPatternDates(t *testing.T) {
+	from, _ := ParseDateBound("1985-03", false)
+	to, _ := ParseDateBound("1985-03", true)
+	if to.Day() != 31 {
+		t.Fatalf("end of 1985-03 is %v", to)
+	}
+	config := PatternConfig{From: from, To: to, Months: []string{"en", "fr"}}
+
+	ks, err := NewPatternKeyspace(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 24 numeric layouts and 8 spelled-out ones per language.
+	if want := uint64(31 * (24 + 2*8)); ks.Size() != want {
+		t.Errorf("Size() = %d, want %d", ks.Size(), want)
+	}
+	if got := ks.At(0); got != "01031985" {
+		t.Errorf("At(0) = %q, want 01031985", got)
+	}
+
+	all := keyspaceAll(t, ks)
+	for _, want := range []string{"15031985", "150385", "1985-03-15", "03/15/1985", "15.03.85", "850315", "15Mar1985", "15march85", "15mars1985"} {
+		if !slices.Contains(all, want) {
+			t.Errorf("missing %q", want)
+		}
+	}
+	if slices.Contains(all, "01041985") {
+		t.Error("date past the range generated")
+	}
+}
+
+func TestPatternPhonesAndLuhn(t *testing.T) {
+	config := PatternConfig{
+		Patterns:      []string{PatternPhones, PatternLuhn},
+		PhonePrefixes: []string{"0612"},
+		PhoneDigits:   6,
+		CountryCode:   "33",
+		LuhnDigits:    4,
+		LuhnPrefix:    "7",
+	}
+	ks, err := NewPatternKeyspace(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	all := keyspaceAll(t, ks)
+	for _, want := range []string{"061234", "+3361234", "003361234", "3361234", "0612-34", "06 12 34", "+33 6 12 34", "0033.612.34", "7997"} {
+		if !slices.Contains(all, want) {
+			t.Errorf("missing %q", want)
+		}
+	}
+	// Four heads in seven layouts, then the Luhn numbers.
+	if len(all) != 4*7*100+100 {
+		t.Errorf("got %d candidates, want 2900", len(all))
+	}
+
+	tests := []struct {
+		body string
+		want int
+	}{
+		{"7992739871", 3},
+		{"453201511283036", 6},
+		{"0", 0},
+	}
+	for _, tt := range tests {
+		if got := LuhnCheckDigit(tt.body); got != tt.want {
+			t.Errorf("LuhnCheckDigit(%q) = %d, want %d", tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestPatternPresets(t *testing.T) {
+	from := time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(1989, 12, 31, 0, 0, 0, 0, time.UTC)
+	config := PatternConfig{Patterns: []string{"name4-ddmm", "name-yyyy"}, From: from, To: to, Names: []string{"Dupont"}}
+
+	ks, err := NewPatternKeyspace(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	all := keyspaceAll(t, ks)
+	for _, want := range []string{"DUPO1503", "dupo2902", "Dupo0101", "dupont1985", "DUPONT1980"} {
+		if !slices.Contains(all, want) {
+			t.Errorf("missing %q", want)
+		}
+	}
+	if want := 3*366 + 3*10; len(all) != want {
+		t.Errorf("got %d candidates, want %d", len(all), want)
+	}
+
+	for _, config := range []PatternConfig{
+		{Patterns: []string{"name4-ddmm"}, From: from, To: to},
+		{Patterns: []string{"nonsense"}, From: from, To: to},
+		{Patterns: []string{PatternPhones}, From: from, To: to},
+		{Months: []string{"xx"}, From: from, To: to},
+		{From: to, To: from},
+	} {
+		if _, err := NewPatternKeyspace(config); err == nil {
+			t.Errorf("%+v: no error", config)
+		}
+	}
+}
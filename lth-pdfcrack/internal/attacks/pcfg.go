@@ -0,0 +1,444 @@
+package attacks
+
+import (
+	"bufio"
+	"compress/gzip"
+	"container/heap"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const pcfgMagic = "PDFCRACK-PCFG1\n"
+
+// PCFGGrammar holds the counts learned from a password corpus. Passwords are
+// split into runs of letters (L), digits (D) and anything else (S); the base
+// structure of "Summer2024!" is L6D4S1. Letter runs are counted lowercased,
+// with their capitalization kept separately as a mask such as "ULLLLL".
+// The inner maps are keyed by run length.
+type PCFGGrammar struct {
+	Structures map[string]uint64         `json:"structures"`
+	Words      map[int]map[string]uint64 `json:"words"`
+	Digits     map[int]map[string]uint64 `json:"digits"`
+	Specials   map[int]map[string]uint64 `json:"specials"`
+	Caps       map[int]map[string]uint64 `json:"caps"`
+}
+
+func NewPCFGGrammar() *PCFGGrammar {
+	return &PCFGGrammar{
+		Structures: make(map[string]uint64),
+		Words:      make(map[int]map[string]uint64),
+		Digits:     make(map[int]map[string]uint64),
+		Specials:   make(map[int]map[string]uint64),
+		Caps:       make(map[int]map[string]uint64),
+	}
+}
+
+// Add counts the structure and runs of password.
+func (g *PCFGGrammar) Add(password string) {
+	var structure strings.Builder
+	for _, run := range pcfgRuns(password) {
+		n := len(run.text)
+		structure.WriteByte(run.class)
+		structure.WriteString(strconv.Itoa(n))
+		switch run.class {
+		case 'L':
+			caps := make([]byte, n)
+			for i := 0; i < n; i++ {
+				caps[i] = 'L'
+				if run.text[i] >= 'A' && run.text[i] <= 'Z' {
+					caps[i] = 'U'
+				}
+			}
+			countIn(g.Words, n, strings.ToLower(run.text))
+			countIn(g.Caps, n, string(caps))
+		case 'D':
+			countIn(g.Digits, n, run.text)
+		default:
+			countIn(g.Specials, n, run.text)
+		}
+	}
+	g.Structures[structure.String()]++
+}
+
+func countIn(m map[int]map[string]uint64, n int, value string) {
+	if m[n] == nil {
+		m[n] = make(map[string]uint64)
+	}
+	m[n][value]++
+}
+
+type pcfgRun struct {
+	class byte
+	text  string
+}
+
+func pcfgClass(c byte) byte {
+	switch {
+	case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		return 'L'
+	case c >= '0' && c <= '9':
+		return 'D'
+	}
+	return 'S'
+}
+
+func pcfgRuns(password string) []pcfgRun {
+	var runs []pcfgRun
+	for i := 0; i < len(password); {
+		class := pcfgClass(password[i])
+		j := i + 1
+		for j < len(password) && pcfgClass(password[j]) == class {
+			j++
+		}
+		runs = append(runs, pcfgRun{class: class, text: password[i:j]})
+		i = j
+	}
+	return runs
+}
+
+// TrainPCFG builds a grammar from a corpus, one password per line.
+func TrainPCFG(r io.Reader) (*PCFGGrammar, uint64, error) {
+	g := NewPCFGGrammar()
+	scanner := bufio.NewScanner(r)
+	buf := make([]byte, 0, 64*1024)
+	scanner.Buffer(buf, 1024*1024)
+
+	var words uint64
+	for scanner.Scan() {
+		word := strings.TrimRight(scanner.Text(), "\r")
+		if word == "" {
+			continue
+		}
+		g.Add(word)
+		words++
+	}
+	return g, words, scanner.Err()
+}
+
+func (g *PCFGGrammar) Write(w io.Writer) error {
+	zw := gzip.NewWriter(w)
+	if _, err := io.WriteString(zw, pcfgMagic); err != nil {
+		return err
+	}
+	if err := json.NewEncoder(zw).Encode(g); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func ReadPCFGGrammar(r io.Reader) (*PCFGGrammar, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("not a PCFG grammar file: %w", err)
+	}
+	defer zr.Close()
+
+	magic := make([]byte, len(pcfgMagic))
+	if _, err := io.ReadFull(zr, magic); err != nil || string(magic) != pcfgMagic {
+		return nil, fmt.Errorf("not a PCFG grammar file")
+	}
+	g := NewPCFGGrammar()
+	if err := json.NewDecoder(zr).Decode(g); err != nil {
+		return nil, fmt.Errorf("corrupt PCFG grammar: %w", err)
+	}
+	return g, nil
+}
+
+// LoadPCFGGrammar reads a grammar file, or returns the built-in grammar when
+// path is empty.
+func LoadPCFGGrammar(path string) (*PCFGGrammar, error) {
+	if path == "" {
+		return DefaultPCFGGrammar(), nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	g, err := ReadPCFGGrammar(bufio.NewReader(f))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return g, nil
+}
+
+var (
+	defaultPCFGOnce    sync.Once
+	defaultPCFGGrammar *PCFGGrammar
+)
+
+// DefaultPCFGGrammar is trained from the same built-in list as the default
+// Markov statistics.
+func DefaultPCFGGrammar() *PCFGGrammar {
+	defaultPCFGOnce.Do(func() {
+		defaultPCFGGrammar, _, _ = TrainPCFG(strings.NewReader(markovCorpus))
+	})
+	return defaultPCFGGrammar
+}
+
+// pcfgChoices lists the values of one run, most probable first.
+type pcfgChoices struct {
+	values []string
+	probs  []float64
+}
+
+func newPCFGChoices(counts map[string]uint64) *pcfgChoices {
+	c := &pcfgChoices{}
+	var total uint64
+	for value, n := range counts {
+		c.values = append(c.values, value)
+		total += n
+	}
+	sort.Slice(c.values, func(i, j int) bool {
+		a, b := counts[c.values[i]], counts[c.values[j]]
+		if a != b {
+			return a > b
+		}
+		return c.values[i] < c.values[j]
+	})
+	c.probs = make([]float64, len(c.values))
+	for i, value := range c.values {
+		c.probs[i] = float64(counts[value]) / float64(total)
+	}
+	return c
+}
+
+// pcfgStructure is a base structure with one choice list per dimension. A
+// letter run has two dimensions, the word and its capitalization.
+type pcfgStructure struct {
+	classes []byte
+	dims    []*pcfgChoices
+	prob    float64
+}
+
+// PCFG generates guesses from a grammar in descending probability order.
+type PCFG struct {
+	structures []*pcfgStructure
+	size       uint64
+	overflow   bool
+}
+
+func NewPCFG(g *PCFGGrammar) *PCFG {
+	cache := map[string]*pcfgChoices{}
+	choices := func(kind string, m map[int]map[string]uint64, n int) *pcfgChoices {
+		key := kind + strconv.Itoa(n)
+		if c, ok := cache[key]; ok {
+			return c
+		}
+		var c *pcfgChoices
+		if len(m[n]) > 0 {
+			c = newPCFGChoices(m[n])
+		}
+		cache[key] = c
+		return c
+	}
+
+	var total uint64
+	for _, n := range g.Structures {
+		total += n
+	}
+
+	p := &PCFG{}
+	for _, name := range sortedKeys(g.Structures) {
+		s := &pcfgStructure{prob: float64(g.Structures[name]) / float64(total)}
+		valid := true
+		for _, seg := range parseStructure(name) {
+			var dims []*pcfgChoices
+			switch seg.class {
+			case 'L':
+				dims = []*pcfgChoices{choices("W", g.Words, seg.n), choices("C", g.Caps, seg.n)}
+			case 'D':
+				dims = []*pcfgChoices{choices("D", g.Digits, seg.n)}
+			default:
+				dims = []*pcfgChoices{choices("S", g.Specials, seg.n)}
+			}
+			for _, d := range dims {
+				if d == nil {
+					valid = false
+				}
+			}
+			s.classes = append(s.classes, seg.class)
+			s.dims = append(s.dims, dims...)
+		}
+		if !valid || len(s.dims) == 0 {
+			continue
+		}
+
+		count := uint64(1)
+		for _, d := range s.dims {
+			count = mulSaturating64(count, uint64(len(d.values)))
+		}
+		p.size = addSaturating64(p.size, count)
+		if count == math.MaxUint64 || p.size == math.MaxUint64 {
+			p.overflow = true
+		}
+		p.structures = append(p.structures, s)
+	}
+	return p
+}
+
+func sortedKeys(m map[string]uint64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+type pcfgSegment struct {
+	class byte
+	n     int
+}
+
+func parseStructure(name string) []pcfgSegment {
+	var segs []pcfgSegment
+	for i := 0; i < len(name); {
+		j := i + 1
+		for j < len(name) && name[j] >= '0' && name[j] <= '9' {
+			j++
+		}
+		n, _ := strconv.Atoi(name[i+1 : j])
+		if n > 0 {
+			segs = append(segs, pcfgSegment{class: name[i], n: n})
+		}
+		i = j
+	}
+	return segs
+}
+
+// Size is the number of guesses the grammar can produce.
+func (p *PCFG) Size() uint64 {
+	return p.size
+}
+
+// Overflow reports that there are more than 2^64 guesses.
+func (p *PCFG) Overflow() bool {
+	return p.overflow
+}
+
+func (p *PCFG) guess(s *pcfgStructure, idx []int) string {
+	var b strings.Builder
+	d := 0
+	for _, class := range s.classes {
+		value := s.dims[d].values[idx[d]]
+		if class == 'L' {
+			caps := s.dims[d+1].values[idx[d+1]]
+			for i := 0; i < len(value); i++ {
+				c := value[i]
+				if caps[i] == 'U' {
+					c -= 'a' - 'A'
+				}
+				b.WriteByte(c)
+			}
+			d += 2
+			continue
+		}
+		b.WriteString(value)
+		d++
+	}
+	return b.String()
+}
+
+// Generator emits guesses most probable first, skipping the first start so
+// a checkpointed run can resume. Each structure starts at its likeliest
+// guess; popping a guess pushes the guesses one step less likely in each
+// dimension at or after its pivot, which reaches every guess exactly once.
+func (p *PCFG) Generator(ctx context.Context, start uint64) <-chan string {
+	ch := make(chan string, 10000)
+
+	go func() {
+		defer close(ch)
+
+		q := &pcfgQueue{}
+		for i, s := range p.structures {
+			q.push(&pcfgNode{structure: i, idx: make([]int, len(s.dims)), prob: p.prob(s, make([]int, len(s.dims)))})
+		}
+
+		var emitted uint64
+		for q.Len() > 0 {
+			node := heap.Pop(q).(*pcfgNode)
+			s := p.structures[node.structure]
+
+			for d := node.pivot; d < len(s.dims); d++ {
+				if node.idx[d]+1 >= len(s.dims[d].values) {
+					continue
+				}
+				idx := append([]int(nil), node.idx...)
+				idx[d]++
+				q.push(&pcfgNode{structure: node.structure, idx: idx, pivot: d, prob: p.prob(s, idx)})
+			}
+
+			emitted++
+			if emitted <= start {
+				continue
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case ch <- p.guess(s, node.idx):
+			}
+		}
+	}()
+
+	return ch
+}
+
+func (p *PCFG) prob(s *pcfgStructure, idx []int) float64 {
+	prob := s.prob
+	for d, i := range idx {
+		prob *= s.dims[d].probs[i]
+	}
+	return prob
+}
+
+type pcfgNode struct {
+	structure int
+	idx       []int
+	pivot     int
+	prob      float64
+	seq       uint64
+}
+
+// pcfgQueue is a max-heap on probability; ties go to the node pushed first
+// so the order is deterministic.
+type pcfgQueue struct {
+	nodes []*pcfgNode
+	seq   uint64
+}
+
+func (q *pcfgQueue) push(n *pcfgNode) {
+	n.seq = q.seq
+	q.seq++
+	heap.Push(q, n)
+}
+
+func (q *pcfgQueue) Len() int { return len(q.nodes) }
+
+func (q *pcfgQueue) Less(i, j int) bool {
+	a, b := q.nodes[i], q.nodes[j]
+	if a.prob != b.prob {
+		return a.prob > b.prob
+	}
+	return a.seq < b.seq
+}
+
+func (q *pcfgQueue) Swap(i, j int) { q.nodes[i], q.nodes[j] = q.nodes[j], q.nodes[i] }
+
+func (q *pcfgQueue) Push(x any) { q.nodes = append(q.nodes, x.(*pcfgNode)) }
+
+func (q *pcfgQueue) Pop() any {
+	old := q.nodes
+	n := old[len(old)-1]
+	q.nodes = old[:len(old)-1]
+	return n
+}
@@ -0,0 +1,64 @@
+package attacks
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPCFGTrain(t *testing.T) {
+	g, words, err := TrainPCFG(strings.NewReader("Summer2024\nsummer2024\r\n\nwinter2024\nSummer1!\n"))
+	if err != nil || words != 4 {
+		t.Fatalf("TrainPCFG = %d words, %v", words, err)
+	}
+	if g.Structures["L6D4"] != 3 || g.Structures["L6D1S1"] != 1 {
+		t.Errorf("structures = %v", g.Structures)
+	}
+	if g.Words[6]["summer"] != 3 || g.Caps[6]["ULLLLL"] != 2 || g.Digits[4]["2024"] != 3 || g.Specials[1]["!"] != 1 {
+		t.Errorf("grammar = %+v", g)
+	}
+
+	var buf bytes.Buffer
+	if err := g.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadPCFGGrammar(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(read, g) {
+		t.Error("grammar changed in a write/read round trip")
+	}
+	if _, err := ReadPCFGGrammar(strings.NewReader("not gzip")); err == nil {
+		t.Error("expected an error for a file that is not a grammar")
+	}
+}
+
+func TestPCFGGenerator(t *testing.T) {
+	g, _, _ := TrainPCFG(strings.NewReader("Summer2024\nsummer2024\nwinter2024\nSummer1!\n"))
+	p := NewPCFG(g)
+	if p.Size() != 8 {
+		t.Fatalf("Size() = %d, want 8", p.Size())
+	}
+
+	all := "summer2024,Summer2024,summer1!,winter2024,Summer1!,Winter2024,winter1!,Winter1!"
+	tests := []struct {
+		start uint64
+		want  string
+	}{
+		{0, all},
+		{3, "winter2024,Summer1!,Winter2024,winter1!,Winter1!"},
+		{8, ""},
+	}
+	for _, tt := range tests {
+		var got []string
+		for c := range p.Generator(context.Background(), tt.start) {
+			got = append(got, c)
+		}
+		if strings.Join(got, ",") != tt.want {
+			t.Errorf("from %d = %s, want %s", tt.start, strings.Join(got, ","), tt.want)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package attacks
+
+import (
+	"context"
+	"math/bits"
+)
+
+const feistelRounds = 6
+
+// Permutation is a keyed bijection on [0, n). It is a balanced Feistel
+// network over the smallest even number of bits that covers n; indices it
+// maps past n are encrypted again (cycle walking) until they land inside,
+// which takes fewer than four rounds on average.
+type Permutation struct {
+	n    uint64
+	half uint
+	mask uint64
+	keys [feistelRounds]uint64
+}
+
+func NewPermutation(n uint64, seed int64) *Permutation {
+	p := &Permutation{n: n}
+	if n > 1 {
+		p.half = uint(bits.Len64(n-1)+1) / 2
+	}
+	p.mask = 1<<p.half - 1
+	state := uint64(seed)
+	for i := range p.keys {
+		state += 0x9e3779b97f4a7c15
+		p.keys[i] = mix64(state)
+	}
+	return p
+}
+
+// mix64 is the splitmix64 finalizer.
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	return x ^ x>>31
+}
+
+// At maps index, which must be below n, to its place in the permutation.
+func (p *Permutation) At(index uint64) uint64 {
+	if p.n <= 1 {
+		return index
+	}
+	for {
+		index = p.encrypt(index)
+		if index < p.n {
+			return index
+		}
+	}
+}
+
+func (p *Permutation) encrypt(x uint64) uint64 {
+	left, right := x>>p.half, x&p.mask
+	for _, key := range p.keys {
+		left, right = right, left^(mix64(right^key)&p.mask)
+	}
+	return left<<p.half | right
+}
+
+// RandomKeyspace is the incremental keyspace in a random order fixed by the
+// seed. Every candidate comes up exactly once, and any range of positions
+// can be generated on its own, so a run resumes or splits like incremental
+// mode. Keyspaces over 2^64 candidates cannot be permuted; see Overflow.
+type RandomKeyspace struct {
+	ks   *IncrementalKeyspace
+	perm *Permutation
+}
+
+func NewRandomKeyspace(config RandomConfig) *RandomKeyspace {
+	ks := NewIncrementalKeyspace(IncrementalConfig{
+		Charset:   config.Charset,
+		MinLength: config.MinLength,
+		MaxLength: config.MaxLength,
+	})
+	return &RandomKeyspace{ks: ks, perm: NewPermutation(ks.Size(), config.Seed)}
+}
+
+func (r *RandomKeyspace) Size() uint64 {
+	return r.ks.Size()
+}
+
+// Overflow reports a keyspace too large to permute, where only
+// RandomGenerator's draws, which can repeat, are possible.
+func (r *RandomKeyspace) Overflow() bool {
+	return r.ks.Overflow()
+}
+
+func (r *RandomKeyspace) At(index uint64) string {
+	return r.ks.At(r.perm.At(index))
+}
+
+// RandomKeyspaceGenerator walks the permuted keyspace from start, or falls
+// back to RandomGenerator when it overflows.
+func RandomKeyspaceGenerator(ctx context.Context, config RandomConfig, start uint64) <-chan string {
+	r := NewRandomKeyspace(config)
+	if r.Overflow() {
+		return RandomGenerator(ctx, config)
+	}
+	return KeyspaceGenerator(ctx, r, start, r.Size())
+}
@@ -0,0 +1,75 @@
+package attacks
+
+import (
+	"context"
+	"testing"
+)
+
+func TestPermutation(t *testing.T) {
+	for _, n := range []uint64{1, 2, 3, 10, 64, 1000, 4097} {
+		p := NewPermutation(n, 42)
+		seen := make([]bool, n)
+		moved := 0
+		for i := uint64(0); i < n; i++ {
+			j := p.At(i)
+			if j >= n || seen[j] {
+				t.Fatalf("n=%d: At(%d) = %d is out of range or repeated", n, i, j)
+			}
+			seen[j] = true
+			if j != i {
+				moved++
+			}
+		}
+		if n >= 10 && moved < int(n)/2 {
+			t.Errorf("n=%d: only %d indices moved", n, moved)
+		}
+	}
+
+	a, b := NewPermutation(1000, 1), NewPermutation(1000, 2)
+	same := 0
+	for i := uint64(0); i < 1000; i++ {
+		if a.At(i) == b.At(i) {
+			same++
+		}
+		if a.At(i) != NewPermutation(1000, 1).At(i) {
+			t.Fatalf("seed 1 gives a different order at %d", i)
+		}
+	}
+	if same > 50 {
+		t.Errorf("seeds 1 and 2 agree on %d of 1000 positions", same)
+	}
+}
+
+func TestRandomKeyspaceGenerator(t *testing.T) {
+	config := RandomConfig{Charset: "abc", MinLength: 1, MaxLength: 3, Seed: 7}
+	ks := NewRandomKeyspace(config)
+	if ks.Size() != 3+9+27 {
+		t.Fatalf("Size() = %d, want 39", ks.Size())
+	}
+
+	var all []string
+	seen := make(map[string]bool)
+	for w := range RandomKeyspaceGenerator(context.Background(), config, 0) {
+		if seen[w] {
+			t.Errorf("%q repeated", w)
+		}
+		seen[w] = true
+		all = append(all, w)
+	}
+	if len(all) != 39 {
+		t.Fatalf("got %d candidates, want 39", len(all))
+	}
+
+	var resumed []string
+	for w := range RandomKeyspaceGenerator(context.Background(), config, 30) {
+		resumed = append(resumed, w)
+	}
+	if len(resumed) != 9 {
+		t.Fatalf("resuming at 30 gave %d candidates, want 9", len(resumed))
+	}
+	for i, w := range resumed {
+		if all[30+i] != w {
+			t.Errorf("resumed candidate %d = %q, want %q", i, w, all[30+i])
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package attacks
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// PrinceConfig describes a PRINCE run: candidates are chains of wordlist
+// elements whose lengths add up to a password length.
+type PrinceConfig struct {
+	Wordlist string
+	// MinLength and MaxLength bound the password length, up to 16.
+	MinLength int
+	MaxLength int
+	// MinElements and MaxElements bound the number of elements per chain.
+	// Zero means 1 and 8.
+	MinElements int
+	MaxElements int
+	// Skip and Limit select a slice of the candidates, as princeprocessor's
+	// --skip and --limit do. A zero Limit means no limit.
+	Skip  uint64
+	Limit uint64
+}
+
+type princeChain struct {
+	lengths []int
+	size    uint64
+}
+
+// PrinceKeyspace numbers every PRINCE candidate. Password lengths that are
+// more common in the wordlist come first; within a length, chains with
+// fewer candidates (built from rarer element lengths) come first; within a
+// chain, elements earlier in the wordlist, assumed more probable, come
+// first, the last element changing fastest.
+type PrinceKeyspace struct {
+	// elements[n] holds the distinct words of length n in wordlist order.
+	elements [][]string
+	chains   []princeChain
+	// starts[i] is the index of the first candidate of chains[i].
+	starts   []uint64
+	size     uint64
+	overflow bool
+	skip     uint64
+	limit    uint64
+}
+
+func NewPrinceKeyspace(config PrinceConfig) (*PrinceKeyspace, error) {
+	minLen, maxLen := normalizeLengths(config.MinLength, config.MaxLength)
+	minElems, maxElems := config.MinElements, config.MaxElements
+	if minElems <= 0 {
+		minElems = 1
+	}
+	if maxElems <= 0 {
+		maxElems = 8
+	}
+	if minElems > maxElems {
+		return nil, fmt.Errorf("element count range %d-%d is empty", minElems, maxElems)
+	}
+
+	ks := &PrinceKeyspace{
+		elements: make([][]string, maxLen+1),
+		skip:     config.Skip,
+		limit:    config.Limit,
+	}
+	lengthFreq := make([]int, maxLen+1)
+	if err := ks.load(config.Wordlist, maxLen, lengthFreq); err != nil {
+		return nil, err
+	}
+
+	order := make([]int, 0, maxLen-minLen+1)
+	for n := minLen; n <= maxLen; n++ {
+		order = append(order, n)
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return lengthFreq[order[i]] > lengthFreq[order[j]]
+	})
+
+	for _, n := range order {
+		var chains []princeChain
+		ks.compose(n, nil, minElems, maxElems, &chains)
+		sort.SliceStable(chains, func(i, j int) bool {
+			return chains[i].size < chains[j].size
+		})
+		for _, c := range chains {
+			ks.chains = append(ks.chains, c)
+			ks.starts = append(ks.starts, ks.size)
+			ks.size = addSaturating64(ks.size, c.size)
+			if ks.size == math.MaxUint64 || c.size == math.MaxUint64 {
+				ks.overflow = true
+			}
+		}
+	}
+	return ks, nil
+}
+
+func (ks *PrinceKeyspace) load(wordlist string, maxLen int, lengthFreq []int) error {
+	f, err := openWordlistFile(wordlist)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	seen := make(map[string]bool)
+	first := true
+	scanner := bufio.NewScanner(f)
+	buf := make([]byte, 0, 64*1024)
+	scanner.Buffer(buf, 1024*1024)
+	for scanner.Scan() {
+		word := cleanLine(scanner.Text(), first)
+		first = false
+		if word == "" || len(word) > maxLen {
+			continue
+		}
+		lengthFreq[len(word)]++
+		if seen[word] {
+			continue
+		}
+		seen[word] = true
+		ks.elements[len(word)] = append(ks.elements[len(word)], word)
+	}
+	return scanner.Err()
+}
+
+// compose appends every chain of element lengths that adds up to remaining,
+// using only lengths present in the wordlist.
+func (ks *PrinceKeyspace) compose(remaining int, prefix []int, minElems, maxElems int, chains *[]princeChain) {
+	if remaining == 0 {
+		if len(prefix) < minElems {
+			return
+		}
+		size := uint64(1)
+		for _, n := range prefix {
+			size = mulSaturating64(size, uint64(len(ks.elements[n])))
+		}
+		*chains = append(*chains, princeChain{lengths: append([]int(nil), prefix...), size: size})
+		return
+	}
+	if len(prefix) == maxElems {
+		return
+	}
+	for n := 1; n <= remaining; n++ {
+		if len(ks.elements[n]) == 0 {
+			continue
+		}
+		ks.compose(remaining-n, append(prefix, n), minElems, maxElems, chains)
+	}
+}
+
+func mulSaturating64(a, b uint64) uint64 {
+	if n, ok := mulChecked(a, b); ok {
+		return n
+	}
+	return math.MaxUint64
+}
+
+func (ks *PrinceKeyspace) Size() uint64 {
+	return ks.size
+}
+
+// Overflow reports that there are more than 2^64 candidates.
+func (ks *PrinceKeyspace) Overflow() bool {
+	return ks.overflow
+}
+
+func (ks *PrinceKeyspace) At(index uint64) string {
+	i := sort.Search(len(ks.starts), func(i int) bool { return ks.starts[i] > index }) - 1
+	chain := ks.chains[i]
+	index -= ks.starts[i]
+
+	words := make([]string, len(chain.lengths))
+	for p := len(chain.lengths) - 1; p >= 0; p-- {
+		elems := ks.elements[chain.lengths[p]]
+		base := uint64(len(elems))
+		words[p] = elems[index%base]
+		index /= base
+	}
+	return strings.Join(words, "")
+}
+
+// Chains returns the number of element chains.
+func (ks *PrinceKeyspace) Chains() int {
+	return len(ks.chains)
+}
+
+// Selected is the number of candidates left after Skip and Limit.
+func (ks *PrinceKeyspace) Selected() uint64 {
+	first, end := ks.window()
+	return end - first
+}
+
+func (ks *PrinceKeyspace) window() (first, end uint64) {
+	first, end = ks.skip, ks.size
+	if first > end {
+		first = end
+	}
+	if ks.limit > 0 && ks.limit < end-first {
+		end = first + ks.limit
+	}
+	return first, end
+}
+
+// Generator returns a generator over the candidates selected by Skip and
+// Limit, starting start candidates into the selection so a checkpointed run
+// can resume.
+func (ks *PrinceKeyspace) Generator(start uint64) func(ctx context.Context) <-chan string {
+	first, end := ks.window()
+	first = addSaturating64(first, start)
+	return func(ctx context.Context) <-chan string {
+		return KeyspaceGenerator(ctx, ks, first, end)
+	}
+}
@@ -0,0 +1,62 @@
+package attacks
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPrinceKeyspace(t *testing.T) {
+	wordlist := filepath.Join(t.TempDir(), "words.txt")
+	if err := os.WriteFile(wordlist, []byte("ab\ncd\r\nx\nab\ny\nefg\ntoolongword\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	all := "ab,cd,xx,xy,yx,yy,efg,xab,xcd,yab,ycd,abx,aby,cdx,cdy"
+
+	tests := []struct {
+		name   string
+		config PrinceConfig
+		start  uint64
+		want   string
+	}{
+		{"all", PrinceConfig{}, 0, all},
+		{"skip and limit", PrinceConfig{Skip: 4, Limit: 3}, 0, "yx,yy,efg"},
+		{"resumed", PrinceConfig{Skip: 4, Limit: 3}, 1, "yy,efg"},
+		{"skip past end", PrinceConfig{Skip: 100}, 0, ""},
+		{"two elements only", PrinceConfig{MinElements: 2}, 0, "xx,xy,yx,yy,xab,xcd,yab,ycd,abx,aby,cdx,cdy"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := tt.config
+			config.Wordlist = wordlist
+			config.MinLength, config.MaxLength = 2, 3
+			if config.MaxElements == 0 {
+				config.MaxElements = 2
+			}
+			ks, err := NewPrinceKeyspace(config)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for c := range ks.Generator(tt.start)(context.Background()) {
+				got = append(got, c)
+			}
+			if strings.Join(got, ",") != tt.want {
+				t.Errorf("candidates = %s, want %s", strings.Join(got, ","), tt.want)
+			}
+			if want := uint64(len(strings.Split(tt.want, ","))); tt.want != "" && tt.start == 0 && ks.Selected() != want {
+				t.Errorf("Selected() = %d, want %d", ks.Selected(), want)
+			}
+		})
+	}
+}
+
+func TestPrinceElementRange(t *testing.T) {
+	if _, err := NewPrinceKeyspace(PrinceConfig{Wordlist: os.DevNull, MinElements: 3, MaxElements: 2}); err == nil {
+		t.Error("expected an error for an empty element count range")
+	}
+}
@@ -0,0 +1,95 @@
+package rules
+
+import (
+	"context"
+	_ "embed"
+	"math"
+	"math/bits"
+	"strings"
+)
+
+//go:embed best64.rule
+var best64 string
+
+func builtinRules(name string) (string, bool) {
+	switch strings.ToLower(name) {
+	case "best64", "best64.rule":
+		return best64, true
+	}
+	return "", false
+}
+
+// Engine stacks rule files the way repeated -r does in hashcat: every rule
+// of the first file is combined with every rule of the second, and so on.
+// The combinations are never materialised; index i is decoded into one rule
+// per file on demand.
+type Engine struct {
+	sets [][]Rule
+	size uint64
+}
+
+func NewEngine(sets ...[]Rule) *Engine {
+	e := &Engine{size: 1}
+	for _, set := range sets {
+		if len(set) == 0 {
+			continue
+		}
+		e.sets = append(e.sets, set)
+		hi, lo := bits.Mul64(e.size, uint64(len(set)))
+		if hi != 0 {
+			lo = math.MaxUint64
+		}
+		e.size = lo
+	}
+	return e
+}
+
+// Size is the number of stacked rules, i.e. candidates per word before
+// rejections.
+func (e *Engine) Size() uint64 {
+	return e.size
+}
+
+// Apply runs stacked rule index on word.
+func (e *Engine) Apply(word string, index uint64) (string, bool) {
+	picks := make([]int, len(e.sets))
+	for i := len(e.sets) - 1; i >= 0; i-- {
+		n := uint64(len(e.sets[i]))
+		picks[i] = int(index % n)
+		index /= n
+	}
+
+	ok := true
+	for i, p := range picks {
+		word, ok = e.sets[i][p].Apply(word)
+		if !ok {
+			return "", false
+		}
+	}
+	return word, true
+}
+
+// Generator applies every stacked rule to each word from words as it
+// arrives, dropping rejected candidates.
+func (e *Engine) Generator(ctx context.Context, words <-chan string) <-chan string {
+	ch := make(chan string, 1000)
+
+	go func() {
+		defer close(ch)
+		for word := range words {
+			for i := uint64(0); i < e.size; i++ {
+				candidate, ok := e.Apply(word, i)
+				if !ok {
+					continue
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case ch <- candidate:
+				}
+			}
+		}
+	}()
+
+	return ch
+}
@@ -0,0 +1,419 @@
+// Package rules implements the hashcat rule language for mangling wordlist
+// candidates.
+package rules
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// maxWordLen matches hashcat's limit on the length of a mangled candidate.
+const maxWordLen = 256
+
+type paramKind int
+
+const (
+	pPos paramKind = iota
+	pChar
+)
+
+// arity lists the parameters each rule function takes, in order.
+var arity = map[byte][]paramKind{
+	':': nil, 'l': nil, 'u': nil, 'c': nil, 'C': nil, 't': nil,
+	'r': nil, 'd': nil, 'f': nil, '{': nil, '}': nil, '[': nil, ']': nil,
+	'k': nil, 'K': nil, 'q': nil, 'E': nil, 'M': nil, '4': nil, '6': nil, 'Q': nil,
+
+	'T': {pPos}, 'p': {pPos}, 'D': {pPos}, '\'': {pPos}, 'z': {pPos}, 'Z': {pPos},
+	'L': {pPos}, 'R': {pPos}, '+': {pPos}, '-': {pPos}, '.': {pPos}, ',': {pPos},
+	'y': {pPos}, 'Y': {pPos}, '<': {pPos}, '>': {pPos}, '_': {pPos},
+
+	'$': {pChar}, '^': {pChar}, '@': {pChar}, '!': {pChar}, '/': {pChar},
+	'(': {pChar}, ')': {pChar}, 'e': {pChar},
+
+	'i': {pPos, pChar}, 'o': {pPos, pChar}, '=': {pPos, pChar}, '%': {pPos, pChar},
+	'x': {pPos, pPos}, 'O': {pPos, pPos}, '*': {pPos, pPos},
+	's': {pChar, pChar},
+	'X': {pPos, pPos, pPos},
+}
+
+type op struct {
+	fn    byte
+	pos   [3]int
+	chars [2]byte
+}
+
+// Rule is one line of a rule file: a sequence of functions applied left to
+// right.
+type Rule struct {
+	text string
+	ops  []op
+}
+
+func (r Rule) String() string {
+	return r.text
+}
+
+// Parse compiles one rule. Spaces between functions are ignored, but a space
+// is a valid argument (e.g. "$ " appends a space).
+func Parse(line string) (Rule, error) {
+	r := Rule{text: line}
+
+	for i := 0; i < len(line); {
+		fn := line[i]
+		if fn == ' ' || fn == '\t' {
+			i++
+			continue
+		}
+		params, ok := arity[fn]
+		if !ok {
+			return Rule{}, fmt.Errorf("unknown rule function %q at offset %d", fn, i)
+		}
+		i++
+
+		o := op{fn: fn}
+		npos, nchar := 0, 0
+		for _, kind := range params {
+			if i >= len(line) {
+				return Rule{}, fmt.Errorf("rule function %q is missing parameters", fn)
+			}
+			switch kind {
+			case pPos:
+				n, ok := position(line[i])
+				if !ok {
+					return Rule{}, fmt.Errorf("invalid position %q for rule function %q", line[i], fn)
+				}
+				o.pos[npos] = n
+				npos++
+			case pChar:
+				o.chars[nchar] = line[i]
+				nchar++
+			}
+			i++
+		}
+		r.ops = append(r.ops, o)
+	}
+
+	return r, nil
+}
+
+// position decodes hashcat's 0-9, A-Z position and length arguments.
+func position(c byte) (int, bool) {
+	switch {
+	case c >= '0' && c <= '9':
+		return int(c - '0'), true
+	case c >= 'A' && c <= 'Z':
+		return int(c-'A') + 10, true
+	}
+	return 0, false
+}
+
+// Read parses a rule file. Blank lines and lines starting with # are skipped.
+func Read(r io.Reader) ([]Rule, error) {
+	var rules []Rule
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule, err := Parse(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNum, err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, scanner.Err()
+}
+
+// Load reads a rule file, or one of the built-in sets by name.
+func Load(name string) ([]Rule, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		if builtin, ok := builtinRules(name); ok {
+			return Read(strings.NewReader(builtin))
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	rules, err := Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return rules, nil
+}
+
+// Apply mangles word. ok is false when a rejection function drops it.
+func (r Rule) Apply(word string) (string, bool) {
+	w := []byte(word)
+	var memory []byte
+
+	for _, o := range r.ops {
+		n, m := o.pos[0], o.pos[1]
+		x, y := o.chars[0], o.chars[1]
+
+		switch o.fn {
+		case ':':
+		case 'l':
+			w = mapBytes(w, lower)
+		case 'u':
+			w = mapBytes(w, upper)
+		case 'c':
+			w = mapBytes(w, lower)
+			if len(w) > 0 {
+				w[0] = upper(w[0])
+			}
+		case 'C':
+			w = mapBytes(w, upper)
+			if len(w) > 0 {
+				w[0] = lower(w[0])
+			}
+		case 't':
+			for i := range w {
+				w[i] = toggle(w[i])
+			}
+		case 'T':
+			if n < len(w) {
+				w[n] = toggle(w[n])
+			}
+		case 'r':
+			reverse(w)
+		case 'd':
+			w = append(w, w...)
+		case 'p':
+			orig := append([]byte(nil), w...)
+			for i := 0; i < n; i++ {
+				w = append(w, orig...)
+			}
+		case 'f':
+			rev := append([]byte(nil), w...)
+			reverse(rev)
+			w = append(w, rev...)
+		case '{':
+			if len(w) > 0 {
+				w = append(w[1:], w[0])
+			}
+		case '}':
+			if len(w) > 0 {
+				last := w[len(w)-1]
+				w = append([]byte{last}, w[:len(w)-1]...)
+			}
+		case '$':
+			w = append(w, x)
+		case '^':
+			w = append([]byte{x}, w...)
+		case '[':
+			if len(w) > 0 {
+				w = w[1:]
+			}
+		case ']':
+			if len(w) > 0 {
+				w = w[:len(w)-1]
+			}
+		case 'D':
+			if n < len(w) {
+				w = append(w[:n:n], w[n+1:]...)
+			}
+		case 'x':
+			if n < len(w) && n+m <= len(w) {
+				w = append([]byte(nil), w[n:n+m]...)
+			}
+		case 'O':
+			if n < len(w) && n+m <= len(w) {
+				w = append(w[:n:n], w[n+m:]...)
+			}
+		case 'i':
+			if n <= len(w) {
+				w = append(w[:n:n], append([]byte{x}, w[n:]...)...)
+			}
+		case 'o':
+			if n < len(w) {
+				w[n] = x
+			}
+		case '\'':
+			if n < len(w) {
+				w = w[:n]
+			}
+		case 's':
+			for i := range w {
+				if w[i] == x {
+					w[i] = y
+				}
+			}
+		case '@':
+			w = bytes.ReplaceAll(w, []byte{x}, nil)
+		case 'z':
+			if len(w) > 0 {
+				w = append(bytes.Repeat(w[:1], n), w...)
+			}
+		case 'Z':
+			if len(w) > 0 {
+				w = append(w, bytes.Repeat(w[len(w)-1:], n)...)
+			}
+		case 'q':
+			out := make([]byte, 0, 2*len(w))
+			for _, c := range w {
+				out = append(out, c, c)
+			}
+			w = out
+		case 'k':
+			if len(w) >= 2 {
+				w[0], w[1] = w[1], w[0]
+			}
+		case 'K':
+			if len(w) >= 2 {
+				w[len(w)-1], w[len(w)-2] = w[len(w)-2], w[len(w)-1]
+			}
+		case '*':
+			if n < len(w) && m < len(w) {
+				w[n], w[m] = w[m], w[n]
+			}
+		case 'L':
+			if n < len(w) {
+				w[n] <<= 1
+			}
+		case 'R':
+			if n < len(w) {
+				w[n] >>= 1
+			}
+		case '+':
+			if n < len(w) {
+				w[n]++
+			}
+		case '-':
+			if n < len(w) {
+				w[n]--
+			}
+		case '.':
+			if n+1 < len(w) {
+				w[n] = w[n+1]
+			}
+		case ',':
+			if n > 0 && n < len(w) {
+				w[n] = w[n-1]
+			}
+		case 'y':
+			if n <= len(w) {
+				w = append(append([]byte(nil), w[:n]...), w...)
+			}
+		case 'Y':
+			if n <= len(w) {
+				w = append(w, w[len(w)-n:]...)
+			}
+		case 'E':
+			w = title(w, ' ')
+		case 'e':
+			w = title(w, x)
+		case 'M':
+			memory = append(memory[:0], w...)
+		case '4':
+			w = append(w, memory...)
+		case '6':
+			w = append(append([]byte(nil), memory...), w...)
+		case 'X':
+			i := o.pos[2]
+			if n+m <= len(memory) && i <= len(w) {
+				w = append(w[:i:i], append(append([]byte(nil), memory[n:n+m]...), w[i:]...)...)
+			}
+
+		case '<':
+			if len(w) > n {
+				return "", false
+			}
+		case '>':
+			if len(w) < n {
+				return "", false
+			}
+		case '_':
+			if len(w) != n {
+				return "", false
+			}
+		case '!':
+			if bytes.IndexByte(w, x) >= 0 {
+				return "", false
+			}
+		case '/':
+			if bytes.IndexByte(w, x) < 0 {
+				return "", false
+			}
+		case '(':
+			if len(w) == 0 || w[0] != x {
+				return "", false
+			}
+		case ')':
+			if len(w) == 0 || w[len(w)-1] != x {
+				return "", false
+			}
+		case '=':
+			if n >= len(w) || w[n] != x {
+				return "", false
+			}
+		case '%':
+			if bytes.Count(w, []byte{x}) < n {
+				return "", false
+			}
+		case 'Q':
+			if bytes.Equal(memory, w) {
+				return "", false
+			}
+		}
+
+		if len(w) > maxWordLen {
+			return "", false
+		}
+	}
+
+	return string(w), true
+}
+
+func lower(c byte) byte {
+	if c >= 'A' && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
+
+func upper(c byte) byte {
+	if c >= 'a' && c <= 'z' {
+		return c - ('a' - 'A')
+	}
+	return c
+}
+
+func toggle(c byte) byte {
+	if c >= 'a' && c <= 'z' {
+		return upper(c)
+	}
+	return lower(c)
+}
+
+// mapBytes changes case byte by byte; hashcat rules are ASCII-only and must
+// leave other bytes, including invalid UTF-8, untouched.
+func mapBytes(w []byte, f func(byte) byte) []byte {
+	for i := range w {
+		w[i] = f(w[i])
+	}
+	return w
+}
+
+func reverse(w []byte) {
+	for i, j := 0, len(w)-1; i < j; i, j = i+1, j-1 {
+		w[i], w[j] = w[j], w[i]
+	}
+}
+
+func title(w []byte, sep byte) []byte {
+	w = mapBytes(w, lower)
+	for i := range w {
+		if i == 0 || w[i-1] == sep {
+			w[i] = upper(w[i])
+		}
+	}
+	return w
+}
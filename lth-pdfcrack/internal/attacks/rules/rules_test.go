@@ -0,0 +1,150 @@
+package rules
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		rule string
+		word string
+		want string
+		ok   bool
+	}{
+		{":", "p@ssW0rd", "p@ssW0rd", true},
+		{"l", "p@ssW0rd", "p@ssw0rd", true},
+		{"u", "p@ssW0rd", "P@SSW0RD", true},
+		{"c", "p@ssW0rd", "P@ssw0rd", true},
+		{"C", "p@ssW0rd", "p@SSW0RD", true},
+		{"t", "p@ssW0rd", "P@SSw0RD", true},
+		{"T3", "p@ssW0rd", "p@sSW0rd", true},
+		{"r", "p@ssW0rd", "dr0Wss@p", true},
+		{"d", "p@ssW0rd", "p@ssW0rdp@ssW0rd", true},
+		{"p2", "abc", "abcabcabc", true},
+		{"f", "abc", "abccba", true},
+		{"{", "p@ssW0rd", "@ssW0rdp", true},
+		{"}", "p@ssW0rd", "dp@ssW0r", true},
+		{"$1", "p@ssW0rd", "p@ssW0rd1", true},
+		{"^1", "p@ssW0rd", "1p@ssW0rd", true},
+		{"$ ", "a", "a ", true},
+		{"[", "p@ssW0rd", "@ssW0rd", true},
+		{"]", "p@ssW0rd", "p@ssW0r", true},
+		{"D3", "p@ssW0rd", "p@sW0rd", true},
+		{"x04", "p@ssW0rd", "p@ss", true},
+		{"O12", "p@ssW0rd", "psW0rd", true},
+		{"i4!", "p@ssW0rd", "p@ss!W0rd", true},
+		{"o3$", "p@ssW0rd", "p@s$W0rd", true},
+		{"'6", "p@ssW0rd", "p@ssW0", true},
+		{"ss$", "p@ssW0rd", "p@$$W0rd", true},
+		{"@s", "p@ssW0rd", "p@W0rd", true},
+		{"z2", "p@ssW0rd", "ppp@ssW0rd", true},
+		{"Z2", "p@ssW0rd", "p@ssW0rddd", true},
+		{"q", "abc", "aabbcc", true},
+		{"k", "p@ssW0rd", "@pssW0rd", true},
+		{"K", "p@ssW0rd", "p@ssW0dr", true},
+		{"*34", "p@ssW0rd", "p@sWs0rd", true},
+		{"+0", "abc", "bbc", true},
+		{"-1", "abc", "aac", true},
+		{".1", "abc", "acc", true},
+		{",1", "abc", "aac", true},
+		{"L0", "abc", "\xc2bc", true},
+		{"R0", "abc", "0bc", true},
+		{"y2", "p@ssW0rd", "p@p@ssW0rd", true},
+		{"Y2", "p@ssW0rd", "p@ssW0rdrd", true},
+		{"E", "hello WORLD", "Hello World", true},
+		{"e-", "john-paul", "John-Paul", true},
+		{"M $1 4", "ab", "ab1ab", true},
+		{"M ^x 6", "ab", "abxab", true},
+		{"M r X021", "abc", "cabba", true},
+		{"c $1 $2 $3", "winter", "Winter123", true},
+		{"T9", "short", "short", true},
+
+		{"<5", "abcdef", "", false},
+		{"<6", "abcdef", "abcdef", true},
+		{">7", "abcdef", "", false},
+		{"_6", "abcdef", "abcdef", true},
+		{"_5", "abcdef", "", false},
+		{"!a", "abc", "", false},
+		{"/z", "abc", "", false},
+		{"(a", "abc", "abc", true},
+		{")a", "abc", "", false},
+		{"=1b", "abc", "abc", true},
+		{"=1c", "abc", "", false},
+		{"%2s", "pass", "pass", true},
+		{"%3s", "pass", "", false},
+		{"M l Q", "abc", "", false},
+		{"M u Q", "abc", "ABC", true},
+	}
+
+	for _, tt := range tests {
+		r, err := Parse(tt.rule)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.rule, err)
+			continue
+		}
+		got, ok := r.Apply(tt.word)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("%q on %q = %q, %v, want %q, %v", tt.rule, tt.word, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, rule := range []string{"w", "$", "T", "Ta", "s1", "x0", "X01"} {
+		if _, err := Parse(rule); err == nil {
+			t.Errorf("Parse(%q) succeeded", rule)
+		}
+	}
+}
+
+func TestRead(t *testing.T) {
+	rules, err := Read(strings.NewReader("# comment\n:\n\n$1\r\nc $!\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 3 || rules[2].String() != "c $!" {
+		t.Errorf("rules = %v", rules)
+	}
+
+	if _, err := Read(strings.NewReader(":\nbogus\n")); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("error = %v, want line 2", err)
+	}
+
+	best, err := Load("best64")
+	if err != nil {
+		t.Fatalf("Load(best64): %v", err)
+	}
+	if len(best) != 64 {
+		t.Errorf("best64 has %d rules, want 64", len(best))
+	}
+}
+
+func TestEngineStacking(t *testing.T) {
+	first, _ := Read(strings.NewReader(":\nc\n"))
+	second, _ := Read(strings.NewReader("$1\n$2\n<3\n"))
+
+	e := NewEngine(first, second)
+	if e.Size() != 6 {
+		t.Fatalf("Size() = %d, want 6", e.Size())
+	}
+
+	words := make(chan string, 2)
+	words <- "ab"
+	words <- "pass"
+	close(words)
+
+	var got []string
+	for c := range e.Generator(context.Background(), words) {
+		got = append(got, c)
+	}
+	want := "ab1,ab2,ab,Ab1,Ab2,Ab,pass1,pass2,Pass1,Pass2"
+	if strings.Join(got, ",") != want {
+		t.Errorf("candidates = %s, want %s", strings.Join(got, ","), want)
+	}
+
+	if NewEngine().Size() != 1 {
+		t.Error("empty engine should pass words through unchanged")
+	}
+}
@@ -0,0 +1,123 @@
+package attacks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// minShardBytes keeps shards large enough that splitting pays off; smaller
+// files get fewer shards, or are read whole.
+var minShardBytes int64 = 16 << 20
+
+// ShardConfig splits large plain-text wordlists into byte ranges, each read
+// by its own goroutine, for when one reader cannot keep up with the workers.
+// Lines from different shards interleave, so the order is not the file's.
+type ShardConfig struct {
+	// Shards is the number of ranges per file; 0 or 1 reads files whole.
+	Shards int
+	// Mmap maps files into memory rather than reading them.
+	Mmap bool
+}
+
+// count is the number of shards for f. Compressed files and standard input
+// can only be read from the start.
+func (c ShardConfig) count(f *wordlistFile) int {
+	if c.Shards <= 1 || f.raw != nil || f.file == os.Stdin {
+		return 1
+	}
+	return int(min(int64(c.Shards), f.size/minShardBytes))
+}
+
+// ShardKey names shard i of path in checkpoint positions.
+func ShardKey(path string, i int) string {
+	return path + "#" + strconv.Itoa(i)
+}
+
+type byteRange struct {
+	start, end int64
+}
+
+// shardRanges cuts the size bytes of r into n ranges of about equal size,
+// each starting at the beginning of a line. A line that spans a cut goes to
+// the range it starts in, so ranges near the end may be empty.
+func shardRanges(r io.ReaderAt, size int64, n int) ([]byteRange, error) {
+	starts := []int64{0}
+	buf := make([]byte, 64*1024)
+	for i := 1; i < n; i++ {
+		// The first line starting at or after the cut begins after the
+		// first newline at or after the byte before it.
+		pos := max(size*int64(i)/int64(n), starts[len(starts)-1], 1) - 1
+		for pos < size {
+			k, err := r.ReadAt(buf[:min(int64(len(buf)), size-pos)], pos)
+			if j := bytes.IndexByte(buf[:k], '\n'); j >= 0 {
+				pos += int64(j) + 1
+				break
+			}
+			pos += int64(k)
+			if err == io.EOF || k == 0 {
+				pos = size
+			} else if err != nil {
+				return nil, err
+			}
+		}
+		starts = append(starts, min(pos, size))
+	}
+
+	ranges := make([]byteRange, n)
+	for i, start := range starts {
+		end := size
+		if i+1 < n {
+			end = starts[i+1]
+		}
+		ranges[i] = byteRange{start, end}
+	}
+	return ranges, nil
+}
+
+// readShards reads the n shards of f in parallel, each skipping the lines
+// skip holds under its ShardKey, and returns false if the run was cancelled.
+func readShards(ctx context.Context, f *wordlistFile, s *sourceProgress, n int, useMmap bool, skip map[string]uint64, progress *WordlistProgress, norm *Normalizer, ch chan<- string) bool {
+	ranges, err := shardRanges(f.file, f.size, n)
+	if err != nil {
+		// ReadAt leaves f at its start, so it can still be read in one go.
+		fmt.Fprintf(os.Stderr, "\nWordlist: %s: %v; reading it without shards\n", s.path, err)
+		return readLines(ctx, f, f, s, nil, true, skip[s.path], progress, norm, ch)
+	}
+
+	var data []byte
+	if useMmap {
+		data, err = mmapFile(f.file, f.size)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "\nWordlist: %s: %v; reading it instead\n", s.path, err)
+		} else {
+			defer munmapFile(data)
+		}
+	}
+
+	counts := make([]atomic.Uint64, n)
+	s.shards.Store(&counts)
+
+	var cancelled atomic.Bool
+	var wg sync.WaitGroup
+	for i, br := range ranges {
+		var r io.Reader = io.NewSectionReader(f.file, br.start, br.end-br.start)
+		if data != nil {
+			r = bytes.NewReader(data[br.start:br.end])
+		}
+		wg.Add(1)
+		go func(i int, r io.Reader) {
+			defer wg.Done()
+			if !readLines(ctx, r, f, s, &counts[i], i == 0, skip[ShardKey(s.path, i)], progress, norm, ch) {
+				cancelled.Store(true)
+			}
+		}(i, r)
+	}
+	wg.Wait()
+	return !cancelled.Load()
+}
@@ -0,0 +1,117 @@
+package attacks
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestShardRanges(t *testing.T) {
+	data := "alpha\nbeta\ngamma\ndelta\nepsilon\nzeta\neta\ntheta"
+	for n := 1; n <= 10; n++ {
+		ranges, err := shardRanges(strings.NewReader(data), int64(len(data)), n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(ranges) != n {
+			t.Fatalf("n=%d: got %d ranges", n, len(ranges))
+		}
+		var joined string
+		for i, r := range ranges {
+			if r.start > 0 && r.start < r.end && data[r.start-1] != '\n' {
+				t.Errorf("n=%d: range %d starts mid-line at %d", n, i, r.start)
+			}
+			joined += data[r.start:r.end]
+		}
+		if joined != data {
+			t.Errorf("n=%d: ranges cover %q", n, joined)
+		}
+	}
+}
+
+func writeShardedList(t *testing.T, lines int) (string, map[string]bool) {
+	t.Helper()
+	var b strings.Builder
+	want := make(map[string]bool)
+	for i := 0; i < lines; i++ {
+		w := fmt.Sprintf("word%05d", i)
+		b.WriteString(w + "\n")
+		want[w] = true
+	}
+	path := filepath.Join(t.TempDir(), "big.txt")
+	if err := os.WriteFile(path, []byte(b.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path, want
+}
+
+func TestShardedWordlist(t *testing.T) {
+	defer func(n int64) { minShardBytes = n }(minShardBytes)
+	minShardBytes = 1
+
+	path, want := writeShardedList(t, 1000)
+	for _, config := range []ShardConfig{{Shards: 4}, {Shards: 7, Mmap: true}} {
+		progress := &WordlistProgress{}
+		ch, err := WordlistSourcesGenerator(context.Background(), []string{path}, nil, progress, nil, config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := make(map[string]bool)
+		for w := range ch {
+			if got[w] || !want[w] {
+				t.Errorf("%+v: unexpected or repeated %q", config, w)
+			}
+			got[w] = true
+		}
+		if len(got) != len(want) {
+			t.Errorf("%+v: read %d words, want %d", config, len(got), len(want))
+		}
+		if positions := progress.SourcePositions(1000); len(positions) != config.Shards {
+			t.Errorf("%+v: positions %v, want one per shard", config, positions)
+		}
+	}
+}
+
+func TestShardedWordlistResume(t *testing.T) {
+	defer func(n int64) { minShardBytes = n }(minShardBytes)
+	minShardBytes = 1
+
+	// Each shard resumes as far back as the lines buffered in the channel,
+	// so the list must be well over the buffer's size.
+	path, want := writeShardedList(t, 20000)
+	config := ShardConfig{Shards: 4}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	progress := &WordlistProgress{}
+	ch, err := WordlistSourcesGenerator(ctx, []string{path}, nil, progress, nil, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := make(map[string]bool)
+	for len(seen) < 10000 {
+		seen[<-ch] = true
+	}
+	cancel()
+	for range ch {
+	}
+
+	positions := progress.SourcePositions(10000)
+	ch, err = WordlistSourcesGenerator(context.Background(), []string{path}, positions, nil, nil, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resumed := 0
+	for w := range ch {
+		seen[w] = true
+		resumed++
+	}
+	if len(seen) != len(want) {
+		t.Errorf("resuming from %v missed %d words", positions, len(want)-len(seen))
+	}
+	if resumed >= len(want) {
+		t.Errorf("resuming from %v read all %d words again", positions, resumed)
+	}
+}
@@ -0,0 +1,292 @@
+package attacks
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// Stdin is the wordlist name that reads standard input.
+const Stdin = "-"
+
+// wordlistFile is an open wordlist, decompressed if needed.
+type wordlistFile struct {
+	io.Reader
+	file *os.File
+	size int64
+	// raw counts the compressed bytes consumed; it is nil for plain text,
+	// where line lengths give the position.
+	raw *countingReader
+}
+
+type countingReader struct {
+	r io.Reader
+	n atomic.Int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n.Add(int64(n))
+	return n, err
+}
+
+func (f *wordlistFile) Close() error {
+	if f.file == os.Stdin {
+		return nil
+	}
+	return f.file.Close()
+}
+
+// openWordlistFile opens path, or standard input for "-", and detects gzip
+// and bzip2 compression from the first bytes rather than the file name.
+func openWordlistFile(path string) (*wordlistFile, error) {
+	f := &wordlistFile{file: os.Stdin}
+	if path != Stdin {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		f.file = file
+		if st, err := file.Stat(); err == nil && st.Mode().IsRegular() {
+			f.size = st.Size()
+		}
+	}
+
+	raw := &countingReader{r: f.file}
+	br := bufio.NewReaderSize(raw, 64*1024)
+	magic, _ := br.Peek(3)
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		f.Reader, f.raw = zr, raw
+	case bytes.HasPrefix(magic, []byte("BZh")):
+		f.Reader, f.raw = bzip2.NewReader(br), raw
+	default:
+		f.Reader = br
+	}
+	return f, nil
+}
+
+// OpenWordlist opens a wordlist file, or standard input for "-",
+// decompressing gzip and bzip2 transparently.
+func OpenWordlist(path string) (io.ReadCloser, error) {
+	return openWordlistFile(path)
+}
+
+// ExpandWordlists turns wordlist arguments into the files to read, in order.
+// A directory stands for every file below it and a pattern with *, ? or [
+// for its matches, both in lexical order; "-" is standard input.
+func ExpandWordlists(args []string) ([]string, error) {
+	var sources []string
+	for _, arg := range args {
+		if arg == Stdin {
+			sources = append(sources, arg)
+			continue
+		}
+
+		paths := []string{arg}
+		if strings.ContainsAny(arg, "*?[") {
+			matches, err := filepath.Glob(arg)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", arg, err)
+			}
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("%s: no wordlists match", arg)
+			}
+			sort.Strings(matches)
+			paths = matches
+		}
+
+		for _, path := range paths {
+			st, err := os.Stat(path)
+			if err != nil {
+				return nil, err
+			}
+			if !st.IsDir() {
+				sources = append(sources, path)
+				continue
+			}
+			found := false
+			err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if d.Type().IsRegular() {
+					sources = append(sources, p)
+					found = true
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+			if !found {
+				return nil, fmt.Errorf("%s: no wordlists in directory", path)
+			}
+		}
+	}
+	return sources, nil
+}
+
+// sourceProgress is one source's share of a WordlistProgress.
+type sourceProgress struct {
+	path  string
+	lines atomic.Uint64
+	done  atomic.Bool
+	// shards counts the lines read from each shard of a split source.
+	shards atomic.Pointer[[]atomic.Uint64]
+}
+
+// SourcePositions splits position, a line count across all sources, into
+// the line reached in each source, or in each shard of a split source under
+// ShardKey. Sources not reached yet are left out.
+func (p *WordlistProgress) SourcePositions(position uint64) map[string]uint64 {
+	positions := make(map[string]uint64)
+	sources := p.sources.Load()
+	if sources == nil {
+		return positions
+	}
+	for _, s := range *sources {
+		lines := s.lines.Load()
+		shards := s.shards.Load()
+		if !s.done.Load() || position < lines {
+			if shards == nil {
+				if position > 0 {
+					positions[s.path] = position
+				}
+				break
+			}
+			// Shards are read in parallel, so the lines not tried yet may
+			// all come from any one of them; each resumes that far back.
+			behind := lines - min(position, lines)
+			for i := range *shards {
+				if n := (*shards)[i].Load(); n > behind {
+					positions[ShardKey(s.path, i)] = n - behind
+				}
+			}
+			break
+		}
+		if shards == nil {
+			positions[s.path] = lines
+		} else {
+			for i := range *shards {
+				positions[ShardKey(s.path, i)] = (*shards)[i].Load()
+			}
+		}
+		position -= lines
+	}
+	return positions
+}
+
+// WordlistSourcesGenerator reads the sources one after another, skipping the
+// first skip[path] lines of each, and records the combined read position in
+// progress, if non-nil. Lines go through norm; a nil norm only does the
+// cleanup every reader does. Large plain files are split as shards says.
+// Missing files are reported before anything is read.
+func WordlistSourcesGenerator(ctx context.Context, sources []string, skip map[string]uint64, progress *WordlistProgress, norm *Normalizer, shards ShardConfig) (<-chan string, error) {
+	if progress == nil {
+		progress = &WordlistProgress{}
+	}
+	progress.size = 0
+	var states []*sourceProgress
+	for _, path := range sources {
+		if path != Stdin {
+			st, err := os.Stat(path)
+			if err != nil {
+				return nil, err
+			}
+			if st.Mode().IsRegular() {
+				progress.size += st.Size()
+			}
+		}
+		states = append(states, &sourceProgress{path: path})
+	}
+	progress.sources.Store(&states)
+
+	ch := make(chan string, 1000)
+
+	go func() {
+		defer close(ch)
+
+		var base int64
+		for _, s := range states {
+			f, err := openWordlistFile(s.path)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "\nWordlist: %v\n", err)
+				return
+			}
+			var ok bool
+			if n := shards.count(f); n > 1 {
+				ok = readShards(ctx, f, s, n, shards.Mmap, skip, progress, norm, ch)
+			} else {
+				ok = readLines(ctx, f, f, s, nil, true, skip[s.path], progress, norm, ch)
+			}
+			f.Close()
+			if !ok {
+				return
+			}
+			s.done.Store(true)
+			base += f.size
+			progress.offset.Store(base)
+		}
+		progress.offset.Store(progress.size)
+	}()
+
+	return ch, nil
+}
+
+// readLines sends the lines of r, all of f or one shard of it, and returns
+// false if the run was cancelled. start marks r as beginning at the start of
+// f. For a shard, shard is its line count, which skip then applies to.
+func readLines(ctx context.Context, r io.Reader, f *wordlistFile, s *sourceProgress, shard *atomic.Uint64, start bool, skip uint64, progress *WordlistProgress, norm *Normalizer, ch chan<- string) bool {
+	scanner := bufio.NewScanner(r)
+	buf := make([]byte, 0, 64*1024)
+	scanner.Buffer(buf, 1024*1024)
+
+	var read int64
+	for scanner.Scan() {
+		switch {
+		case f.raw != nil:
+			n := f.raw.n.Load()
+			progress.offset.Add(n - read)
+			read = n
+		case f.size > 0:
+			progress.offset.Add(int64(len(scanner.Bytes()) + 1))
+		}
+		progress.lines.Add(1)
+		line := s.lines.Add(1)
+		if shard != nil {
+			line = shard.Add(1)
+		}
+		if line <= skip {
+			continue
+		}
+		word, ok := norm.Line(scanner.Text(), start && line == 1)
+		if !ok {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return false
+		case ch <- word:
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		fmt.Fprintf(os.Stderr, "\nWordlist: %s: %v\n", s.path, err)
+	}
+	return true
+}
@@ -0,0 +1,119 @@
+package attacks
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// bzip2 of "delta\nepsilon\n"; the standard library only decompresses.
+var bzip2Words = []byte{
+	0x42, 0x5a, 0x68, 0x39, 0x31, 0x41, 0x59, 0x26, 0x53, 0x59, 0x54, 0xd7, 0xa7, 0x7b, 0x00, 0x00,
+	0x01, 0xc1, 0x80, 0x00, 0x10, 0x26, 0x25, 0xcc, 0x00, 0x20, 0x00, 0x22, 0x0d, 0x01, 0x90, 0x80,
+	0x69, 0xa6, 0x8c, 0x0a, 0x6a, 0x16, 0xe9, 0x21, 0x0f, 0x17, 0x72, 0x45, 0x38, 0x50, 0x90, 0x54,
+	0xd7, 0xa7, 0x7b,
+}
+
+func writeSources(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write([]byte("beta\ngamma\n"))
+	zw.Close()
+
+	files := map[string][]byte{
+		"a/1.txt":      []byte("alpha\n"),
+		"a/2.list":     gz.Bytes(), // compression is detected, not named
+		"b/words.bz2":  bzip2Words,
+		"c/zeta.txt":   []byte("zeta\n"),
+		"c/omega.text": []byte("omega\n"),
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestExpandWordlists(t *testing.T) {
+	dir := writeSources(t)
+	sources, err := ExpandWordlists([]string{filepath.Join(dir, "a"), filepath.Join(dir, "c", "*.txt"), "-", filepath.Join(dir, "b", "words.bz2")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, s := range sources {
+		rel, _ := filepath.Rel(dir, s)
+		if s == Stdin {
+			rel = s
+		}
+		got = append(got, filepath.ToSlash(rel))
+	}
+	want := "a/1.txt,a/2.list,c/zeta.txt,-,b/words.bz2"
+	if strings.Join(got, ",") != want {
+		t.Errorf("sources = %s, want %s", strings.Join(got, ","), want)
+	}
+
+	for _, arg := range []string{filepath.Join(dir, "*.none"), filepath.Join(dir, "missing.txt")} {
+		if _, err := ExpandWordlists([]string{arg}); err == nil {
+			t.Errorf("%s: expected an error", arg)
+		}
+	}
+}
+
+func TestWordlistSourcesGenerator(t *testing.T) {
+	dir := writeSources(t)
+	sources, err := ExpandWordlists([]string{filepath.Join(dir, "a"), filepath.Join(dir, "b")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		skip map[string]uint64
+		want string
+	}{
+		{nil, "alpha,beta,gamma,delta,epsilon"},
+		{map[string]uint64{sources[0]: 1, sources[1]: 1}, "gamma,delta,epsilon"},
+		{map[string]uint64{sources[2]: 5}, "alpha,beta,gamma"},
+	}
+	for _, tt := range tests {
+		progress := &WordlistProgress{}
+		ch, err := WordlistSourcesGenerator(context.Background(), sources, tt.skip, progress, nil, ShardConfig{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for w := range ch {
+			got = append(got, w)
+		}
+		if strings.Join(got, ",") != tt.want {
+			t.Errorf("skip %v: got %s, want %s", tt.skip, strings.Join(got, ","), tt.want)
+		}
+		if progress.Fraction() != 1 {
+			t.Errorf("skip %v: fraction %.2f after reading everything", tt.skip, progress.Fraction())
+		}
+	}
+
+	// Positions count lines across sources, skipped ones included.
+	progress := &WordlistProgress{}
+	ch, _ := WordlistSourcesGenerator(context.Background(), sources, nil, progress, nil, ShardConfig{})
+	for range ch {
+	}
+	got := progress.SourcePositions(4)
+	want := map[string]uint64{sources[0]: 1, sources[1]: 2, sources[2]: 1}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SourcePositions(4) = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,335 @@
+package attacks
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// TemplateKeyspace indexes every candidate of a template: known fragments
+// of a password with the unknown parts in braces.
+//
+//	Rex{?a,1-4}{?d?d}{?s}
+//
+// Text outside braces is literal. A brace segment is one of
+//
+//	{Rex|rex|REX}  alternatives, tried in order; an empty one is allowed
+//	{?a,1-4}       one charset repeated 1 to 4 times, shortest first; {?d,3}
+//	               is exactly 3
+//	{?u?l?d}       one charset per position, as in a mask
+//
+// where a charset is a mask placeholder (?l ?u ?d ?s ?a ?h ?H ?b ?1-?4) or a
+// set in brackets such as [aeiou], [a-f0-9] or [?d!@#]. A ? right after the
+// closing brace makes the segment optional, tried absent first. A backslash
+// escapes the next character anywhere. The first segment changes slowest.
+type TemplateKeyspace struct {
+	product productKeyspace
+}
+
+func NewTemplateKeyspace(template string, custom [4]string) (*TemplateKeyspace, error) {
+	slots, err := parseTemplate(template, custom)
+	if err != nil {
+		return nil, fmt.Errorf("template %q: %w", template, err)
+	}
+	if len(slots) == 0 {
+		return nil, fmt.Errorf("empty template")
+	}
+	ks := &TemplateKeyspace{product: productKeyspace{slots: slots}}
+	ks.product.init()
+	if ks.product.overflow {
+		return nil, fmt.Errorf("template %q has more than 2^64 candidates", template)
+	}
+	return ks, nil
+}
+
+func (ks *TemplateKeyspace) Size() uint64 {
+	return ks.product.Size()
+}
+
+func (ks *TemplateKeyspace) At(index uint64) string {
+	return ks.product.At(index)
+}
+
+// optionalKeyspace is the empty string followed by inner's candidates.
+type optionalKeyspace struct {
+	inner Keyspace
+}
+
+func (ks optionalKeyspace) Size() uint64 {
+	return ks.inner.Size() + 1
+}
+
+func (ks optionalKeyspace) At(index uint64) string {
+	if index == 0 {
+		return ""
+	}
+	return ks.inner.At(index - 1)
+}
+
+func parseTemplate(template string, custom [4]string) ([]Keyspace, error) {
+	var slots []Keyspace
+	var literal strings.Builder
+	flush := func() {
+		if literal.Len() > 0 {
+			slots = append(slots, valueList{literal.String()})
+			literal.Reset()
+		}
+	}
+
+	for i := 0; i < len(template); i++ {
+		switch c := template[i]; c {
+		case '\\':
+			if i+1 >= len(template) {
+				return nil, fmt.Errorf("ends with '\\'")
+			}
+			i++
+			literal.WriteByte(template[i])
+		case '}':
+			return nil, fmt.Errorf("'}' at %d closes no segment", i)
+		case '{':
+			end, err := segmentEnd(template, i+1)
+			if err != nil {
+				return nil, err
+			}
+			slot, err := parseSegment(template[i+1:end], custom)
+			if err != nil {
+				return nil, err
+			}
+			i = end
+			if i+1 < len(template) && template[i+1] == '?' {
+				slot = optionalKeyspace{slot}
+				i++
+			}
+			flush()
+			slots = append(slots, slot)
+		default:
+			literal.WriteByte(c)
+		}
+	}
+	flush()
+	return slots, nil
+}
+
+// segmentEnd finds the brace closing the segment whose body starts at start,
+// skipping escaped characters and bracket sets.
+func segmentEnd(template string, start int) (int, error) {
+	inSet := false
+	for i := start; i < len(template); i++ {
+		switch template[i] {
+		case '\\':
+			i++
+		case '[':
+			inSet = true
+		case ']':
+			inSet = false
+		case '{':
+			if !inSet {
+				return 0, fmt.Errorf("segments cannot be nested")
+			}
+		case '}':
+			if !inSet {
+				return i, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("unclosed '{' at %d", start-1)
+}
+
+// splitUnescaped splits s at sep where it is neither escaped nor inside a
+// bracket set, keeping the escapes.
+func splitUnescaped(s string, sep byte) []string {
+	var parts []string
+	inSet, from := false, 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '[':
+			inSet = true
+		case ']':
+			inSet = false
+		case sep:
+			if !inSet {
+				parts = append(parts, s[from:i])
+				from = i + 1
+			}
+		}
+	}
+	return append(parts, s[from:])
+}
+
+func parseSegment(body string, custom [4]string) (Keyspace, error) {
+	if alts := splitUnescaped(body, '|'); len(alts) > 1 {
+		values := make(valueList, len(alts))
+		for i, alt := range alts {
+			values[i] = unescapeTemplate(alt)
+		}
+		return values, nil
+	}
+
+	if parts := splitUnescaped(body, ','); len(parts) == 2 {
+		charsets, err := parseTemplateCharsets(parts[0], custom)
+		if err != nil {
+			return nil, err
+		}
+		if len(charsets) != 1 {
+			return nil, fmt.Errorf("{%s}: a repeated segment takes a single charset", body)
+		}
+		minLen, maxLen, err := parseRepeat(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("{%s}: %w", body, err)
+		}
+		positions := make([]string, maxLen)
+		for i := range positions {
+			positions[i] = charsets[0]
+		}
+		return checkedPositions(body, positions, minLen, maxLen)
+	}
+
+	charsets, err := parseTemplateCharsets(body, custom)
+	if err != nil {
+		return nil, err
+	}
+	if len(charsets) == 0 {
+		return nil, fmt.Errorf("empty segment {}")
+	}
+	return checkedPositions(body, charsets, len(charsets), len(charsets))
+}
+
+func checkedPositions(body string, charsets []string, minLen, maxLen int) (Keyspace, error) {
+	ks := newPositionsKeyspace(charsets, minLen, maxLen)
+	if ks.Overflow() {
+		return nil, fmt.Errorf("{%s} has more than 2^64 candidates", body)
+	}
+	return ks, nil
+}
+
+// parseRepeat reads N or N-M.
+func parseRepeat(s string) (minLen, maxLen int, err error) {
+	lo, hi, isRange := strings.Cut(s, "-")
+	if minLen, err = strconv.Atoi(lo); err != nil {
+		return 0, 0, fmt.Errorf("invalid repeat count %q", s)
+	}
+	maxLen = minLen
+	if isRange {
+		if maxLen, err = strconv.Atoi(hi); err != nil {
+			return 0, 0, fmt.Errorf("invalid repeat count %q", s)
+		}
+	}
+	if minLen < 0 || maxLen < minLen {
+		return 0, 0, fmt.Errorf("invalid repeat range %q", s)
+	}
+	return minLen, maxLen, nil
+}
+
+// parseTemplateCharsets returns one charset per position of a mask-like
+// body: placeholders, bracket sets and literal characters.
+func parseTemplateCharsets(body string, custom [4]string) ([]string, error) {
+	var charsets []string
+	for i := 0; i < len(body); i++ {
+		switch body[i] {
+		case '\\':
+			if i+1 >= len(body) {
+				return nil, fmt.Errorf("segment ends with '\\'")
+			}
+			i++
+			charsets = append(charsets, body[i:i+1])
+		case '?':
+			if i+1 >= len(body) {
+				return nil, fmt.Errorf("segment ends with '?'")
+			}
+			cs, err := ParseMask(body[i:i+2], custom)
+			if err != nil {
+				return nil, err
+			}
+			charsets = append(charsets, cs[0])
+			i++
+		case '[':
+			end := i + 1
+			for end < len(body) && body[end] != ']' {
+				if body[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(body) {
+				return nil, fmt.Errorf("unclosed '[' in {%s}", body)
+			}
+			cs, err := parseSet(body[i+1:end], custom)
+			if err != nil {
+				return nil, err
+			}
+			charsets = append(charsets, cs)
+			i = end
+		default:
+			charsets = append(charsets, body[i:i+1])
+		}
+	}
+	return charsets, nil
+}
+
+// parseSet expands a bracket set: literal characters, a-z style ranges and
+// placeholders; a ? not followed by a placeholder is literal. Duplicates are
+// dropped.
+func parseSet(set string, custom [4]string) (string, error) {
+	var out []byte
+	seen := make(map[byte]bool)
+	add := func(c byte) {
+		if !seen[c] {
+			seen[c] = true
+			out = append(out, c)
+		}
+	}
+
+	for i := 0; i < len(set); i++ {
+		c := set[i]
+		switch {
+		case c == '\\' && i+1 < len(set):
+			i++
+			add(set[i])
+		case c == '?' && i+1 < len(set) && isPlaceholder(set[i+1]):
+			cs, err := ParseMask(set[i:i+2], custom)
+			if err != nil {
+				return "", err
+			}
+			for j := 0; j < len(cs[0]); j++ {
+				add(cs[0][j])
+			}
+			i++
+		case i+2 < len(set) && set[i+1] == '-':
+			hi := set[i+2]
+			if hi < c {
+				return "", fmt.Errorf("invalid range %c-%c in [%s]", c, hi, set)
+			}
+			for b := int(c); b <= int(hi); b++ {
+				add(byte(b))
+			}
+			i += 2
+		default:
+			add(c)
+		}
+	}
+	if len(out) == 0 {
+		return "", fmt.Errorf("empty set []")
+	}
+	return string(out), nil
+}
+
+func isPlaceholder(c byte) bool {
+	_, ok := builtinMaskCharset(c)
+	return ok || c == '?' || c >= '1' && c <= '4'
+}
+
+func unescapeTemplate(s string) string {
+	if !strings.Contains(s, "\\") {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,66 @@
+package attacks
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTemplateKeyspace(t *testing.T) {
+	tests := []struct {
+		template string
+		custom   [4]string
+		want     string
+	}{
+		{"ab", [4]string{}, "ab"},
+		{"{Rex|rex}!", [4]string{}, "Rex!,rex!"},
+		{"x{[01],1-2}", [4]string{}, "x0,x1,x00,x01,x10,x11"},
+		{"{[a-c]}{?1}", [4]string{"xy"}, "ax,ay,bx,by,cx,cy"},
+		{"a{!}?", [4]string{}, "a,a!"},
+		{"{|_}{?d,0-1}", [4]string{}, ",0,1,2,3,4,5,6,7,8,9,_,_0,_1,_2,_3,_4,_5,_6,_7,_8,_9"},
+		{`\{{\||\,}\}`, [4]string{}, "{|},{,}"},
+		{"{[\\]\\-]}", [4]string{}, "],-"},
+		{"{[?.]}{[?d]}", [4]string{}, "?0,?1,?2,?3,?4,?5,?6,?7,?8,?9,.0,.1,.2,.3,.4,.5,.6,.7,.8,.9"},
+	}
+	for _, tt := range tests {
+		ks, err := NewTemplateKeyspace(tt.template, tt.custom)
+		if err != nil {
+			t.Errorf("%s: %v", tt.template, err)
+			continue
+		}
+		if got := strings.Join(keyspaceAll(t, ks), ","); got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.template, got, tt.want)
+		}
+	}
+
+	ks, err := NewTemplateKeyspace("Rex{?a,1-4}{?d?d}{?s}", [4]string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, s := uint64(95), uint64(33)
+	if want := (a + a*a + a*a*a + a*a*a*a) * 100 * s; ks.Size() != want {
+		t.Errorf("Size() = %d, want %d", ks.Size(), want)
+	}
+	if got := ks.At(0); got != "Rexa00 " {
+		t.Errorf("At(0) = %q", got)
+	}
+}
+
+func TestTemplateErrors(t *testing.T) {
+	for _, template := range []string{
+		"",
+		"abc{",
+		"abc}",
+		"{a{b}}",
+		"{?x}",
+		"{?1}",
+		"{?d?l,2}",
+		"{?d,3-1}",
+		"{?d,x}",
+		"{[z-a]}",
+		"{?b,1-9}",
+	} {
+		if _, err := NewTemplateKeyspace(template, [4]string{}); err == nil {
+			t.Errorf("%q: no error", template)
+		}
+	}
+}
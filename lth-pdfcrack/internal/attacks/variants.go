@@ -0,0 +1,401 @@
+package attacks
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/lth/pdfcrack/internal/attacks/rules"
+)
+
+// AllToggles lets every letter of a word change case.
+const AllToggles = -1
+
+// VariantConfig describes how each word is varied: case toggles, leetspeak
+// and accents, in any combination.
+type VariantConfig struct {
+	// MaxToggles is how many letters may change case: 0 for none, or
+	// AllToggles.
+	MaxToggles int
+	// Leet maps a lower-case letter to the characters that may stand in
+	// for it, as from LeetTable.
+	Leet map[rune][]rune
+	// StripAccents lets accented letters lose their accent, and AddAccents
+	// lets plain letters take one.
+	StripAccents bool
+	AddAccents   bool
+	// MaxVariants caps the variants of each word, which come fewest changes
+	// first; 0 means no cap.
+	MaxVariants uint64
+}
+
+var leetTables = map[string]string{
+	"basic": "a:4@,e:3,i:1,o:0,s:5$",
+	"full":  "a:4@,b:8,e:3,g:9,i:1!,l:1,o:0,s:5$,t:7,z:2",
+}
+
+// LeetTable returns the built-in table "basic" or "full", or parses a table
+// written like them: letters, each with the characters that replace it.
+func LeetTable(spec string) (map[rune][]rune, error) {
+	if builtin, ok := leetTables[strings.ToLower(spec)]; ok {
+		spec = builtin
+	}
+	table := make(map[rune][]rune)
+	for _, entry := range strings.Split(spec, ",") {
+		from, to, ok := strings.Cut(entry, ":")
+		if !ok || utf8.RuneCountInString(from) != 1 || to == "" {
+			return nil, fmt.Errorf("bad leet substitution %q (want e.g. a:4@ or basic, full)", entry)
+		}
+		r, _ := utf8.DecodeRuneInString(from)
+		r = unicode.ToLower(r)
+		table[r] = append(table[r], []rune(to)...)
+	}
+	return table, nil
+}
+
+// accents lists the accented forms of each plain letter, most common first.
+var accents = map[rune]string{
+	'a': "àáâäãå",
+	'c': "ç",
+	'e': "éèêë",
+	'i': "íìîï",
+	'n': "ñ",
+	'o': "óòôöõ",
+	'u': "úùûü",
+	'y': "ýÿ",
+}
+
+// plainLetters maps each accented letter to its plain one.
+var plainLetters = func() map[rune]rune {
+	m := make(map[rune]rune)
+	for plain, forms := range accents {
+		for _, r := range forms {
+			m[r] = plain
+		}
+	}
+	return m
+}()
+
+// Variants expands words into their variants.
+type Variants struct {
+	config VariantConfig
+}
+
+func NewVariants(config VariantConfig) *Variants {
+	return &Variants{config: config}
+}
+
+// Count returns the number of variants of word, the word itself included.
+func (v *Variants) Count(word string) uint64 {
+	return v.expand(word).size
+}
+
+// variantAlt is a character that may replace one of the word's; toggle
+// marks a case change, which counts against MaxToggles.
+type variantAlt struct {
+	r      rune
+	toggle bool
+}
+
+// wordVariants numbers the variants of one word. A variant picks, for each
+// position, the original character or one of its alternatives; variants
+// with fewer changed positions come first.
+type wordVariants struct {
+	runes []rune
+	alts  [][]variantAlt
+	maxK  int
+	maxT  int
+	// ways[(i*(maxK+1)+k)*(maxT+1)+t] counts the ways to vary positions i
+	// onwards with exactly k changes, at most t of them case toggles.
+	ways []uint64
+	size uint64
+}
+
+func (v *Variants) expand(word string) *wordVariants {
+	w := &wordVariants{runes: []rune(word)}
+	w.alts = make([][]variantAlt, len(w.runes))
+	changeable := 0
+	for i, r := range w.runes {
+		w.alts[i] = v.alternatives(r)
+		if len(w.alts[i]) > 0 {
+			changeable++
+		}
+	}
+
+	// Find how many changes it takes to reach the cap, widening the table
+	// until it does rather than sizing it for every change up front.
+	limit := v.config.MaxVariants
+	w.maxK = min(4, changeable)
+	for {
+		w.maxT = w.maxK
+		if v.config.MaxToggles != AllToggles {
+			w.maxT = min(v.config.MaxToggles, w.maxK)
+		}
+		w.count()
+		if w.maxK == changeable || limit > 0 && w.size >= limit {
+			break
+		}
+		w.maxK = min(2*w.maxK, changeable)
+	}
+	if limit > 0 && w.size > limit {
+		w.size = limit
+	}
+	return w
+}
+
+func (v *Variants) alternatives(r rune) []variantAlt {
+	var alts []variantAlt
+	add := func(c rune, toggle bool) {
+		if c == r {
+			return
+		}
+		for _, a := range alts {
+			if a.r == c {
+				return
+			}
+		}
+		alts = append(alts, variantAlt{c, toggle})
+	}
+	// sameCase puts c in the case of r.
+	sameCase := func(c rune) rune {
+		if unicode.IsUpper(r) {
+			return unicode.ToUpper(c)
+		}
+		return c
+	}
+
+	lower := unicode.ToLower(r)
+	if v.config.MaxToggles != 0 {
+		if unicode.IsUpper(r) {
+			add(lower, true)
+		} else {
+			add(unicode.ToUpper(r), true)
+		}
+	}
+	for _, c := range v.config.Leet[lower] {
+		add(c, false)
+	}
+	if plain, ok := plainLetters[lower]; ok && v.config.StripAccents {
+		add(sameCase(plain), false)
+	}
+	if v.config.AddAccents {
+		for _, c := range accents[lower] {
+			add(sameCase(c), false)
+		}
+	}
+	return alts
+}
+
+func (w *wordVariants) index(i, k, t int) int {
+	return (i*(w.maxK+1)+k)*(w.maxT+1) + t
+}
+
+func (w *wordVariants) count() {
+	n := len(w.runes)
+	w.ways = make([]uint64, (n+1)*(w.maxK+1)*(w.maxT+1))
+	for t := 0; t <= w.maxT; t++ {
+		w.ways[w.index(n, 0, t)] = 1
+	}
+	for i := n - 1; i >= 0; i-- {
+		for k := 0; k <= w.maxK; k++ {
+			for t := 0; t <= w.maxT; t++ {
+				ways := w.ways[w.index(i+1, k, t)]
+				if k > 0 {
+					for _, a := range w.alts[i] {
+						ways = addSaturating64(ways, w.next(i, k, t, a))
+					}
+				}
+				w.ways[w.index(i, k, t)] = ways
+			}
+		}
+	}
+	w.size = 0
+	for k := 0; k <= w.maxK; k++ {
+		w.size = addSaturating64(w.size, w.ways[w.index(0, k, w.maxT)])
+	}
+}
+
+// next counts the ways to finish after changing position i to a, with k
+// changes and t toggles left before it.
+func (w *wordVariants) next(i, k, t int, a variantAlt) uint64 {
+	if !a.toggle {
+		return w.ways[w.index(i+1, k-1, t)]
+	}
+	if t == 0 {
+		return 0
+	}
+	return w.ways[w.index(i+1, k-1, t-1)]
+}
+
+func (w *wordVariants) at(index uint64) string {
+	k := 0
+	for ; k < w.maxK; k++ {
+		n := w.ways[w.index(0, k, w.maxT)]
+		if index < n {
+			break
+		}
+		index -= n
+	}
+	t := w.maxT
+	out := make([]rune, len(w.runes))
+	for i, r := range w.runes {
+		// Changes to earlier positions come first: Abc, aBc, abC.
+		out[i] = r
+		for _, a := range w.alts[i] {
+			if k == 0 {
+				break
+			}
+			n := w.next(i, k, t, a)
+			if index < n {
+				out[i] = a.r
+				k--
+				if a.toggle {
+					t--
+				}
+				break
+			}
+			index -= n
+		}
+	}
+	return string(out)
+}
+
+// VariantKeyspace numbers the variants of a list of words, word by word.
+type VariantKeyspace struct {
+	words    []string
+	variants *Variants
+	// ends[i] is the number of variants of words[:i+1].
+	ends []uint64
+	// last caches the word At expanded most recently, as At is called in
+	// order from one goroutine.
+	last      *wordVariants
+	lastIndex int
+}
+
+func NewVariantKeyspace(words []string, v *Variants) *VariantKeyspace {
+	ks := &VariantKeyspace{words: words, variants: v, ends: make([]uint64, len(words)), lastIndex: -1}
+	var total uint64
+	for i, w := range words {
+		total = addSaturating64(total, v.Count(w))
+		ks.ends[i] = total
+	}
+	return ks
+}
+
+func (ks *VariantKeyspace) Size() uint64 {
+	if len(ks.ends) == 0 {
+		return 0
+	}
+	return ks.ends[len(ks.ends)-1]
+}
+
+// Overflow reports more than 2^64 variants; Size then saturates.
+func (ks *VariantKeyspace) Overflow() bool {
+	return ks.Size() == math.MaxUint64
+}
+
+func (ks *VariantKeyspace) At(index uint64) string {
+	i := sort.Search(len(ks.ends), func(i int) bool { return ks.ends[i] > index })
+	if i == len(ks.ends) {
+		return ""
+	}
+	if i != ks.lastIndex {
+		ks.last, ks.lastIndex = ks.variants.expand(ks.words[i]), i
+	}
+	if i > 0 {
+		index -= ks.ends[i-1]
+	}
+	return ks.last.at(index)
+}
+
+// variantMark is how many words go between the positions VariantProgress
+// keeps for good.
+const variantMark = 256
+
+// VariantProgress maps candidates tried back to the words they came from,
+// which a checkpoint needs as each word has its own number of variants.
+// It keeps every variantMark-th word's end and the ends of the words since.
+type VariantProgress struct {
+	mu     sync.Mutex
+	total  uint64
+	marks  []uint64
+	recent []uint64
+}
+
+func (p *VariantProgress) add(n uint64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.total += n
+	p.recent = append(p.recent, p.total)
+	if len(p.recent) == variantMark {
+		p.marks = append(p.marks, p.total)
+		p.recent = p.recent[:0]
+	}
+}
+
+// Words returns how many words had all of their candidates among the first
+// candidates generated, rounded down to a multiple of 256 words when it is
+// more than 256 words behind.
+func (p *VariantProgress) Words(candidates uint64) uint64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	j := sort.Search(len(p.marks), func(j int) bool { return p.marks[j] > candidates })
+	words := uint64(j) * variantMark
+	if j == len(p.marks) {
+		words += uint64(sort.Search(len(p.recent), func(i int) bool { return p.recent[i] > candidates }))
+	}
+	return words
+}
+
+// VariantGenerator expands each word from words through engine's rules, if
+// any, and then into its variants, recording each word's count in
+// progress.
+func VariantGenerator(ctx context.Context, words <-chan string, engine *rules.Engine, v *Variants, progress *VariantProgress) <-chan string {
+	ch := make(chan string, 1000)
+
+	go func() {
+		defer close(ch)
+		send := func(word string) (uint64, bool) {
+			w := v.expand(word)
+			for i := uint64(0); i < w.size; i++ {
+				select {
+				case <-ctx.Done():
+					return i, false
+				case ch <- w.at(i):
+				}
+			}
+			return w.size, true
+		}
+
+		for word := range words {
+			var n uint64
+			if engine == nil {
+				sent, ok := send(word)
+				if n += sent; !ok {
+					return
+				}
+			} else {
+				for i := uint64(0); i < engine.Size(); i++ {
+					candidate, ok := engine.Apply(word, i)
+					if !ok {
+						continue
+					}
+					sent, ok := send(candidate)
+					if n += sent; !ok {
+						return
+					}
+				}
+			}
+			if progress != nil {
+				progress.add(n)
+			}
+		}
+	}()
+
+	return ch
+}
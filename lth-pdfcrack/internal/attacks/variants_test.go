@@ -0,0 +1,83 @@
+package attacks
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestVariants(t *testing.T) {
+	basic, err := LeetTable("basic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		config VariantConfig
+		word   string
+		want   string
+	}{
+		{VariantConfig{MaxToggles: AllToggles}, "ab", "ab,Ab,aB,AB"},
+		{VariantConfig{MaxToggles: 1}, "abc", "abc,Abc,aBc,abC"},
+		{VariantConfig{MaxToggles: AllToggles}, "a1", "a1,A1"},
+		{VariantConfig{Leet: basic}, "sea", "sea,5ea,$ea,s3a,se4,se@,53a,5e4,5e@,$3a,$e4,$e@,s34,s3@,534,53@,$34,$3@"},
+		{VariantConfig{Leet: basic, MaxVariants: 3}, "sea", "sea,5ea,$ea"},
+		{VariantConfig{StripAccents: true}, "Élève", "Élève,Elève,Éleve,Eleve"},
+		{VariantConfig{AddAccents: true, MaxVariants: 4}, "Cafe", "Cafe,Çafe,Càfe,Cáfe"},
+		{VariantConfig{MaxToggles: 1, Leet: basic}, "Os", "Os,os,0s,OS,O5,O$,o5,o$,0S,05,0$"},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(keyspaceAll(t, NewVariantKeyspace([]string{tt.word}, NewVariants(tt.config))), ","); got != tt.want {
+			t.Errorf("%+v %q = %q, want %q", tt.config, tt.word, got, tt.want)
+		}
+	}
+
+	// Counting must agree with enumeration past the first table size.
+	v := NewVariants(VariantConfig{MaxToggles: 3, Leet: basic})
+	all := keyspaceAll(t, NewVariantKeyspace([]string{"Password"}, v))
+	seen := make(map[string]bool)
+	for _, w := range all {
+		if seen[w] {
+			t.Errorf("%q repeats", w)
+		}
+		seen[w] = true
+	}
+	if !seen["P4$$w0rD"] || seen["PA55WORD"] || v.Count("Password") != uint64(len(all)) {
+		t.Errorf("Password: %d variants", len(all))
+	}
+
+	if _, err := LeetTable("a=4"); err == nil {
+		t.Error("bad leet table accepted")
+	}
+}
+
+func TestVariantKeyspace(t *testing.T) {
+	v := NewVariants(VariantConfig{MaxToggles: AllToggles})
+	ks := NewVariantKeyspace([]string{"ab", "1", "c"}, v)
+	var got []string
+	for i := uint64(0); i < ks.Size(); i++ {
+		got = append(got, ks.At(i))
+	}
+	if strings.Join(got, ",") != "ab,Ab,aB,AB,1,c,C" {
+		t.Errorf("keyspace = %q", got)
+	}
+
+	words := make(chan string, 1200)
+	for i := 0; i < 600; i++ {
+		words <- "ab"
+		words <- "1"
+	}
+	close(words)
+	progress := &VariantProgress{}
+	n := 0
+	for range VariantGenerator(context.Background(), words, nil, v, progress) {
+		n++
+	}
+	if n != 3000 {
+		t.Errorf("generated %d", n)
+	}
+	for _, tt := range []struct{ candidates, words uint64 }{{0, 0}, {1500, 512}, {2563, 1024}, {2564, 1025}, {2565, 1026}, {3000, 1200}} {
+		if got := progress.Words(tt.candidates); got != tt.words {
+			t.Errorf("Words(%d) = %d, want %d", tt.candidates, got, tt.words)
+		}
+	}
+}
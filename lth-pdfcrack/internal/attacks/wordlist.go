@@ -1,37 +1,62 @@
 package attacks
 
 import (
-	"bufio"
 	"context"
-	"os"
+	"sync/atomic"
 )
 
-func WordlistGenerator(ctx context.Context, filename string) (<-chan string, error) {
-	f, err := os.Open(filename)
-	if err != nil {
-		return nil, err
+// WordlistProgress tracks how far a wordlist generator has read into its
+// file. It is safe to read while the generator runs.
+type WordlistProgress struct {
+	size   int64
+	offset atomic.Int64
+	lines  atomic.Uint64
+	// sources holds per-source line counts for SourcePositions.
+	sources atomic.Pointer[[]*sourceProgress]
+}
+
+func (p *WordlistProgress) Size() int64 {
+	return p.size
+}
+
+func (p *WordlistProgress) Offset() int64 {
+	return p.offset.Load()
+}
+
+func (p *WordlistProgress) Lines() uint64 {
+	return p.lines.Load()
+}
+
+func (p *WordlistProgress) Fraction() float64 {
+	if p.size <= 0 {
+		return 0
 	}
-	
-	ch := make(chan string, 1000)
-	
-	go func() {
-		defer close(ch)
-		defer f.Close()
-		
-		scanner := bufio.NewScanner(f)
-		buf := make([]byte, 0, 64*1024)
-		scanner.Buffer(buf, 1024*1024)
-		
-		for scanner.Scan() {
-			select {
-			case <-ctx.Done():
-				return
-			case ch <- scanner.Text():
-			}
-		}
-	}()
-	
-	return ch, nil
+	f := float64(p.Offset()) / float64(p.size)
+	if f > 1 {
+		f = 1
+	}
+	return f
+}
+
+// RemainingLines estimates the lines left from the average line length so
+// far.
+func (p *WordlistProgress) RemainingLines() uint64 {
+	offset, lines := p.Offset(), p.Lines()
+	if offset <= 0 || lines == 0 || offset >= p.size {
+		return 0
+	}
+	perLine := float64(offset) / float64(lines)
+	return uint64(float64(p.size-offset) / perLine)
+}
+
+func WordlistGenerator(ctx context.Context, filename string) (<-chan string, error) {
+	return WordlistGeneratorFrom(ctx, filename, 0, nil)
+}
+
+// WordlistGeneratorFrom skips the first skip lines and records its read
+// position in progress, if non-nil.
+func WordlistGeneratorFrom(ctx context.Context, filename string, skip uint64, progress *WordlistProgress) (<-chan string, error) {
+	return WordlistSourcesGenerator(ctx, []string{filename}, map[string]uint64{filename: skip}, progress, nil, ShardConfig{})
 }
 
 func SliceGenerator(ctx context.Context, passwords []string) <-chan string {
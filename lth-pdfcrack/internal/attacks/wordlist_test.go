@@ -0,0 +1,54 @@
+package attacks
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWordlistGeneratorFromProgress(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "words.txt")
+	content := "alpha\nbeta\ngamma\ndelta\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		skip uint64
+		want []string
+	}{
+		{0, []string{"alpha", "beta", "gamma", "delta"}},
+		{2, []string{"gamma", "delta"}},
+		{10, nil},
+	}
+
+	for _, tt := range tests {
+		progress := &WordlistProgress{}
+		ch, err := WordlistGeneratorFrom(context.Background(), path, tt.skip, progress)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var got []string
+		for w := range ch {
+			got = append(got, w)
+		}
+		if len(got) != len(tt.want) {
+			t.Fatalf("skip %d: got %v, want %v", tt.skip, got, tt.want)
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("skip %d: got %v, want %v", tt.skip, got, tt.want)
+				break
+			}
+		}
+
+		if progress.Size() != int64(len(content)) || progress.Fraction() != 1 {
+			t.Errorf("skip %d: size %d fraction %.2f after reading everything", tt.skip, progress.Size(), progress.Fraction())
+		}
+		if progress.Lines() != 4 {
+			t.Errorf("skip %d: lines = %d, want 4", tt.skip, progress.Lines())
+		}
+	}
+}
@@ -30,6 +30,8 @@ type Cracker struct {
 	attempts    uint64
 	startTime   time.Time
 	progressCb  func(Progress)
+	gate        *Gate
+	rate        rateMeter
 	mu          sync.Mutex
 }
 
@@ -40,6 +42,7 @@ func New(encInfo *pdf.EncryptionInfo, workers int) *Cracker {
 	return &Cracker{
 		encInfo: encInfo,
 		workers: workers,
+		gate:    NewGate(),
 	}
 }
 
@@ -51,14 +54,31 @@ func (c *Cracker) Workers() int {
 	return c.workers
 }
 
+func (c *Cracker) SetGate(g *Gate) {
+	c.gate = g
+}
+
+func (c *Cracker) Pause() {
+	c.gate.Pause()
+}
+
+func (c *Cracker) Resume() {
+	c.gate.Resume()
+}
+
+func (c *Cracker) Paused() bool {
+	return c.gate.Paused()
+}
+
 func (c *Cracker) reportProgress(current string) {
 	if c.progressCb == nil {
 		return
 	}
 	
+	now := time.Now()
 	attempts := atomic.LoadUint64(&c.attempts)
-	elapsed := time.Since(c.startTime)
-	rate := float64(attempts) / elapsed.Seconds()
+	elapsed := now.Sub(c.startTime)
+	rate := c.rate.update(attempts, now)
 	
 	c.progressCb(Progress{
 		Attempts:    attempts,
@@ -71,9 +91,12 @@ func (c *Cracker) reportProgress(current string) {
 func (c *Cracker) CrackWithWordlist(ctx context.Context, passwords <-chan string) Result {
 	c.startTime = time.Now()
 	atomic.StoreUint64(&c.attempts, 0)
+	c.rate.reset(c.startTime)
 	
 	resultChan := make(chan string, 1)
 	doneChan := make(chan struct{})
+	significant := c.encInfo.SignificantLength()
+	prefixes := newPrefixFilter(significant)
 	
 	var wg sync.WaitGroup
 	
@@ -82,6 +105,9 @@ func (c *Cracker) CrackWithWordlist(ctx context.Context, passwords <-chan string
 		go func() {
 			defer wg.Done()
 			for {
+				if !c.gate.Wait(ctx) {
+					return
+				}
 				select {
 				case <-ctx.Done():
 					return
@@ -93,8 +119,11 @@ func (c *Cracker) CrackWithWordlist(ctx context.Context, passwords <-chan string
 					}
 					
 					atomic.AddUint64(&c.attempts, 1)
+					if !prefixes.apply(password) {
+						continue
+					}
 					
-					if c.encInfo.CheckPassword(password) {
+					if c.encInfo.CheckPassword(significantPrefix(password, significant)) {
 						select {
 						case resultChan <- password:
 							close(doneChan)
@@ -133,7 +162,7 @@ func (c *Cracker) CrackWithGenerator(ctx context.Context, generator func(ctx con
 
 func (c *Cracker) TryPassword(password string) bool {
 	atomic.AddUint64(&c.attempts, 1)
-	return c.encInfo.CheckPassword(password)
+	return c.encInfo.CheckPassword(significantPrefix(password, c.encInfo.SignificantLength()))
 }
 
 func (c *Cracker) Attempts() uint64 {
@@ -2,10 +2,12 @@ package cracker
 
 import (
 	"context"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/lth/pdfcrack/internal/pdf"
+	"github.com/lth/pdfcrack/internal/pdf/pdftest"
 )
 
 func TestCrackerCreation(t *testing.T) {
@@ -38,10 +40,7 @@ func TestCrackerProgress(t *testing.T) {
 
 	c := New(info, 2)
 
-	progressCalled := false
-	c.SetProgressCallback(func(p Progress) {
-		progressCalled = true
-	})
+	c.SetProgressCallback(func(p Progress) {})
 
 	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
 	defer cancel()
@@ -59,6 +58,69 @@ func TestCrackerProgress(t *testing.T) {
 	}
 }
 
+func TestCrackerSignificantLength(t *testing.T) {
+	prefix := strings.Repeat("k", 32)
+	c := New(pdftest.Encrypt(prefix, 3, "doc"), 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	passwords := make(chan string, 2)
+	passwords <- "short"
+	passwords <- prefix + "tail"
+	close(passwords)
+
+	result := c.CrackWithWordlist(ctx, passwords)
+	if !result.Found || result.Password != prefix+"tail" {
+		t.Errorf("result = %+v, want the whole candidate", result)
+	}
+	if !c.TryPassword(prefix + "other") {
+		t.Error("TryPassword tested more than the first 32 bytes")
+	}
+}
+
+func TestCrackerPause(t *testing.T) {
+	info := &pdf.EncryptionInfo{
+		Version:   2,
+		Revision:  3,
+		Length:    128,
+		OwnerHash: make([]byte, 32),
+		UserHash:  make([]byte, 32),
+		FileID:    make([]byte, 16),
+	}
+
+	c := New(info, 2)
+	c.Pause()
+	if !c.Paused() {
+		t.Fatal("Paused() = false after Pause()")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	passwords := make(chan string, 100)
+	for i := 0; i < 100; i++ {
+		passwords <- "test"
+	}
+	close(passwords)
+
+	done := make(chan Result, 1)
+	go func() {
+		done <- c.CrackWithWordlist(ctx, passwords)
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	if c.Attempts() != 0 {
+		t.Fatalf("Attempts() = %d while paused, want 0", c.Attempts())
+	}
+
+	c.Resume()
+	result := <-done
+	if result.Attempts != 100 {
+		t.Errorf("Attempts = %d after resume, want 100", result.Attempts)
+	}
+}
+
 func BenchmarkCracker(b *testing.B) {
 	info := &pdf.EncryptionInfo{
 		Version:     2,
@@ -77,3 +139,22 @@ func BenchmarkCracker(b *testing.B) {
 		c.TryPassword("testpassword123")
 	}
 }
+
+func TestRateMeter(t *testing.T) {
+	var m rateMeter
+	start := time.Unix(0, 0)
+	m.reset(start)
+
+	if got := m.update(1000, start.Add(time.Second)); got != 1000 {
+		t.Fatalf("first sample rate = %.0f, want 1000", got)
+	}
+	if got := m.update(1001, start.Add(1050*time.Millisecond)); got != 1000 {
+		t.Errorf("sample inside minimum interval changed rate to %.0f", got)
+	}
+
+	// One half-life at double the throughput moves the rate halfway.
+	got := m.update(1000+2000*5, start.Add(6*time.Second))
+	if got < 1499 || got > 1501 {
+		t.Errorf("rate after one half-life at 2000/s = %.0f, want 1500", got)
+	}
+}
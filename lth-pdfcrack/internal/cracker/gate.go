@@ -0,0 +1,67 @@
+package cracker
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// Gate lets workers be paused between candidates. One gate can be shared by
+// several crackers so a single key press pauses every attack mode.
+type Gate struct {
+	mu     sync.Mutex
+	paused int32
+	resume chan struct{}
+}
+
+func NewGate() *Gate {
+	return &Gate{}
+}
+
+func (g *Gate) Pause() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if atomic.LoadInt32(&g.paused) == 1 {
+		return
+	}
+	g.resume = make(chan struct{})
+	atomic.StoreInt32(&g.paused, 1)
+}
+
+func (g *Gate) Resume() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if atomic.LoadInt32(&g.paused) == 0 {
+		return
+	}
+	atomic.StoreInt32(&g.paused, 0)
+	close(g.resume)
+}
+
+func (g *Gate) Paused() bool {
+	return atomic.LoadInt32(&g.paused) == 1
+}
+
+// Wait blocks while the gate is paused. It returns false if ctx ends first.
+func (g *Gate) Wait(ctx context.Context) bool {
+	if atomic.LoadInt32(&g.paused) == 0 {
+		return true
+	}
+
+	g.mu.Lock()
+	resume := g.resume
+	paused := atomic.LoadInt32(&g.paused) == 1
+	g.mu.Unlock()
+	if !paused {
+		return true
+	}
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-resume:
+		return true
+	}
+}
@@ -0,0 +1,208 @@
+package cracker
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lth/pdfcrack/internal/pdf"
+)
+
+type Target struct {
+	Name string
+	Info *pdf.EncryptionInfo
+}
+
+// SignificantLength is the longest password prefix any of targets uses. A
+// candidate longer than that tests the same as its prefix for all of them.
+func SignificantLength(targets []Target) int {
+	n := 0
+	for _, t := range targets {
+		n = max(n, t.Info.SignificantLength())
+	}
+	return n
+}
+
+type Cracked struct {
+	Target   Target
+	Password string
+	Duration time.Duration
+}
+
+type setTarget struct {
+	info    *pdf.EncryptionInfo
+	aliases []Target
+	cracked bool
+}
+
+// targetGroup holds targets whose key derivation shares the same /O and P
+// prefix, so each candidate is prepared once per group.
+type targetGroup struct {
+	targets []*setTarget
+}
+
+// TargetSet is the shared pool of uncracked targets. Every attack mode tests
+// its candidates against the same set; targets drop out for all of them as
+// soon as one cracks it.
+type TargetSet struct {
+	mu        sync.Mutex
+	groups    atomic.Value
+	total     int
+	unique    int
+	remaining int
+	cracked   []Cracked
+	startTime time.Time
+	done      chan struct{}
+	onCracked func(Cracked)
+	// significant is the longest password prefix any target looks at.
+	significant int
+}
+
+func NewTargetSet(targets []Target) *TargetSet {
+	s := &TargetSet{
+		startTime: time.Now(),
+		done:      make(chan struct{}),
+	}
+
+	byHash := make(map[string]*setTarget)
+	var unique []*setTarget
+	for _, t := range targets {
+		key := t.Info.Hash()
+		if st, ok := byHash[key]; ok {
+			st.aliases = append(st.aliases, t)
+			continue
+		}
+		st := &setTarget{info: t.Info, aliases: []Target{t}}
+		byHash[key] = st
+		unique = append(unique, st)
+	}
+
+	s.total = len(targets)
+	s.unique = len(unique)
+	s.remaining = len(unique)
+	s.significant = SignificantLength(targets)
+	s.groups.Store(buildGroups(unique))
+	if s.remaining == 0 {
+		close(s.done)
+	}
+
+	return s
+}
+
+func buildGroups(targets []*setTarget) []targetGroup {
+	var groups []targetGroup
+	index := make(map[string]int)
+	for _, t := range targets {
+		if t.cracked {
+			continue
+		}
+		key := t.info.SharedKey()
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, targetGroup{})
+		}
+		groups[i].targets = append(groups[i].targets, t)
+	}
+	return groups
+}
+
+func (s *TargetSet) SetCrackedCallback(cb func(Cracked)) {
+	s.onCracked = cb
+}
+
+// SignificantLength is the number of password bytes that matter for some
+// target in the set.
+func (s *TargetSet) SignificantLength() int {
+	return s.significant
+}
+
+func (s *TargetSet) Total() int {
+	return s.total
+}
+
+// Unique is the number of distinct documents; copies of one count once, as
+// Remaining does.
+func (s *TargetSet) Unique() int {
+	return s.unique
+}
+
+func (s *TargetSet) Remaining() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.remaining
+}
+
+func (s *TargetSet) Cracked() []Cracked {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Cracked(nil), s.cracked...)
+}
+
+func (s *TargetSet) Done() <-chan struct{} {
+	return s.done
+}
+
+// Check tests one candidate against every uncracked target and reports
+// whether any target fell to it.
+func (s *TargetSet) Check(password string) bool {
+	groups := s.groups.Load().([]targetGroup)
+	tested := significantPrefix(password, s.significant)
+
+	hit := false
+	for _, g := range groups {
+		if len(g.targets) == 1 {
+			if g.targets[0].info.CheckPassword(tested) {
+				s.markCracked(g.targets[0], password)
+				hit = true
+			}
+			continue
+		}
+
+		prepared := g.targets[0].info.PreparePassword(tested)
+		for _, t := range g.targets {
+			if t.info.CheckPrepared(prepared) {
+				s.markCracked(t, password)
+				hit = true
+			}
+		}
+	}
+	return hit
+}
+
+func (s *TargetSet) markCracked(t *setTarget, password string) {
+	s.mu.Lock()
+	if t.cracked {
+		s.mu.Unlock()
+		return
+	}
+	t.cracked = true
+	s.remaining--
+
+	var all []*setTarget
+	for _, g := range s.groups.Load().([]targetGroup) {
+		all = append(all, g.targets...)
+	}
+	s.groups.Store(buildGroups(all))
+
+	var results []Cracked
+	for _, alias := range t.aliases {
+		results = append(results, Cracked{
+			Target:   alias,
+			Password: password,
+			Duration: time.Since(s.startTime),
+		})
+	}
+	s.cracked = append(s.cracked, results...)
+	if s.remaining == 0 {
+		close(s.done)
+	}
+	s.mu.Unlock()
+
+	if s.onCracked != nil {
+		for _, r := range results {
+			s.onCracked(r)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package cracker
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lth/pdfcrack/internal/pdf/pdftest"
+)
+
+func TestTargetSet(t *testing.T) {
+	targets := []Target{
+		{Name: "a.pdf", Info: pdftest.Encrypt("apple", 3, "a")},
+		{Name: "b.pdf", Info: pdftest.Encrypt("banana", 3, "b")},
+		{Name: "c.pdf", Info: pdftest.Encrypt("cherry", 2, "c")},
+		{Name: "a-copy.pdf", Info: pdftest.Encrypt("apple", 3, "a")},
+		{Name: "never.pdf", Info: pdftest.Encrypt("not-in-list", 3, "n")},
+		{Name: "long.pdf", Info: pdftest.Encrypt(strings.Repeat("k", 32), 3, "l")},
+	}
+
+	set := NewTargetSet(targets)
+	if set.Total() != 6 || set.Unique() != 5 || set.Remaining() != 5 {
+		t.Fatalf("Total() = %d, Unique() = %d, Remaining() = %d, want 6, 5 and 5", set.Total(), set.Unique(), set.Remaining())
+	}
+
+	var mu sync.Mutex
+	var names []string
+	set.SetCrackedCallback(func(c Cracked) {
+		mu.Lock()
+		names = append(names, c.Target.Name+"="+c.Password)
+		mu.Unlock()
+	})
+
+	// Only the first 32 bytes of a password matter, but the cracked entry
+	// keeps the candidate as given.
+	for _, p := range []string{"x", "banana", "apple", "y", "cherry", "apple", strings.Repeat("k", 32) + "tail"} {
+		set.Check(p)
+	}
+	if set.Remaining() != 1 {
+		t.Errorf("Remaining() = %d, want 1", set.Remaining())
+	}
+
+	sort.Strings(names)
+	want := []string{"a-copy.pdf=apple", "a.pdf=apple", "b.pdf=banana", "c.pdf=cherry", "long.pdf=" + strings.Repeat("k", 32) + "tail"}
+	if len(names) != len(want) {
+		t.Fatalf("cracked = %v, want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Errorf("cracked[%d] = %s, want %s", i, names[i], want[i])
+		}
+	}
+}
+
+func TestCrackSetStopsWhenAllCracked(t *testing.T) {
+	set := NewTargetSet([]Target{
+		{Name: "a.pdf", Info: pdftest.Encrypt("1", 3, "a")},
+		{Name: "b.pdf", Info: pdftest.Encrypt("2", 3, "b")},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	s := NewScheduler(2)
+	s.AddSource(Source{Name: "endless", Generate: func(ctx context.Context) <-chan string {
+		ch := make(chan string)
+		go func() {
+			defer close(ch)
+			for i := 0; ; i++ {
+				select {
+				case <-ctx.Done():
+					return
+				case ch <- strconv.Itoa(i):
+				}
+			}
+		}()
+		return ch
+	}})
+
+	results := s.CrackSet(ctx, set)
+	if !results[0].Found {
+		t.Error("Found = false after every target was cracked")
+	}
+	if ctx.Err() != nil {
+		t.Error("scheduler kept running after the set was exhausted")
+	}
+}
@@ -0,0 +1,54 @@
+package cracker
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// rateHalfLife controls how quickly the smoothed rate follows changes in
+// throughput; older samples lose half their weight every rateHalfLife.
+const rateHalfLife = 5 * time.Second
+
+// rateMeter keeps an exponentially weighted moving average of attempts per
+// second so that warm-up and pauses do not skew the rate for the rest of a
+// run the way a lifetime average does.
+type rateMeter struct {
+	mu        sync.Mutex
+	last      time.Time
+	lastCount uint64
+	rate      float64
+	primed    bool
+}
+
+func (m *rateMeter) reset(now time.Time) {
+	m.mu.Lock()
+	m.last = now
+	m.lastCount = 0
+	m.rate = 0
+	m.primed = false
+	m.mu.Unlock()
+}
+
+func (m *rateMeter) update(count uint64, now time.Time) float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	dt := now.Sub(m.last)
+	if dt < 100*time.Millisecond || count < m.lastCount {
+		return m.rate
+	}
+
+	instant := float64(count-m.lastCount) / dt.Seconds()
+	if !m.primed {
+		m.rate = instant
+		m.primed = true
+	} else {
+		alpha := 1 - math.Exp2(-dt.Seconds()/rateHalfLife.Seconds())
+		m.rate += alpha * (instant - m.rate)
+	}
+	m.last = now
+	m.lastCount = count
+
+	return m.rate
+}
@@ -0,0 +1,288 @@
+package cracker
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lth/pdfcrack/internal/pdf"
+)
+
+const defaultBatchSize = 256
+
+// Source is one attack mode feeding a Scheduler. Weight is its share of the
+// worker pool relative to the other sources still producing candidates.
+type Source struct {
+	Name     string
+	Weight   int
+	Generate func(ctx context.Context) <-chan string
+}
+
+type scheduledSource struct {
+	Source
+	passwords <-chan string
+	attempts  uint64
+	credit    int
+	exhausted bool
+	finished  time.Time
+	found     string
+	rate      rateMeter
+	prefixes  *prefixFilter
+}
+
+// Scheduler runs a single pool of workers over several candidate sources.
+// Workers take batches from the sources in proportion to their weights using
+// smooth weighted round-robin; once a source runs dry its share is spread over
+// the ones that remain.
+type Scheduler struct {
+	workers    int
+	batchSize  int
+	gate       *Gate
+	progressCb func(source string, p Progress)
+
+	mu          sync.Mutex
+	sources     []*scheduledSource
+	startTime   time.Time
+	significant int
+}
+
+func NewScheduler(workers int) *Scheduler {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	return &Scheduler{
+		workers:   workers,
+		batchSize: defaultBatchSize,
+		gate:      NewGate(),
+	}
+}
+
+func (s *Scheduler) AddSource(src Source) {
+	if src.Weight <= 0 {
+		src.Weight = 1
+	}
+	s.sources = append(s.sources, &scheduledSource{Source: src})
+}
+
+func (s *Scheduler) SetGate(g *Gate) {
+	s.gate = g
+}
+
+func (s *Scheduler) SetProgressCallback(cb func(source string, p Progress)) {
+	s.progressCb = cb
+}
+
+func (s *Scheduler) Workers() int {
+	return s.workers
+}
+
+// Crack stops at the first candidate that opens encInfo. The result for the
+// source that produced it has Found set.
+func (s *Scheduler) Crack(ctx context.Context, encInfo *pdf.EncryptionInfo) []Result {
+	s.significant = encInfo.SignificantLength()
+	check := func(password string) bool {
+		return encInfo.CheckPassword(significantPrefix(password, s.significant))
+	}
+	return s.run(ctx, check, true, nil)
+}
+
+// CrackSet runs until every target in set is cracked or the sources run
+// out. Found reports that the whole set fell.
+func (s *Scheduler) CrackSet(ctx context.Context, set *TargetSet) []Result {
+	s.significant = set.SignificantLength()
+	results := s.run(ctx, set.Check, false, set.Done())
+	if set.Remaining() == 0 {
+		for i := range results {
+			results[i].Found = true
+		}
+	}
+	return results
+}
+
+func (s *Scheduler) run(ctx context.Context, check func(string) bool, stopOnHit bool, done <-chan struct{}) []Result {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	s.startTime = time.Now()
+	for _, src := range s.sources {
+		src.passwords = src.Generate(ctx)
+		src.rate.reset(s.startTime)
+		src.prefixes = newPrefixFilter(s.significant)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < s.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			batch := make([]string, 0, s.batchSize)
+			for {
+				if !s.gate.Wait(ctx) {
+					return
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case <-done:
+					return
+				default:
+				}
+
+				src := s.next()
+				if src == nil {
+					return
+				}
+				batch = s.fill(ctx, src, batch[:0])
+
+				for _, password := range batch {
+					attempts := atomic.AddUint64(&src.attempts, 1)
+					if check(password) && stopOnHit {
+						s.mu.Lock()
+						if src.found == "" {
+							src.found = password
+						}
+						s.mu.Unlock()
+						cancel()
+						return
+					}
+					if attempts%1000 == 0 {
+						s.reportProgress(src, attempts, password)
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	end := time.Now()
+	results := make([]Result, len(s.sources))
+	for i, src := range s.sources {
+		finished := end
+		if src.exhausted {
+			finished = src.finished
+		}
+		results[i] = Result{
+			Found:    src.found != "",
+			Password: src.found,
+			Attempts: atomic.LoadUint64(&src.attempts),
+			Duration: finished.Sub(s.startTime),
+		}
+	}
+	return results
+}
+
+// next picks the source for the next batch, or nil once all are exhausted.
+func (s *Scheduler) next() *scheduledSource {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var best *scheduledSource
+	total := 0
+	for _, src := range s.sources {
+		if src.exhausted {
+			continue
+		}
+		src.credit += src.Weight
+		total += src.Weight
+		if best == nil || src.credit > best.credit {
+			best = src
+		}
+	}
+	if best != nil {
+		best.credit -= total
+	}
+	return best
+}
+
+func (s *Scheduler) fill(ctx context.Context, src *scheduledSource, batch []string) []string {
+	for len(batch) < s.batchSize {
+		select {
+		case <-ctx.Done():
+			return batch
+		case password, ok := <-src.passwords:
+			if !ok {
+				s.mu.Lock()
+				if !src.exhausted {
+					src.exhausted = true
+					src.finished = time.Now()
+				}
+				s.mu.Unlock()
+				return batch
+			}
+			if !src.prefixes.apply(password) {
+				// Skipped candidates still count, so that attempts stay a
+				// position in the source's output.
+				atomic.AddUint64(&src.attempts, 1)
+				continue
+			}
+			batch = append(batch, password)
+		}
+	}
+	return batch
+}
+
+func (s *Scheduler) reportProgress(src *scheduledSource, attempts uint64, current string) {
+	if s.progressCb == nil {
+		return
+	}
+
+	now := time.Now()
+	s.progressCb(src.Name, Progress{
+		Attempts:    attempts,
+		Rate:        src.rate.update(attempts, now),
+		Current:     current,
+		ElapsedTime: now.Sub(s.startTime),
+	})
+}
+
+// prefixCacheSize bounds the prefixes remembered per source. Generators emit
+// candidates that share a long prefix close together, so a small set catches
+// most repeats.
+const prefixCacheSize = 1 << 16
+
+// significantPrefix is the part of password a target with n significant
+// bytes tests. Candidates are still reported whole, as the generator wrote
+// them.
+func significantPrefix(password string, n int) string {
+	if n > 0 && len(password) > n {
+		return password[:n]
+	}
+	return password
+}
+
+// prefixFilter drops candidates whose significant prefix was tried
+// recently, as they cannot open anything new.
+type prefixFilter struct {
+	length int
+	mu     sync.Mutex
+	seen   map[string]struct{}
+}
+
+func newPrefixFilter(length int) *prefixFilter {
+	if length <= 0 {
+		return nil
+	}
+	return &prefixFilter{length: length, seen: make(map[string]struct{})}
+}
+
+// apply reports whether password is worth testing.
+func (f *prefixFilter) apply(password string) bool {
+	if f == nil {
+		return true
+	}
+	// Candidates no longer than the prefix are recorded too, so that one
+	// of exactly that length and a longer one sharing it are not both tried.
+	prefix := significantPrefix(password, f.length)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.seen[prefix]; ok {
+		return false
+	}
+	if len(f.seen) >= prefixCacheSize {
+		clear(f.seen)
+	}
+	f.seen[prefix] = struct{}{}
+	return true
+}
@@ -0,0 +1,172 @@
+package cracker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lth/pdfcrack/internal/pdf/pdftest"
+)
+
+func listSource(name string, weight int, passwords []string) Source {
+	return Source{
+		Name:   name,
+		Weight: weight,
+		Generate: func(ctx context.Context) <-chan string {
+			ch := make(chan string)
+			go func() {
+				defer close(ch)
+				for _, p := range passwords {
+					select {
+					case <-ctx.Done():
+						return
+					case ch <- p:
+					}
+				}
+			}()
+			return ch
+		},
+	}
+}
+
+func TestSchedulerWeights(t *testing.T) {
+	tests := []struct {
+		weights   []int
+		exhausted []bool
+		picks     int
+		want      []int
+	}{
+		{[]int{70, 20, 10}, []bool{false, false, false}, 100, []int{70, 20, 10}},
+		{[]int{70, 20, 10}, []bool{true, false, false}, 30, []int{0, 20, 10}},
+		{[]int{1, 1}, []bool{false, false}, 10, []int{5, 5}},
+		{[]int{0, 3}, []bool{false, false}, 8, []int{2, 6}},
+		{[]int{5, 5}, []bool{true, true}, 3, []int{0, 0}},
+	}
+
+	for _, tt := range tests {
+		s := NewScheduler(1)
+		for i, w := range tt.weights {
+			s.AddSource(Source{Name: fmt.Sprint(i), Weight: w})
+			s.sources[i].exhausted = tt.exhausted[i]
+		}
+
+		got := make([]int, len(tt.weights))
+		for i := 0; i < tt.picks; i++ {
+			src := s.next()
+			if src == nil {
+				continue
+			}
+			for j := range s.sources {
+				if s.sources[j] == src {
+					got[j]++
+				}
+			}
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("weights %v exhausted %v: picks = %v, want %v", tt.weights, tt.exhausted, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestSchedulerCrack(t *testing.T) {
+	info := pdftest.Encrypt("needle", 3, "doc")
+
+	var many []string
+	for i := 0; i < 5000; i++ {
+		many = append(many, fmt.Sprintf("hay%d", i))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	s := NewScheduler(4)
+	s.AddSource(listSource("Wordlist", 70, []string{"a", "b", "c"}))
+	s.AddSource(listSource("Incremental", 20, append(many, "needle")))
+	s.AddSource(listSource("Random", 10, many))
+
+	results := s.Crack(ctx, info)
+	if len(results) != 3 {
+		t.Fatalf("got %d results, want 3", len(results))
+	}
+	if results[0].Attempts != 3 || results[0].Found {
+		t.Errorf("Wordlist result = %+v, want 3 attempts and not found", results[0])
+	}
+	if !results[1].Found || results[1].Password != "needle" {
+		t.Errorf("Incremental result = %+v, want needle", results[1])
+	}
+	if results[2].Found {
+		t.Errorf("Random result = %+v, want not found", results[2])
+	}
+	if results[0].Duration > results[1].Duration {
+		t.Errorf("exhausted Wordlist ran for %v, longer than the whole run %v", results[0].Duration, results[1].Duration)
+	}
+}
+
+func TestSchedulerCrackSet(t *testing.T) {
+	set := NewTargetSet([]Target{
+		{Name: "a.pdf", Info: pdftest.Encrypt("apple", 3, "a")},
+		{Name: "b.pdf", Info: pdftest.Encrypt("banana", 3, "b")},
+		{Name: "c.pdf", Info: pdftest.Encrypt("cherry", 3, "c")},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	s := NewScheduler(2)
+	s.AddSource(listSource("first", 1, []string{"x", "apple", "y"}))
+	s.AddSource(listSource("second", 1, []string{"banana", "z"}))
+
+	results := s.CrackSet(ctx, set)
+	if results[0].Attempts != 3 || results[1].Attempts != 2 {
+		t.Errorf("attempts = %d, %d, want 3, 2", results[0].Attempts, results[1].Attempts)
+	}
+	if results[0].Found || set.Remaining() != 1 {
+		t.Errorf("Found = %v, Remaining() = %d, want false and 1", results[0].Found, set.Remaining())
+	}
+}
+
+func TestSchedulerSignificantLength(t *testing.T) {
+	prefix := strings.Repeat("k", 32)
+	info := pdftest.Encrypt(prefix, 3, "doc")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	s := NewScheduler(1)
+	s.AddSource(listSource("Wordlist", 1, []string{"short", prefix + "tail"}))
+	results := s.Crack(ctx, info)
+	if !results[0].Found || results[0].Password != prefix+"tail" {
+		t.Errorf("result = %+v, want the whole candidate", results[0])
+	}
+}
+
+func TestPrefixFilter(t *testing.T) {
+	long := strings.Repeat("a", 32)
+	tests := []struct {
+		in   string
+		want bool
+	}{
+		{"short", true},
+		{long + "1", true},
+		{long + "2", false},
+		{long, false},
+		{"b" + long, true},
+		{"b" + long[:31], false},
+		{"short", false},
+	}
+
+	f := newPrefixFilter(32)
+	for _, tt := range tests {
+		if got := f.apply(tt.in); got != tt.want {
+			t.Errorf("apply(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+	if !newPrefixFilter(0).apply(long+"x") || !newPrefixFilter(0).apply(long+"x") {
+		t.Error("disabled filter dropped a candidate")
+	}
+}
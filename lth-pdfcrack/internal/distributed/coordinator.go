@@ -0,0 +1,413 @@
+package distributed
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultChunkSize        = 1000000
+	DefaultHeartbeatTimeout = 30 * time.Second
+)
+
+var (
+	ErrUnknownJob   = errors.New("unknown job")
+	ErrUnknownChunk = errors.New("unknown chunk")
+)
+
+type assignment struct {
+	chunk    Chunk
+	worker   string
+	lastSeen time.Time
+	attempts uint64
+}
+
+type job struct {
+	req       JobRequest
+	status    JobStatus
+	next      uint64
+	nextChunk uint64
+	requeued  []Chunk
+	assigned  map[uint64]*assignment
+}
+
+type workerStats struct {
+	status   WorkerStatus
+	lastRate time.Time
+}
+
+type Coordinator struct {
+	mu               sync.Mutex
+	jobs             map[string]*job
+	order            []string
+	workers          map[string]*workerStats
+	heartbeatTimeout time.Duration
+	now              func() time.Time
+	onFound          func(JobStatus)
+	jobCounter       int
+}
+
+func NewCoordinator(heartbeatTimeout time.Duration) *Coordinator {
+	if heartbeatTimeout <= 0 {
+		heartbeatTimeout = DefaultHeartbeatTimeout
+	}
+	return &Coordinator{
+		jobs:             make(map[string]*job),
+		workers:          make(map[string]*workerStats),
+		heartbeatTimeout: heartbeatTimeout,
+		now:              time.Now,
+	}
+}
+
+func (c *Coordinator) SetFoundCallback(cb func(JobStatus)) {
+	c.onFound = cb
+}
+
+func (c *Coordinator) AddJob(req JobRequest) (JobStatus, error) {
+	if req.Target == nil {
+		return JobStatus{}, errors.New("job has no target")
+	}
+
+	keyspace, err := req.Attack.Keyspace()
+	if err != nil {
+		return JobStatus{}, err
+	}
+	if req.ChunkSize == 0 {
+		req.ChunkSize = DefaultChunkSize
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.jobCounter++
+	id := fmt.Sprintf("job-%d", c.jobCounter)
+	j := &job{
+		req: req,
+		status: JobStatus{
+			ID:       id,
+			Attack:   req.Attack,
+			Keyspace: keyspace,
+			Done:     keyspace == 0,
+		},
+		assigned: make(map[uint64]*assignment),
+	}
+	c.jobs[id] = j
+	c.order = append(c.order, id)
+
+	return j.status, nil
+}
+
+func (c *Coordinator) NextChunk(req WorkRequest) (Chunk, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	w := c.touchWorker(req.Worker, now)
+	if req.Threads > 0 {
+		w.status.Threads = req.Threads
+	}
+
+	for _, id := range c.order {
+		j := c.jobs[id]
+		if j.status.Done {
+			continue
+		}
+
+		var chunk Chunk
+		if len(j.requeued) > 0 {
+			chunk = j.requeued[0]
+			j.requeued = j.requeued[1:]
+		} else if j.next < j.status.Keyspace {
+			end := j.next + j.req.ChunkSize
+			if end > j.status.Keyspace || end < j.next {
+				end = j.status.Keyspace
+			}
+			chunk = Chunk{
+				JobID:  id,
+				ID:     j.nextChunk,
+				Start:  j.next,
+				End:    end,
+				Target: j.req.Target,
+				Attack: j.req.Attack,
+			}
+			j.next = end
+			j.nextChunk++
+		} else {
+			continue
+		}
+
+		j.assigned[chunk.ID] = &assignment{
+			chunk:    chunk,
+			worker:   req.Worker,
+			lastSeen: now,
+		}
+		return chunk, true
+	}
+
+	return Chunk{}, false
+}
+
+func (c *Coordinator) Heartbeat(hb Heartbeat) (HeartbeatReply, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	w := c.touchWorker(hb.Worker, now)
+
+	j, ok := c.jobs[hb.JobID]
+	if !ok {
+		return HeartbeatReply{Cancel: true}, ErrUnknownJob
+	}
+	if j.status.Done {
+		return HeartbeatReply{Cancel: true}, nil
+	}
+
+	a, ok := j.assigned[hb.ChunkID]
+	if !ok || a.worker != hb.Worker {
+		// The chunk was handed to someone else after this worker went quiet.
+		return HeartbeatReply{Cancel: true}, nil
+	}
+
+	if hb.Attempts > a.attempts {
+		c.recordAttempts(w, hb.Attempts-a.attempts, now)
+		a.attempts = hb.Attempts
+	}
+	a.lastSeen = now
+
+	return HeartbeatReply{}, nil
+}
+
+func (c *Coordinator) Complete(res ChunkResult) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	w := c.touchWorker(res.Worker, now)
+
+	j, ok := c.jobs[res.JobID]
+	if !ok {
+		return ErrUnknownJob
+	}
+
+	a, ok := j.assigned[res.ChunkID]
+	if !ok || a.worker != res.Worker {
+		if res.Found && !j.status.Found {
+			c.markFound(j, res.Password)
+			return nil
+		}
+		// A late result for a reaped chunk still counts if nobody has
+		// picked the chunk up again yet.
+		for i, chunk := range j.requeued {
+			if chunk.ID == res.ChunkID {
+				j.requeued = append(j.requeued[:i], j.requeued[i+1:]...)
+				a = &assignment{chunk: chunk}
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return ErrUnknownChunk
+		}
+	}
+
+	if res.Attempts > a.attempts {
+		c.recordAttempts(w, res.Attempts-a.attempts, now)
+	}
+	delete(j.assigned, res.ChunkID)
+	w.status.Chunks++
+	j.status.Attempts += res.Attempts
+
+	if res.Found {
+		c.markFound(j, res.Password)
+		return nil
+	}
+
+	j.status.Completed += a.chunk.End - a.chunk.Start
+	if j.status.Completed >= j.status.Keyspace {
+		j.status.Done = true
+	}
+	return nil
+}
+
+func (c *Coordinator) Reap() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	reaped := 0
+	for _, id := range c.order {
+		j := c.jobs[id]
+		for chunkID, a := range j.assigned {
+			if now.Sub(a.lastSeen) <= c.heartbeatTimeout {
+				continue
+			}
+			delete(j.assigned, chunkID)
+			if !j.status.Done {
+				j.requeued = append(j.requeued, a.chunk)
+			}
+			reaped++
+		}
+	}
+
+	for _, w := range c.workers {
+		w.status.Alive = now.Sub(w.status.LastSeen) <= c.heartbeatTimeout
+	}
+
+	return reaped
+}
+
+func (c *Coordinator) Job(id string) (JobStatus, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	j, ok := c.jobs[id]
+	if !ok {
+		return JobStatus{}, false
+	}
+	return j.status, true
+}
+
+func (c *Coordinator) Status() Status {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var st Status
+	for _, id := range c.order {
+		st.Jobs = append(st.Jobs, c.jobs[id].status)
+	}
+	for _, w := range c.workers {
+		st.Workers = append(st.Workers, w.status)
+	}
+	sort.Slice(st.Workers, func(i, j int) bool {
+		return st.Workers[i].ID < st.Workers[j].ID
+	})
+	return st
+}
+
+func (c *Coordinator) touchWorker(id string, now time.Time) *workerStats {
+	w, ok := c.workers[id]
+	if !ok {
+		w = &workerStats{status: WorkerStatus{ID: id}, lastRate: now}
+		c.workers[id] = w
+	}
+	w.status.LastSeen = now
+	w.status.Alive = true
+	return w
+}
+
+func (c *Coordinator) recordAttempts(w *workerStats, delta uint64, now time.Time) {
+	w.status.Attempts += delta
+
+	elapsed := now.Sub(w.lastRate).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+	rate := float64(delta) / elapsed
+	if w.status.Rate == 0 {
+		w.status.Rate = rate
+	} else {
+		w.status.Rate = 0.7*w.status.Rate + 0.3*rate
+	}
+	w.lastRate = now
+}
+
+func (c *Coordinator) markFound(j *job, password string) {
+	j.status.Found = true
+	j.status.Done = true
+	j.status.Password = password
+	j.requeued = nil
+	if c.onFound != nil {
+		c.onFound(j.status)
+	}
+}
+
+func (c *Coordinator) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/api/jobs", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, c.Status().Jobs)
+		case http.MethodPost:
+			var req JobRequest
+			if !readJSON(w, r, &req) {
+				return
+			}
+			st, err := c.AddJob(req)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			writeJSON(w, http.StatusCreated, st)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/api/status", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, c.Status())
+	})
+
+	mux.HandleFunc("/api/work", func(w http.ResponseWriter, r *http.Request) {
+		var req WorkRequest
+		if !requirePost(w, r) || !readJSON(w, r, &req) {
+			return
+		}
+		chunk, ok := c.NextChunk(req)
+		if !ok {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		writeJSON(w, http.StatusOK, chunk)
+	})
+
+	mux.HandleFunc("/api/heartbeat", func(w http.ResponseWriter, r *http.Request) {
+		var hb Heartbeat
+		if !requirePost(w, r) || !readJSON(w, r, &hb) {
+			return
+		}
+		reply, _ := c.Heartbeat(hb)
+		writeJSON(w, http.StatusOK, reply)
+	})
+
+	mux.HandleFunc("/api/result", func(w http.ResponseWriter, r *http.Request) {
+		var res ChunkResult
+		if !requirePost(w, r) || !readJSON(w, r, &res) {
+			return
+		}
+		if err := c.Complete(res); err != nil && !errors.Is(err, ErrUnknownChunk) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	return mux
+}
+
+func requirePost(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return false
+	}
+	return true
+}
+
+func readJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
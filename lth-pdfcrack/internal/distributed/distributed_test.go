@@ -0,0 +1,151 @@
+package distributed
+
+import (
+	"context"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lth/pdfcrack/internal/pdf/pdftest"
+)
+
+func TestLoopbackCrack(t *testing.T) {
+	for _, spec := range []AttackSpec{
+		{Mode: ModeIncremental, Charset: "0123456789", MinLength: 1, MaxLength: 3},
+		{Mode: ModeRandom, Charset: "0123456789", MinLength: 1, MaxLength: 3, Seed: 99},
+		{Mode: ModeTemplate, Template: "{?d,1-3}"},
+	} {
+		loopbackCrack(t, spec)
+	}
+}
+
+func loopbackCrack(t *testing.T, spec AttackSpec) {
+	t.Helper()
+	coord := NewCoordinator(time.Second)
+	srv := httptest.NewServer(coord.Handler())
+	defer srv.Close()
+
+	job, err := coord.AddJob(JobRequest{
+		Target:    pdftest.Encrypt("417", 3, "loopback"),
+		Attack:    spec,
+		ChunkSize: 100,
+	})
+	if err != nil {
+		t.Fatalf("%s: AddJob: %v", spec.Mode, err)
+	}
+	if job.Keyspace != 1110 {
+		t.Fatalf("%s: Keyspace = %d, want 1110", spec.Mode, job.Keyspace)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	for i := 0; i < 2; i++ {
+		w := NewWorker(srv.URL, 2)
+		w.ID = []string{"alpha", "beta"}[i]
+		w.HeartbeatInterval = 20 * time.Millisecond
+		w.PollInterval = 10 * time.Millisecond
+		go w.Run(ctx)
+	}
+
+	for {
+		st, _ := coord.Job(job.ID)
+		if st.Done {
+			if !st.Found || st.Password != "417" {
+				t.Fatalf("%s: job finished with %+v, want password 417", spec.Mode, st)
+			}
+			break
+		}
+		select {
+		case <-ctx.Done():
+			t.Fatalf("%s: timed out waiting for the job", spec.Mode)
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	if len(coord.Status().Workers) == 0 {
+		t.Errorf("%s: no worker statistics recorded", spec.Mode)
+	}
+}
+
+func TestReassignDeadWorker(t *testing.T) {
+	now := time.Unix(1000, 0)
+	coord := NewCoordinator(10 * time.Second)
+	coord.now = func() time.Time { return now }
+
+	job, err := coord.AddJob(JobRequest{
+		Target:    pdftest.Encrypt("zz", 3, "reassign"),
+		Attack:    AttackSpec{Mode: ModeIncremental, Charset: "ab", MinLength: 1, MaxLength: 2},
+		ChunkSize: 4,
+	})
+	if err != nil {
+		t.Fatalf("AddJob: %v", err)
+	}
+
+	first, ok := coord.NextChunk(WorkRequest{Worker: "dead"})
+	if !ok || first.Start != 0 || first.End != 4 {
+		t.Fatalf("first chunk = %+v, %v", first, ok)
+	}
+
+	now = now.Add(5 * time.Second)
+	if n := coord.Reap(); n != 0 {
+		t.Fatalf("Reap() = %d before the timeout", n)
+	}
+
+	now = now.Add(6 * time.Second)
+	if n := coord.Reap(); n != 1 {
+		t.Fatalf("Reap() = %d, want 1", n)
+	}
+	for _, w := range coord.Status().Workers {
+		if w.ID == "dead" && w.Alive {
+			t.Error("dead worker still reported alive")
+		}
+	}
+
+	again, ok := coord.NextChunk(WorkRequest{Worker: "live"})
+	if !ok || again.ID != first.ID {
+		t.Fatalf("re-assigned chunk = %+v, want chunk %d", again, first.ID)
+	}
+
+	reply, _ := coord.Heartbeat(Heartbeat{Worker: "dead", JobID: job.ID, ChunkID: first.ID})
+	if !reply.Cancel {
+		t.Error("stale worker was not told to cancel")
+	}
+
+	now = now.Add(time.Second)
+	if err := coord.Complete(ChunkResult{Worker: "live", JobID: job.ID, ChunkID: again.ID, Attempts: 4}); err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	last, ok := coord.NextChunk(WorkRequest{Worker: "live"})
+	if !ok || last.Start != 4 || last.End != 6 {
+		t.Fatalf("last chunk = %+v, %v", last, ok)
+	}
+	if err := coord.Complete(ChunkResult{Worker: "live", JobID: job.ID, ChunkID: last.ID, Attempts: 2}); err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+
+	st, _ := coord.Job(job.ID)
+	if !st.Done || st.Found || st.Completed != 6 {
+		t.Errorf("job status = %+v, want exhausted", st)
+	}
+
+	for _, w := range coord.Status().Workers {
+		if w.ID == "live" && (w.Chunks != 2 || w.Attempts != 6) {
+			t.Errorf("live worker stats = %+v", w)
+		}
+	}
+}
+
+func TestUnreadableChunkNotCompleted(t *testing.T) {
+	w := NewWorker("http://127.0.0.1:0", 1)
+	chunk := Chunk{
+		JobID:  "job",
+		Target: pdftest.Encrypt("x", 3, "missing"),
+		Attack: AttackSpec{Mode: ModeWordlist, Wordlist: filepath.Join(t.TempDir(), "gone.txt")},
+		End:    10,
+	}
+	if _, complete := w.runChunk(context.Background(), chunk); complete {
+		t.Error("chunk with a missing wordlist reported complete")
+	}
+}
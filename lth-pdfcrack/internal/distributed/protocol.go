@@ -0,0 +1,218 @@
+package distributed
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lth/pdfcrack/internal/attacks"
+	"github.com/lth/pdfcrack/internal/pdf"
+)
+
+const (
+	ModeWordlist    = "wordlist"
+	ModeIncremental = "incremental"
+	ModeMask        = "mask"
+	ModeHybridWM    = "hybrid-wm"
+	ModeHybridMW    = "hybrid-mw"
+	ModeRandom      = "random"
+	ModeTemplate    = "template"
+)
+
+type AttackSpec struct {
+	Mode      string `json:"mode"`
+	Wordlist  string `json:"wordlist,omitempty"`
+	Charset   string `json:"charset,omitempty"`
+	MinLength int    `json:"min_length,omitempty"`
+	MaxLength int    `json:"max_length,omitempty"`
+	Seed      int64  `json:"seed,omitempty"`
+	Template  string `json:"template,omitempty"`
+
+	Mask           string   `json:"mask,omitempty"`
+	CustomCharsets []string `json:"custom_charsets,omitempty"`
+	Increment      bool     `json:"increment,omitempty"`
+	IncrementMin   int      `json:"increment_min,omitempty"`
+	IncrementMax   int      `json:"increment_max,omitempty"`
+}
+
+func (s AttackSpec) incrementalConfig() attacks.IncrementalConfig {
+	return attacks.IncrementalConfig{
+		Charset:   s.Charset,
+		MinLength: s.MinLength,
+		MaxLength: s.MaxLength,
+	}
+}
+
+func (s AttackSpec) randomConfig() attacks.RandomConfig {
+	return attacks.RandomConfig{
+		Charset:   s.Charset,
+		MinLength: s.MinLength,
+		MaxLength: s.MaxLength,
+		Seed:      s.Seed,
+	}
+}
+
+func (s AttackSpec) maskConfig() attacks.MaskConfig {
+	config := attacks.MaskConfig{
+		Mask:         s.Mask,
+		Increment:    s.Increment,
+		IncrementMin: s.IncrementMin,
+		IncrementMax: s.IncrementMax,
+	}
+	copy(config.Custom[:], s.CustomCharsets)
+	return config
+}
+
+func (s AttackSpec) templateKeyspace() (*attacks.TemplateKeyspace, error) {
+	var custom [4]string
+	copy(custom[:], s.CustomCharsets)
+	return attacks.NewTemplateKeyspace(s.Template, custom)
+}
+
+func (s AttackSpec) hybrid() (*attacks.Hybrid, error) {
+	return attacks.NewHybrid(s.Wordlist, s.maskConfig(), s.Mode == ModeHybridMW)
+}
+
+func (s AttackSpec) Keyspace() (uint64, error) {
+	switch s.Mode {
+	case ModeHybridWM, ModeHybridMW:
+		h, err := s.hybrid()
+		if err != nil {
+			return 0, err
+		}
+		return h.Keyspace()
+	case ModeMask:
+		ks, err := attacks.NewMaskKeyspace(s.maskConfig())
+		if err != nil {
+			return 0, err
+		}
+		if ks.Overflow() {
+			return 0, fmt.Errorf("mask keyspace exceeds 2^64 candidates")
+		}
+		return ks.Size(), nil
+	case ModeTemplate:
+		ks, err := s.templateKeyspace()
+		if err != nil {
+			return 0, err
+		}
+		return ks.Size(), nil
+	case ModeIncremental:
+		ks := attacks.NewIncrementalKeyspace(s.incrementalConfig())
+		if ks.Overflow() {
+			return 0, fmt.Errorf("incremental keyspace exceeds 2^64 candidates")
+		}
+		return ks.Size(), nil
+	case ModeRandom:
+		ks := attacks.NewRandomKeyspace(s.randomConfig())
+		if ks.Overflow() {
+			return 0, fmt.Errorf("random keyspace exceeds 2^64 candidates")
+		}
+		return ks.Size(), nil
+	case ModeWordlist:
+		return attacks.CountLines(s.Wordlist)
+	default:
+		return 0, fmt.Errorf("unsupported attack mode %q", s.Mode)
+	}
+}
+
+// Generator starts the candidates in [start, end) for this attack. An error
+// means the chunk cannot be worked on here, not that it holds no candidates.
+func (s AttackSpec) Generator(ctx context.Context, start, end uint64) (<-chan string, error) {
+	switch s.Mode {
+	case ModeHybridWM, ModeHybridMW:
+		h, err := s.hybrid()
+		if err != nil {
+			return nil, err
+		}
+		return h.Generator(ctx, start, end, nil)
+	case ModeMask:
+		ks, err := attacks.NewMaskKeyspace(s.maskConfig())
+		if err != nil {
+			return nil, err
+		}
+		return attacks.KeyspaceGenerator(ctx, ks, start, end), nil
+	case ModeTemplate:
+		ks, err := s.templateKeyspace()
+		if err != nil {
+			return nil, err
+		}
+		return attacks.KeyspaceGenerator(ctx, ks, start, end), nil
+	case ModeIncremental:
+		ks := attacks.NewIncrementalKeyspace(s.incrementalConfig())
+		return attacks.KeyspaceGenerator(ctx, ks, start, end), nil
+	case ModeRandom:
+		ks := attacks.NewRandomKeyspace(s.randomConfig())
+		return attacks.KeyspaceGenerator(ctx, ks, start, end), nil
+	case ModeWordlist:
+		return attacks.WordlistRangeGenerator(ctx, s.Wordlist, start, end)
+	default:
+		return nil, fmt.Errorf("unsupported attack mode %q", s.Mode)
+	}
+}
+
+type JobRequest struct {
+	Target    *pdf.EncryptionInfo `json:"target"`
+	Attack    AttackSpec          `json:"attack"`
+	ChunkSize uint64              `json:"chunk_size,omitempty"`
+}
+
+type JobStatus struct {
+	ID        string     `json:"id"`
+	Attack    AttackSpec `json:"attack"`
+	Keyspace  uint64     `json:"keyspace"`
+	Completed uint64     `json:"completed"`
+	Attempts  uint64     `json:"attempts"`
+	Done      bool       `json:"done"`
+	Found     bool       `json:"found"`
+	Password  string     `json:"password,omitempty"`
+}
+
+type Chunk struct {
+	JobID  string              `json:"job_id"`
+	ID     uint64              `json:"id"`
+	Start  uint64              `json:"start"`
+	End    uint64              `json:"end"`
+	Target *pdf.EncryptionInfo `json:"target"`
+	Attack AttackSpec          `json:"attack"`
+}
+
+type WorkRequest struct {
+	Worker  string `json:"worker"`
+	Threads int    `json:"threads"`
+}
+
+type Heartbeat struct {
+	Worker   string `json:"worker"`
+	JobID    string `json:"job_id"`
+	ChunkID  uint64 `json:"chunk_id"`
+	Attempts uint64 `json:"attempts"`
+}
+
+type HeartbeatReply struct {
+	Cancel bool `json:"cancel"`
+}
+
+type ChunkResult struct {
+	Worker   string        `json:"worker"`
+	JobID    string        `json:"job_id"`
+	ChunkID  uint64        `json:"chunk_id"`
+	Attempts uint64        `json:"attempts"`
+	Found    bool          `json:"found"`
+	Password string        `json:"password,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+type WorkerStatus struct {
+	ID       string    `json:"id"`
+	Threads  int       `json:"threads"`
+	LastSeen time.Time `json:"last_seen"`
+	Attempts uint64    `json:"attempts"`
+	Rate     float64   `json:"rate"`
+	Chunks   uint64    `json:"chunks"`
+	Alive    bool      `json:"alive"`
+}
+
+type Status struct {
+	Jobs    []JobStatus    `json:"jobs"`
+	Workers []WorkerStatus `json:"workers"`
+}
@@ -0,0 +1,188 @@
+package distributed
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/lth/pdfcrack/internal/cracker"
+)
+
+type Worker struct {
+	Server            string
+	ID                string
+	Threads           int
+	HeartbeatInterval time.Duration
+	PollInterval      time.Duration
+	Client            *http.Client
+	Logf              func(format string, args ...interface{})
+}
+
+func NewWorker(server string, threads int) *Worker {
+	host, _ := os.Hostname()
+	return &Worker{
+		Server:            strings.TrimRight(server, "/"),
+		ID:                fmt.Sprintf("%s-%d", host, os.Getpid()),
+		Threads:           threads,
+		HeartbeatInterval: 5 * time.Second,
+		PollInterval:      2 * time.Second,
+		Client:            &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (w *Worker) Run(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		chunk, ok, err := w.fetch(ctx)
+		if err != nil {
+			w.logf("fetch failed: %v", err)
+		}
+		if err != nil || !ok {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(w.PollInterval):
+			}
+			continue
+		}
+
+		res, complete := w.runChunk(ctx, chunk)
+		if !complete {
+			// Either we are shutting down or the coordinator took the chunk
+			// back; in both cases it is re-assigned from the coordinator's side.
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			continue
+		}
+		if err := w.post("/api/result", res, nil); err != nil {
+			w.logf("result for %s/%d failed: %v", chunk.JobID, chunk.ID, err)
+		}
+	}
+}
+
+func (w *Worker) runChunk(ctx context.Context, chunk Chunk) (ChunkResult, bool) {
+	res := ChunkResult{Worker: w.ID, JobID: chunk.JobID, ChunkID: chunk.ID}
+
+	chunkCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// A chunk whose candidates cannot be produced is left unreported, so the
+	// coordinator hands it out again once its heartbeat times out.
+	passwords, err := chunk.Attack.Generator(chunkCtx, chunk.Start, chunk.End)
+	if err != nil {
+		w.logf("chunk %s/%d: %v", chunk.JobID, chunk.ID, err)
+		return res, false
+	}
+
+	c := cracker.New(chunk.Target, w.Threads)
+	var attempts uint64
+	c.SetProgressCallback(func(p cracker.Progress) {
+		atomic.StoreUint64(&attempts, p.Attempts)
+	})
+
+	stopBeat := make(chan struct{})
+	beatDone := make(chan struct{})
+	go func() {
+		defer close(beatDone)
+		ticker := time.NewTicker(w.HeartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stopBeat:
+				return
+			case <-ticker.C:
+				hb := Heartbeat{
+					Worker:   w.ID,
+					JobID:    chunk.JobID,
+					ChunkID:  chunk.ID,
+					Attempts: atomic.LoadUint64(&attempts),
+				}
+				var reply HeartbeatReply
+				if err := w.post("/api/heartbeat", hb, &reply); err != nil {
+					w.logf("heartbeat failed: %v", err)
+					continue
+				}
+				if reply.Cancel {
+					cancel()
+				}
+			}
+		}
+	}()
+
+	result := c.CrackWithWordlist(chunkCtx, passwords)
+	close(stopBeat)
+	<-beatDone
+
+	res.Attempts = result.Attempts
+	res.Found = result.Found
+	res.Password = result.Password
+	res.Duration = result.Duration
+	return res, result.Found || chunkCtx.Err() == nil
+}
+
+func (w *Worker) fetch(ctx context.Context) (Chunk, bool, error) {
+	body, _ := json.Marshal(WorkRequest{Worker: w.ID, Threads: w.Threads})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.Server+"/api/work", bytes.NewReader(body))
+	if err != nil {
+		return Chunk{}, false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.Client.Do(req)
+	if err != nil {
+		return Chunk{}, false, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNoContent:
+		return Chunk{}, false, nil
+	case http.StatusOK:
+		var chunk Chunk
+		if err := json.NewDecoder(resp.Body).Decode(&chunk); err != nil {
+			return Chunk{}, false, err
+		}
+		return chunk, true, nil
+	default:
+		return Chunk{}, false, fmt.Errorf("server returned %s", resp.Status)
+	}
+}
+
+func (w *Worker) post(path string, in, out interface{}) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+
+	resp, err := w.Client.Post(w.Server+path, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("server returned %s", resp.Status)
+	}
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
+
+func (w *Worker) logf(format string, args ...interface{}) {
+	if w.Logf != nil {
+		w.Logf(format, args...)
+	}
+}
@@ -0,0 +1,114 @@
+package harvest
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+)
+
+// emailText returns the words of a saved email: the subject, the names and
+// addresses of sender and recipients, the text of its plain and HTML parts,
+// and the names of its attachments. The password of an attached document is
+// often in the covering message itself.
+func emailText(data []byte) []string {
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		return Tokenize(string(data))
+	}
+
+	var out []string
+	dec := new(mime.WordDecoder)
+	subject, err := dec.DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		subject = msg.Header.Get("Subject")
+	}
+	out = append(out, Tokenize(subject)...)
+	for _, field := range []string{"From", "To", "Cc"} {
+		addrs, err := msg.Header.AddressList(field)
+		if err != nil {
+			continue
+		}
+		for _, a := range addrs {
+			out = append(out, Tokenize(a.Name)...)
+			local, domain, _ := strings.Cut(a.Address, "@")
+			out = append(out, Tokenize(local)...)
+			if labels := strings.Split(domain, "."); len(labels) > 1 {
+				out = append(out, Tokenize(labels[len(labels)-2])...)
+			}
+		}
+	}
+
+	part := mailPart{
+		contentType: msg.Header.Get("Content-Type"),
+		encoding:    msg.Header.Get("Content-Transfer-Encoding"),
+		body:        msg.Body,
+	}
+	return append(out, part.words(0)...)
+}
+
+type mailPart struct {
+	contentType string
+	encoding    string
+	disposition string
+	body        io.Reader
+}
+
+// maxMIMEDepth bounds how deeply nested multiparts are followed.
+const maxMIMEDepth = 8
+
+func (p mailPart) words(depth int) []string {
+	var out []string
+	if _, params, err := mime.ParseMediaType(p.disposition); err == nil && params["filename"] != "" {
+		out = append(out, Tokenize(fileStem(params["filename"]))...)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(p.contentType)
+	if err != nil {
+		mediaType = "text/plain"
+	}
+	if name := params["name"]; name != "" {
+		out = append(out, Tokenize(fileStem(name))...)
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		if depth >= maxMIMEDepth || params["boundary"] == "" {
+			return out
+		}
+		r := multipart.NewReader(p.body, params["boundary"])
+		for {
+			part, err := r.NextRawPart()
+			if err != nil {
+				break
+			}
+			child := mailPart{
+				contentType: part.Header.Get("Content-Type"),
+				encoding:    part.Header.Get("Content-Transfer-Encoding"),
+				disposition: part.Header.Get("Content-Disposition"),
+				body:        part,
+			}
+			out = append(out, child.words(depth+1)...)
+		}
+		return out
+	}
+	if mediaType != "text/plain" && mediaType != "text/html" {
+		return out
+	}
+
+	body := p.body
+	switch strings.ToLower(strings.TrimSpace(p.encoding)) {
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	}
+	text, _ := io.ReadAll(io.LimitReader(body, maxFileBytes))
+	if mediaType == "text/html" {
+		text = xmlTag.ReplaceAll(text, []byte(" "))
+	}
+	return append(out, Tokenize(string(text))...)
+}
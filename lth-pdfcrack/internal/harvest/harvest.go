@@ -0,0 +1,267 @@
+// Package harvest builds a targeted wordlist from an encrypted PDF and what
+// surrounds it: its file name, whatever metadata is readable without the
+// password, and the text of other files in the same directory.
+package harvest
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Target is an encrypted PDF to harvest from. EncryptMeta is the /Encrypt
+// dictionary's EncryptMetadata: when false the XMP stream is plain text.
+type Target struct {
+	Path        string
+	EncryptMeta bool
+}
+
+// Counts reports how many distinct words each kind of source gave.
+type Counts struct {
+	Names    int
+	Metadata int
+	Siblings int
+}
+
+const (
+	minWordLen = 3
+	maxWordLen = 32
+	// maxFileBytes caps how much of each sibling file is read.
+	maxFileBytes = 8 << 20
+)
+
+// Words harvests words for targets, most telling first: file names, then
+// metadata and /ID, then words from sibling files by how often they occur.
+// At most max words are returned; 0 means no limit.
+func Words(targets []Target, max int) ([]string, Counts) {
+	var names, meta wordSet
+	siblings := newWordCounter()
+	dirs := make(map[string]bool)
+	self := make(map[string]bool)
+	for _, t := range targets {
+		if abs, err := filepath.Abs(t.Path); err == nil {
+			self[abs] = true
+		}
+	}
+
+	for _, t := range targets {
+		names.addText(fileStem(t.Path))
+		if data, err := os.ReadFile(t.Path); err == nil {
+			meta.addAll(pdfMetadata(data, !t.EncryptMeta))
+			meta.addAll(fileIDWords(data))
+		}
+		dir := filepath.Dir(t.Path)
+		if !dirs[dir] {
+			dirs[dir] = true
+			harvestDir(dir, self, siblings)
+		}
+	}
+
+	var out wordSet
+	out.addAll(names.words)
+	counts := Counts{Names: len(out.words)}
+	out.addAll(meta.words)
+	counts.Metadata = len(out.words) - counts.Names
+	out.addAll(siblings.ranked())
+	counts.Siblings = len(out.words) - counts.Names - counts.Metadata
+
+	words := out.words
+	if max > 0 && len(words) > max {
+		words = words[:max]
+	}
+	return words, counts
+}
+
+// fileStem is a file name without directory and extension.
+func fileStem(path string) string {
+	base := filepath.Base(path)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+// harvestDir reads the .pdf, .txt and .eml files next to the targets. Their
+// names count as well as their text.
+func harvestDir(dir string, self map[string]bool, counter *wordCounter) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		if !e.Type().IsRegular() {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		if abs, err := filepath.Abs(path); err == nil && self[abs] {
+			continue
+		}
+		ext := strings.ToLower(filepath.Ext(path))
+		if ext != ".pdf" && ext != ".txt" && ext != ".eml" {
+			continue
+		}
+		data, err := readCapped(path)
+		if err != nil {
+			continue
+		}
+
+		counter.addText(fileStem(path))
+		switch ext {
+		case ".pdf":
+			counter.addAll(pdfText(data))
+		case ".txt":
+			counter.addText(string(data))
+		case ".eml":
+			counter.addAll(emailText(data))
+		}
+	}
+}
+
+func readCapped(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(io.LimitReader(f, maxFileBytes))
+}
+
+// wordSet keeps words in first-seen order without repeats.
+type wordSet struct {
+	words []string
+	seen  map[string]bool
+}
+
+func (s *wordSet) add(w string) {
+	if s.seen == nil {
+		s.seen = make(map[string]bool)
+	}
+	if !s.seen[w] {
+		s.seen[w] = true
+		s.words = append(s.words, w)
+	}
+}
+
+func (s *wordSet) addAll(words []string) {
+	for _, w := range words {
+		s.add(w)
+	}
+}
+
+func (s *wordSet) addText(text string) {
+	s.addAll(Tokenize(text))
+}
+
+// wordCounter ranks words by how often they occur, ties in first-seen order.
+type wordCounter struct {
+	counts map[string]int
+	order  []string
+}
+
+func newWordCounter() *wordCounter {
+	return &wordCounter{counts: make(map[string]int)}
+}
+
+func (c *wordCounter) addAll(words []string) {
+	for _, w := range words {
+		if stopWords[strings.ToLower(w)] {
+			continue
+		}
+		if c.counts[w] == 0 {
+			c.order = append(c.order, w)
+		}
+		c.counts[w]++
+	}
+}
+
+func (c *wordCounter) addText(text string) {
+	c.addAll(Tokenize(text))
+}
+
+func (c *wordCounter) ranked() []string {
+	words := append([]string(nil), c.order...)
+	sort.SliceStable(words, func(i, j int) bool {
+		return c.counts[words[i]] > c.counts[words[j]]
+	})
+	return words
+}
+
+var hexID = regexp.MustCompile(`^[0-9a-fA-F]{16,}$`)
+
+// Tokenize splits text into candidate words. Space-separated tokens are kept
+// whole, as a password quoted in an email would be, and split into runs of
+// letters and digits, which are split again at case and letter/digit
+// changes: "InvoiceMarch2024" gives itself, "Invoice", "March" and "2024". A
+// short name of several words is also kept joined, as "invoice_march" gives
+// "invoicemarch". Words shorter than 3 or longer than 32 characters are
+// dropped, and so are long hex IDs.
+func Tokenize(text string) []string {
+	var out []string
+	add := func(w string) {
+		n := utf8.RuneCountInString(w)
+		if n >= minWordLen && n <= maxWordLen {
+			out = append(out, w)
+		}
+	}
+
+	isWord := func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsDigit(r)
+	}
+	for _, f := range strings.Fields(text) {
+		f = strings.Trim(f, `.,;:!?"'()[]{}<>`)
+		if strings.IndexFunc(f, isWord) >= 0 && strings.IndexFunc(f, func(r rune) bool { return !isWord(r) }) >= 0 {
+			add(f)
+		}
+	}
+	var fields []string
+	for _, f := range strings.FieldsFunc(text, func(r rune) bool { return !isWord(r) }) {
+		if !hexID.MatchString(f) {
+			fields = append(fields, f)
+		}
+	}
+	for _, f := range fields {
+		add(f)
+		if parts := splitCase(f); len(parts) > 1 {
+			for _, p := range parts {
+				add(p)
+			}
+		}
+	}
+	if len(fields) > 1 && len(fields) <= 4 {
+		add(strings.Join(fields, ""))
+	}
+	return out
+}
+
+// splitCase splits a word where lower case turns to upper case and where
+// letters and digits meet.
+func splitCase(word string) []string {
+	var parts []string
+	runes := []rune(word)
+	start := 0
+	for i := 1; i < len(runes); i++ {
+		prev, cur := runes[i-1], runes[i]
+		if unicode.IsLower(prev) && unicode.IsUpper(cur) ||
+			unicode.IsDigit(prev) != unicode.IsDigit(cur) {
+			parts = append(parts, string(runes[start:i]))
+			start = i
+		}
+	}
+	return append(parts, string(runes[start:]))
+}
+
+// stopWords are common words that sibling text is full of and passwords
+// rarely are.
+var stopWords = map[string]bool{
+	"the": true, "and": true, "for": true, "you": true, "are": true,
+	"with": true, "this": true, "that": true, "from": true, "have": true,
+	"your": true, "will": true, "not": true, "was": true, "but": true,
+	"all": true, "can": true, "our": true, "has": true, "please": true,
+	"thanks": true, "regards": true, "dear": true, "hello": true,
+	"der": true, "die": true, "und": true, "les": true, "des": true,
+	"une": true, "est": true, "pour": true, "que": true, "del": true,
+	"los": true, "las": true, "por": true, "het": true, "een": true,
+	"obj": true, "endobj": true, "stream": true, "endstream": true,
+}
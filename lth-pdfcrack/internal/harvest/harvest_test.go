@@ -0,0 +1,98 @@
+package harvest
+
+import (
+	"bytes"
+	"compress/zlib"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"InvoiceMarch2024", "InvoiceMarch2024,Invoice,March,2024"},
+		{"invoice_march", "invoice_march,invoice,march,invoicemarch"},
+		{"The password is Xy7#pq.", "Xy7#pq,The,password,Xy7"},
+		{"ab 0123456789abcdef0123", ""},
+		{"Überweisung Müller", "Überweisung,Müller,ÜberweisungMüller"},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(Tokenize(tt.text), ","); got != tt.want {
+			t.Errorf("Tokenize(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func flate(t *testing.T, s string) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	w := zlib.NewWriter(&b)
+	w.Write([]byte(s))
+	w.Close()
+	return b.Bytes()
+}
+
+func TestWords(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, data []byte) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	target := write("Payslip_Dupont.pdf", []byte(`%PDF-1.6
+1 0 obj << /Type /Metadata /Subtype /XML >> stream
+<x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:Description pdf:Producer="Acme Payroll" xmp:CreateDate="1985-03-15T10:00:00Z" xmpMM:DocumentID="uuid:0d1f2e3c-aaaa-bbbb-cccc-0123456789ab"><dc:title>Salary Statement</dc:title></rdf:Description></x:xmpmeta>
+endstream endobj
+2 0 obj << /Producer (\x8f\x01\xe3\x99) >> endobj
+trailer << /ID [<00112233445566778899AABBCCDDEEFF><00112233445566778899AABBCCDDEEFF>] /Encrypt 3 0 R >>
+`))
+	write("notes.txt", []byte("Remember: the password is Kx9!mz\n"))
+	write("cover.eml", []byte("From: Jane Roe <jane.roe@example.org>\r\n"+
+		"Subject: =?utf-8?q?Your_documents?=\r\n"+
+		"MIME-Version: 1.0\r\n"+
+		"Content-Type: multipart/mixed; boundary=XX\r\n\r\n"+
+		"--XX\r\nContent-Type: text/plain\r\nContent-Transfer-Encoding: base64\r\n\r\n"+
+		"VGhlIGNvZGUgaXMgUGVwcGVybWludA==\r\n"+
+		"--XX\r\nContent-Type: application/pdf; name=\"Statement_Q3.pdf\"\r\n\r\nxxxx\r\n--XX--\r\n"))
+	content := flate(t, "BT (Quarterly Overview) Tj ET")
+	write("report.pdf", append(append([]byte("%PDF-1.4\n4 0 obj << /Length 1 /Filter /FlateDecode >> stream\n"), content...), []byte("\nendstream endobj\n")...))
+	write("ignored.doc", []byte("Secretword"))
+
+	words, counts := Words([]Target{{Path: target, EncryptMeta: false}}, 0)
+	got := make(map[string]int)
+	for i, w := range words {
+		got[w] = i
+	}
+	for _, want := range []string{
+		"Payslip_Dupont", "Payslip", "Dupont", "PayslipDupont",
+		"Acme", "Payroll", "Salary", "Statement", "15031985", "19850315", "00112233445566778899aabbccddeeff",
+		"Kx9!mz", "Jane", "roe", "example", "documents", "Peppermint", "Statement_Q3", "Quarterly", "notes", "cover",
+	} {
+		if _, ok := got[want]; !ok {
+			t.Errorf("missing %q", want)
+		}
+	}
+	for _, unwanted := range []string{"Secretword", "0d1f2e3c", "the", "uuid"} {
+		if _, ok := got[unwanted]; ok {
+			t.Errorf("unexpected %q", unwanted)
+		}
+	}
+	if got["Dupont"] > got["Acme"] || got["Acme"] > got["Peppermint"] {
+		t.Errorf("file name, metadata and sibling words out of order: %q", words)
+	}
+	if counts.Names != 4 || counts.Metadata == 0 || counts.Siblings == 0 {
+		t.Errorf("counts = %+v", counts)
+	}
+
+	words, _ = Words([]Target{{Path: target, EncryptMeta: true}}, 4)
+	if len(words) != 4 || strings.Contains(strings.Join(words, ","), "Acme") {
+		t.Errorf("encrypted metadata read or limit ignored: %q", words)
+	}
+}
@@ -0,0 +1,215 @@
+package harvest
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/hex"
+	"io"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/lth/pdfcrack/internal/pdf"
+)
+
+var (
+	xmpPacket  = regexp.MustCompile(`(?s)<x:xmpmeta.*?</x:xmpmeta>`)
+	xmlAttr    = regexp.MustCompile(`([A-Za-z]+):([A-Za-z]+)="([^"]*)"`)
+	xmlTag     = regexp.MustCompile(`<[^>]*>`)
+	isoDate    = regexp.MustCompile(`\b((?:19|20)\d\d)-(\d\d)-(\d\d)`)
+	pdfDate    = regexp.MustCompile(`D:((?:19|20)\d\d)(\d\d)(\d\d)`)
+	fileID     = regexp.MustCompile(`/ID\s*\[\s*<([0-9A-Fa-f]+)>\s*<([0-9A-Fa-f]+)>`)
+	fileIDText = regexp.MustCompile(`/ID\s*\[\s*\(((?:\\.|[^\\)])*)\)`)
+	infoString = regexp.MustCompile(`/(?:Title|Author|Subject|Keywords|Creator|Producer|Company)\s*(?:\(((?:\\.|[^\\)])*)\)|<([0-9A-Fa-f\s]+)>)`)
+	streamData = regexp.MustCompile(`(?s)<<((?:[^<>]|<<[^<>]*>>|<[0-9A-Fa-f\s]*>)*)>>\s*stream\r?\n`)
+	textString = regexp.MustCompile(`\(((?:\\.|[^\\)])*)\)`)
+	resourceID = regexp.MustCompile(`^(?:uuid|xmp\.[a-z]+|adobe:docid):`)
+)
+
+// pdfMetadata returns the words of a PDF's metadata that can be read without
+// its password: with readXMP the XMP packet (title, author, a producer such
+// as "Acme Payroll 4.2"), which /EncryptMetadata false leaves in the clear,
+// and any readable document-information strings, as in unencrypted sibling
+// PDFs. Dates become the forms people type, such as 15031985.
+func pdfMetadata(data []byte, readXMP bool) []string {
+	var out wordSet
+
+	if readXMP {
+		for _, packet := range xmpPackets(data) {
+			for _, value := range xmpValues(packet) {
+				out.addText(value)
+				out.addAll(dateForms(isoDate, value))
+			}
+		}
+	}
+
+	for _, m := range infoString.FindAllSubmatch(data, -1) {
+		if s, ok := readableString(m[1], m[2]); ok {
+			out.addText(s)
+		}
+	}
+	out.addAll(dateForms(pdfDate, string(data)))
+	return out.words
+}
+
+// fileIDWords returns the /ID, which is never encrypted. It is usually a
+// hash of the creation time and path, but some tools derive the password
+// from it or write readable text into it.
+func fileIDWords(data []byte) []string {
+	if m := fileID.FindSubmatch(data); m != nil {
+		id := strings.ToLower(string(m[1]))
+		return []string{id, strings.ToUpper(id)}
+	}
+	if m := fileIDText.FindSubmatch(data); m != nil {
+		if s, ok := readableString(m[1], nil); ok {
+			return Tokenize(s)
+		}
+	}
+	return nil
+}
+
+// xmpPackets finds XMP packets, in the clear or in Flate streams.
+func xmpPackets(data []byte) []string {
+	var packets []string
+	for _, p := range xmpPacket.FindAll(data, -1) {
+		packets = append(packets, string(p))
+	}
+	if len(packets) > 0 {
+		return packets
+	}
+	for _, stream := range pdfStreams(data, "/Metadata") {
+		for _, p := range xmpPacket.FindAll(stream, -1) {
+			packets = append(packets, string(p))
+		}
+	}
+	return packets
+}
+
+// xmpValues returns the attribute values and element text of an XMP packet,
+// leaving out namespaces and resource IDs.
+func xmpValues(packet string) []string {
+	var values []string
+	keep := func(v string) {
+		v = strings.TrimSpace(v)
+		if v != "" && !resourceID.MatchString(v) {
+			values = append(values, v)
+		}
+	}
+	for _, m := range xmlAttr.FindAllStringSubmatch(packet, -1) {
+		if m[1] != "xmlns" && m[1] != "rdf" && m[1] != "x" {
+			keep(m[3])
+		}
+	}
+	for _, text := range xmlTag.Split(packet, -1) {
+		keep(text)
+	}
+	return values
+}
+
+// pdfText returns the words of an unencrypted PDF: its metadata and the
+// literal strings of its page streams. Text drawn through font encodings
+// other than the standard ones is missed.
+func pdfText(data []byte) []string {
+	var out []string
+	if bytes.Contains(data, []byte("/Encrypt")) {
+		return nil
+	}
+	out = append(out, pdfMetadata(data, true)...)
+	for _, stream := range pdfStreams(data, "") {
+		for _, m := range textString.FindAllSubmatch(stream, -1) {
+			if s, ok := readableString(m[1], nil); ok {
+				out = append(out, Tokenize(s)...)
+			}
+		}
+	}
+	return out
+}
+
+// pdfStreams returns the decoded data of the streams whose dictionary
+// contains key, or of all streams when key is empty. Streams with filters
+// other than FlateDecode are skipped.
+func pdfStreams(data []byte, key string) [][]byte {
+	var streams [][]byte
+	for _, loc := range streamData.FindAllSubmatchIndex(data, -1) {
+		dict := data[loc[2]:loc[3]]
+		if key != "" && !bytes.Contains(dict, []byte(key)) {
+			continue
+		}
+		body := data[loc[1]:]
+		end := bytes.Index(body, []byte("endstream"))
+		if end < 0 {
+			continue
+		}
+		body = body[:end]
+
+		switch {
+		case bytes.Contains(dict, []byte("/FlateDecode")):
+			r, err := zlib.NewReader(bytes.NewReader(body))
+			if err != nil {
+				continue
+			}
+			decoded, _ := io.ReadAll(io.LimitReader(r, maxFileBytes))
+			streams = append(streams, decoded)
+		case !bytes.Contains(dict, []byte("/Filter")):
+			streams = append(streams, body)
+		}
+	}
+	return streams
+}
+
+// readableString decodes a literal or hex PDF string, with or without a
+// UTF-16 byte order mark, and reports whether it is text rather than the
+// ciphertext an encrypted document's strings are.
+func readableString(literal, hexDigits []byte) (string, bool) {
+	var raw []byte
+	if hexDigits != nil {
+		digits := bytes.Map(func(r rune) rune {
+			if unicode.IsSpace(r) {
+				return -1
+			}
+			return r
+		}, hexDigits)
+		var err error
+		if raw, err = hex.DecodeString(string(digits)); err != nil {
+			return "", false
+		}
+	} else {
+		raw = pdf.UnescapeString(literal)
+	}
+
+	var s string
+	if len(raw) >= 2 && raw[0] == 0xFE && raw[1] == 0xFF {
+		units := make([]uint16, 0, len(raw)/2)
+		for i := 2; i+1 < len(raw); i += 2 {
+			units = append(units, uint16(raw[i])<<8|uint16(raw[i+1]))
+		}
+		s = string(utf16.Decode(units))
+	} else if utf8.Valid(raw) {
+		s = string(raw)
+	} else {
+		// Mostly ciphertext; PDFDocEncoded accents are rare enough to miss.
+		return "", false
+	}
+
+	printable := 0
+	for _, r := range s {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsSpace(r) || unicode.IsPunct(r) {
+			printable++
+		}
+	}
+	n := utf8.RuneCountInString(s)
+	return s, n > 0 && printable*10 >= n*9
+}
+
+// dateForms finds the dates re matches in text, as year, month and day
+// groups, and writes each as DDMMYYYY, YYYYMMDD, DDMMYY, MMDDYYYY and YYYY.
+func dateForms(re *regexp.Regexp, text string) []string {
+	var out []string
+	for _, m := range re.FindAllStringSubmatch(text, -1) {
+		y, mo, d := m[1], m[2], m[3]
+		out = append(out, d+mo+y, y+mo+d, d+mo+y[2:], mo+d+y, y)
+	}
+	return out
+}
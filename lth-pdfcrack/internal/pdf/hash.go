@@ -0,0 +1,76 @@
+package pdf
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const hashPrefix = "$pdf$"
+
+// Hash renders the target in the pdf2john format:
+// $pdf$V*R*Length*P*EncryptMetadata*IDlen*ID*Ulen*U*Olen*O
+func (info *EncryptionInfo) Hash() string {
+	meta := 0
+	if info.EncryptMeta {
+		meta = 1
+	}
+	return fmt.Sprintf("%s%d*%d*%d*%d*%d*%d*%x*%d*%x*%d*%x", hashPrefix,
+		info.Version, info.Revision, info.Length, info.Permissions, meta,
+		len(info.FileID), info.FileID,
+		len(info.UserHash), info.UserHash,
+		len(info.OwnerHash), info.OwnerHash)
+}
+
+// ParseHash reads a pdf2john-style line. An optional "name:" prefix, as
+// written by pdf2john for each input file, is returned separately.
+func ParseHash(line string) (string, *EncryptionInfo, error) {
+	line = strings.TrimSpace(line)
+
+	idx := strings.Index(line, hashPrefix)
+	if idx < 0 {
+		return "", nil, fmt.Errorf("not a $pdf$ hash")
+	}
+	name := strings.TrimSuffix(line[:idx], ":")
+
+	fields := strings.Split(line[idx+len(hashPrefix):], "*")
+	if len(fields) < 11 {
+		return "", nil, fmt.Errorf("$pdf$ hash has %d fields, want 11", len(fields))
+	}
+
+	ints := make([]int64, 5)
+	for i := range ints {
+		v, err := strconv.ParseInt(fields[i], 10, 64)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid $pdf$ field %d: %w", i+1, err)
+		}
+		ints[i] = v
+	}
+
+	info := &EncryptionInfo{
+		Version:     int(ints[0]),
+		Revision:    int(ints[1]),
+		Length:      int(ints[2]),
+		Permissions: int32(ints[3]),
+		EncryptMeta: ints[4] != 0,
+	}
+
+	targets := []*[]byte{&info.FileID, &info.UserHash, &info.OwnerHash}
+	for i, target := range targets {
+		n, err := strconv.Atoi(fields[5+2*i])
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid $pdf$ length field: %w", err)
+		}
+		b, err := hex.DecodeString(fields[6+2*i])
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid $pdf$ hex field: %w", err)
+		}
+		if len(b) < n {
+			return "", nil, fmt.Errorf("$pdf$ field is %d bytes, header says %d", len(b), n)
+		}
+		*target = b[:n]
+	}
+
+	return name, info, nil
+}
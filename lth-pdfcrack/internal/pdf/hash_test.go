@@ -0,0 +1,61 @@
+package pdf_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/lth/pdfcrack/internal/pdf"
+	"github.com/lth/pdfcrack/internal/pdf/pdftest"
+)
+
+func TestHashRoundTrip(t *testing.T) {
+	info := pdftest.Encrypt("secret", 3, "hash.pdf")
+
+	name, parsed, err := pdf.ParseHash("docs/hash.pdf:" + info.Hash())
+	if err != nil {
+		t.Fatalf("ParseHash: %v", err)
+	}
+	if name != "docs/hash.pdf" {
+		t.Errorf("name = %q, want docs/hash.pdf", name)
+	}
+	if parsed.Revision != 3 || parsed.Length != 128 || parsed.Permissions != info.Permissions {
+		t.Errorf("parsed header = %+v", parsed)
+	}
+	if !bytes.Equal(parsed.FileID, info.FileID) || !bytes.Equal(parsed.UserHash, info.UserHash) || !bytes.Equal(parsed.OwnerHash, info.OwnerHash) {
+		t.Error("parsed /ID, /U or /O differ from the original")
+	}
+	if !parsed.CheckPassword("secret") {
+		t.Error("parsed hash does not accept the password")
+	}
+
+	for _, bad := range []string{"", "$pdf$2*3", "$pdf$2*3*128*-3904*1*16*zz*32*00*32*00"} {
+		if _, _, err := pdf.ParseHash(bad); err == nil {
+			t.Errorf("ParseHash(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestCheckPrepared(t *testing.T) {
+	a := pdftest.Encrypt("alpha", 3, "a.pdf")
+	b := pdftest.Encrypt("bravo", 2, "b.pdf")
+	if a.SharedKey() != b.SharedKey() {
+		t.Fatal("targets with the same /O and P have different shared keys")
+	}
+
+	for _, tt := range []struct {
+		password string
+		a, b     bool
+	}{
+		{"alpha", true, false},
+		{"bravo", false, true},
+		{"charlie", false, false},
+	} {
+		prepared := a.PreparePassword(tt.password)
+		if got := a.CheckPrepared(prepared); got != tt.a || got != a.CheckPassword(tt.password) {
+			t.Errorf("a.CheckPrepared(%q) = %v, want %v", tt.password, got, tt.a)
+		}
+		if got := b.CheckPrepared(prepared); got != tt.b || got != b.CheckPassword(tt.password) {
+			t.Errorf("b.CheckPrepared(%q) = %v, want %v", tt.password, got, tt.b)
+		}
+	}
+}
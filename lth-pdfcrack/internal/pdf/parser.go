@@ -8,6 +8,7 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"hash"
 	"io"
 	"os"
 	"regexp"
@@ -37,6 +38,16 @@ func (e *EncryptionInfo) String() string {
 		e.PDFVersion, e.Version, e.Revision, e.Length, algo)
 }
 
+// SignificantLength is how many password bytes the revision looks at; a
+// longer password opens the file exactly when its prefix does. R2-R4 pad or
+// cut the password to 32 bytes and R5/R6 truncate it to 127.
+func (e *EncryptionInfo) SignificantLength() int {
+	if e.Revision >= 5 {
+		return 127
+	}
+	return 32
+}
+
 var (
 	ErrNotEncrypted     = errors.New("PDF is not encrypted")
 	ErrUnsupportedPDF   = errors.New("unsupported PDF encryption")
@@ -237,6 +248,11 @@ func parseHexOrLiteral(data []byte) []byte {
 	return unescapePDFString([]byte(s))
 }
 
+// UnescapeString decodes the backslash escapes of a PDF literal string.
+func UnescapeString(data []byte) []byte {
+	return unescapePDFString(data)
+}
+
 func unescapePDFString(data []byte) []byte {
 	result := make([]byte, 0, len(data))
 	i := 0
@@ -305,6 +321,10 @@ func (info *EncryptionInfo) CheckPassword(password string) bool {
 }
 
 func (info *EncryptionInfo) computeEncryptionKey(password string) []byte {
+	return info.finishEncryptionKey(info.prefixHash(password))
+}
+
+func (info *EncryptionInfo) prefixHash(password string) hash.Hash {
 	paddedPassword := padPassword([]byte(password))
 	
 	h := md5.New()
@@ -315,6 +335,10 @@ func (info *EncryptionInfo) computeEncryptionKey(password string) []byte {
 	binary.LittleEndian.PutUint32(pBytes, uint32(info.Permissions))
 	h.Write(pBytes)
 	
+	return h
+}
+
+func (info *EncryptionInfo) finishEncryptionKey(h hash.Hash) []byte {
 	h.Write(info.FileID)
 	
 	if info.Revision >= 4 && !info.EncryptMeta {
@@ -24,6 +24,26 @@ func TestPadPassword(t *testing.T) {
 	}
 }
 
+func TestSignificantLength(t *testing.T) {
+	tests := []struct {
+		revision int
+		want     int
+	}{
+		{2, 32},
+		{3, 32},
+		{4, 32},
+		{5, 127},
+		{6, 127},
+	}
+
+	for _, tt := range tests {
+		info := &EncryptionInfo{Revision: tt.revision}
+		if got := info.SignificantLength(); got != tt.want {
+			t.Errorf("R%d: SignificantLength() = %d, want %d", tt.revision, got, tt.want)
+		}
+	}
+}
+
 func TestRC4Encrypt(t *testing.T) {
 	key := []byte{0x01, 0x02, 0x03, 0x04, 0x05}
 	data := []byte("test data")
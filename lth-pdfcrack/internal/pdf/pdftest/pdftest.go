@@ -0,0 +1,92 @@
+package pdftest
+
+import (
+	"crypto/md5"
+	"crypto/rc4"
+	"encoding/binary"
+
+	"github.com/lth/pdfcrack/internal/pdf"
+)
+
+var padding = []byte{
+	0x28, 0xBF, 0x4E, 0x5E, 0x4E, 0x75, 0x8A, 0x41,
+	0x64, 0x00, 0x4E, 0x56, 0xFF, 0xFA, 0x01, 0x08,
+	0x2E, 0x2E, 0x00, 0xB6, 0xD0, 0x68, 0x3E, 0x80,
+	0x2F, 0x0C, 0xA9, 0xFE, 0x64, 0x53, 0x69, 0x7A,
+}
+
+// Encrypt builds an RC4 EncryptionInfo (R2 or R3) whose user password is
+// password. Targets share /O and /P and differ only by fileID.
+func Encrypt(password string, revision int, fileID string) *pdf.EncryptionInfo {
+	info := &pdf.EncryptionInfo{
+		Version:     2,
+		Revision:    revision,
+		Length:      128,
+		Permissions: -3904,
+		OwnerHash:   make([]byte, 32),
+		FileID:      md5Sum([]byte(fileID)),
+		EncryptMeta: true,
+		PDFVersion:  "1.4",
+	}
+	if revision == 2 {
+		info.Version = 1
+		info.Length = 40
+	}
+	copy(info.OwnerHash, md5Sum([]byte("owner")))
+	copy(info.OwnerHash[16:], md5Sum([]byte("owner")))
+
+	key := computeKey(info, password)
+	if revision == 2 {
+		info.UserHash = rc4XOR(key, padding)
+		return info
+	}
+
+	h := md5.New()
+	h.Write(padding)
+	h.Write(info.FileID)
+	encrypted := rc4XOR(key, h.Sum(nil))
+	for i := 1; i <= 19; i++ {
+		newKey := make([]byte, len(key))
+		for j := range key {
+			newKey[j] = key[j] ^ byte(i)
+		}
+		encrypted = rc4XOR(newKey, encrypted)
+	}
+	info.UserHash = append(encrypted, make([]byte, 16)...)
+	return info
+}
+
+func computeKey(info *pdf.EncryptionInfo, password string) []byte {
+	padded := make([]byte, 32)
+	n := copy(padded, password)
+	copy(padded[n:], padding)
+
+	h := md5.New()
+	h.Write(padded)
+	h.Write(info.OwnerHash)
+	pBytes := make([]byte, 4)
+	binary.LittleEndian.PutUint32(pBytes, uint32(info.Permissions))
+	h.Write(pBytes)
+	h.Write(info.FileID)
+	key := h.Sum(nil)
+
+	keyLen := info.Length / 8
+	if info.Revision >= 3 {
+		for i := 0; i < 50; i++ {
+			key = md5Sum(key[:keyLen])
+		}
+	}
+	return key[:keyLen]
+}
+
+func rc4XOR(key, data []byte) []byte {
+	c, _ := rc4.NewCipher(key)
+	out := make([]byte, len(data))
+	c.XORKeyStream(out, data)
+	return out
+}
+
+func md5Sum(data []byte) []byte {
+	sum := md5.Sum(data)
+	return sum[:]
+}
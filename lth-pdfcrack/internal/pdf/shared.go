@@ -0,0 +1,43 @@
+package pdf
+
+import (
+	"crypto/md5"
+	"encoding"
+	"encoding/binary"
+)
+
+// PreparedPassword carries the MD5 state over the padded password, /O and P.
+// Targets with the same SharedKey can finish the key derivation from it
+// without hashing those 68 bytes again.
+type PreparedPassword struct {
+	state []byte
+}
+
+func (info *EncryptionInfo) SharedKey() string {
+	pBytes := make([]byte, 4)
+	binary.LittleEndian.PutUint32(pBytes, uint32(info.Permissions))
+	return string(info.OwnerHash) + string(pBytes)
+}
+
+func (info *EncryptionInfo) PreparePassword(password string) PreparedPassword {
+	state, _ := info.prefixHash(password).(encoding.BinaryMarshaler).MarshalBinary()
+	return PreparedPassword{state: state}
+}
+
+func (info *EncryptionInfo) CheckPrepared(prepared PreparedPassword) bool {
+	if info.Revision >= 5 {
+		return false
+	}
+
+	h := md5.New()
+	if err := h.(encoding.BinaryUnmarshaler).UnmarshalBinary(prepared.state); err != nil {
+		return false
+	}
+
+	key := info.finishEncryptionKey(h)
+	if key == nil {
+		return false
+	}
+
+	return info.verifyUserPasswordRC4(key)
+}
@@ -0,0 +1,794 @@
+// Package plan loads attack plans: ordered stages, each with its own attack,
+// budgets and stop condition, run one after another until the targets fall.
+package plan
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/bits"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/lth/pdfcrack/internal/attacks"
+	"github.com/lth/pdfcrack/internal/attacks/rules"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	AttackList        = "list"
+	AttackWordlist    = "wordlist"
+	AttackIncremental = "incremental"
+	AttackMask        = "mask"
+	AttackHybridWM    = "hybrid-wm"
+	AttackHybridMW    = "hybrid-mw"
+	AttackCombinator  = "combinator"
+	AttackMarkov      = "markov"
+	AttackPCFG        = "pcfg"
+	AttackPrince      = "prince"
+	AttackTemplate    = "template"
+	AttackPattern     = "pattern"
+	AttackKeyboard    = "keyboard"
+	AttackPassphrase  = "passphrase"
+	AttackRandom      = "random"
+)
+
+const (
+	// StopAll ends the plan once every target is cracked.
+	StopAll = "all"
+	// StopAny ends the plan after a stage that cracked at least one target.
+	StopAny = "any"
+)
+
+type Plan struct {
+	Name   string  `yaml:"name"`
+	Stages []Stage `yaml:"stages"`
+}
+
+type Stage struct {
+	Name            string        `yaml:"name"`
+	Attack          string        `yaml:"attack"`
+	Passwords       []string      `yaml:"passwords"`
+	Wordlist        string        `yaml:"wordlist"`
+	Rules           []string      `yaml:"rules"`
+	Charset         string        `yaml:"charset"`
+	MinLength       int           `yaml:"min_length"`
+	MaxLength       int           `yaml:"max_length"`
+	Mask            string        `yaml:"mask"`
+	CustomCharsets  []string      `yaml:"custom_charsets"`
+	Increment       bool          `yaml:"increment"`
+	IncrementMin    int           `yaml:"increment_min"`
+	IncrementMax    int           `yaml:"increment_max"`
+	Left            string        `yaml:"left"`
+	Middle          string        `yaml:"middle"`
+	Right           string        `yaml:"right"`
+	Separators      []string      `yaml:"separators"`
+	LeftRules       []string      `yaml:"left_rules"`
+	RightRules      []string      `yaml:"right_rules"`
+	MarkovStats     string        `yaml:"markov_stats"`
+	MarkovThreshold int           `yaml:"markov_threshold"`
+	PCFGGrammar     string        `yaml:"pcfg_grammar"`
+	ElemCntMin      int           `yaml:"elem_cnt_min"`
+	ElemCntMax      int           `yaml:"elem_cnt_max"`
+	Seed            int64         `yaml:"seed"`
+	Template        string        `yaml:"template"`
+	Patterns        []string      `yaml:"patterns"`
+	DateFrom        string        `yaml:"date_from"`
+	DateTo          string        `yaml:"date_to"`
+	Months          []string      `yaml:"months"`
+	Names           []string      `yaml:"names"`
+	PhonePrefixes   []string      `yaml:"phone_prefixes"`
+	PhoneDigits     int           `yaml:"phone_digits"`
+	CountryCode     string        `yaml:"country_code"`
+	LuhnDigits      int           `yaml:"luhn_digits"`
+	LuhnPrefix      string        `yaml:"luhn_prefix"`
+	Layouts         []string      `yaml:"layouts"`
+	Turns           int           `yaml:"turns"`
+	Copies          int           `yaml:"copies"`
+	Shift           []string      `yaml:"shift"`
+	MinWords        int           `yaml:"min_words"`
+	MaxWords        int           `yaml:"max_words"`
+	Cases           []string      `yaml:"cases"`
+	Suffixes        []string      `yaml:"suffixes"`
+	MaxTime         time.Duration `yaml:"max_time"`
+	MaxAttempts     uint64        `yaml:"max_attempts"`
+	StopWhen        string        `yaml:"stop_when"`
+
+	engine     *rules.Engine
+	combinator *attacks.Combinator
+	markov     *attacks.MarkovStats
+	pcfg       *attacks.PCFG
+	prince     *attacks.PrinceKeyspace
+	template   *attacks.TemplateKeyspace
+	pattern    *attacks.PatternKeyspace
+	keyboard   *attacks.KeyboardKeyspace
+	passphrase *attacks.PassphraseKeyspace
+}
+
+// Load reads a plan from YAML or JSON; JSON is accepted as YAML.
+func Load(path string) (*Plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+func Parse(data []byte) (*Plan, error) {
+	var p Plan
+	if err := yaml.Unmarshal(data, &p); err != nil {
+		return nil, err
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (p *Plan) Validate() error {
+	if len(p.Stages) == 0 {
+		return fmt.Errorf("plan has no stages")
+	}
+	for i := range p.Stages {
+		s := &p.Stages[i]
+		if s.Name == "" {
+			s.Name = fmt.Sprintf("stage %d", i+1)
+		}
+		if s.StopWhen == "" {
+			s.StopWhen = StopAll
+		}
+		if err := s.validate(); err != nil {
+			return fmt.Errorf("%s: %w", s.Name, err)
+		}
+	}
+	return nil
+}
+
+func (s *Stage) validate() error {
+	switch s.Attack {
+	case AttackList:
+		if len(s.Passwords) == 0 {
+			return fmt.Errorf("list attack needs passwords")
+		}
+	case AttackWordlist:
+		if s.Wordlist == "" {
+			return fmt.Errorf("wordlist attack needs a wordlist")
+		}
+	case AttackMask, AttackHybridWM, AttackHybridMW:
+		if s.Mask == "" {
+			return fmt.Errorf("%s attack needs a mask", s.Attack)
+		}
+		if s.Attack != AttackMask && s.Wordlist == "" {
+			return fmt.Errorf("%s attack needs a wordlist", s.Attack)
+		}
+		if len(s.CustomCharsets) > 4 {
+			return fmt.Errorf("at most 4 custom charsets")
+		}
+		if _, err := attacks.NewMaskKeyspace(s.maskConfig()); err != nil {
+			return err
+		}
+	case AttackCombinator:
+		left, err := loadEngine(s.LeftRules)
+		if err != nil {
+			return err
+		}
+		right, err := loadEngine(s.RightRules)
+		if err != nil {
+			return err
+		}
+		s.combinator, err = attacks.NewCombinator(attacks.CombinatorConfig{
+			Left:       s.Left,
+			Middle:     s.Middle,
+			Right:      s.Right,
+			Separators: s.Separators,
+			LeftRules:  left,
+			RightRules: right,
+		})
+		if err != nil {
+			return err
+		}
+	case AttackMarkov:
+		if s.MarkovThreshold < 0 {
+			return fmt.Errorf("markov_threshold must not be negative")
+		}
+		var err error
+		if s.markov, err = attacks.LoadMarkovStats(s.MarkovStats); err != nil {
+			return err
+		}
+		if err := s.validateLengths(); err != nil {
+			return err
+		}
+	case AttackPCFG:
+		g, err := attacks.LoadPCFGGrammar(s.PCFGGrammar)
+		if err != nil {
+			return err
+		}
+		s.pcfg = attacks.NewPCFG(g)
+	case AttackPrince:
+		if s.Wordlist == "" {
+			return fmt.Errorf("prince attack needs a wordlist")
+		}
+		if s.ElemCntMin < 0 || s.ElemCntMax < 0 {
+			return fmt.Errorf("element counts must not be negative")
+		}
+		if err := s.validateLengths(); err != nil {
+			return err
+		}
+		config := s.incrementalConfig()
+		var err error
+		s.prince, err = attacks.NewPrinceKeyspace(attacks.PrinceConfig{
+			Wordlist:    s.Wordlist,
+			MinLength:   config.MinLength,
+			MaxLength:   config.MaxLength,
+			MinElements: s.ElemCntMin,
+			MaxElements: s.ElemCntMax,
+		})
+		if err != nil {
+			return err
+		}
+	case AttackTemplate:
+		if s.Template == "" {
+			return fmt.Errorf("template attack needs a template")
+		}
+		if len(s.CustomCharsets) > 4 {
+			return fmt.Errorf("at most 4 custom charsets")
+		}
+		var custom [4]string
+		copy(custom[:], s.CustomCharsets)
+		var err error
+		if s.template, err = attacks.NewTemplateKeyspace(s.Template, custom); err != nil {
+			return err
+		}
+	case AttackPattern:
+		config, err := s.patternConfig()
+		if err != nil {
+			return err
+		}
+		if s.pattern, err = attacks.NewPatternKeyspace(config); err != nil {
+			return err
+		}
+	case AttackKeyboard:
+		config, err := s.keyboardConfig()
+		if err != nil {
+			return err
+		}
+		if s.keyboard, err = attacks.NewKeyboardKeyspace(config); err != nil {
+			return err
+		}
+	case AttackPassphrase:
+		config, err := s.passphraseConfig()
+		if err != nil {
+			return err
+		}
+		if s.passphrase, err = attacks.NewPassphraseKeyspace(config); err != nil {
+			return err
+		}
+	case AttackIncremental, AttackRandom:
+		if err := s.validateLengths(); err != nil {
+			return err
+		}
+	case "":
+		return fmt.Errorf("missing attack")
+	default:
+		return fmt.Errorf("unknown attack %q", s.Attack)
+	}
+
+	if len(s.Rules) > 0 {
+		if s.Attack != AttackList && s.Attack != AttackWordlist && s.Attack != AttackKeyboard {
+			return fmt.Errorf("rules only apply to list, wordlist and keyboard attacks")
+		}
+		var err error
+		if s.engine, err = loadEngine(s.Rules); err != nil {
+			return err
+		}
+	}
+
+	if s.MaxTime < 0 {
+		return fmt.Errorf("max_time must not be negative")
+	}
+	if s.StopWhen != StopAll && s.StopWhen != StopAny {
+		return fmt.Errorf("stop_when must be %q or %q", StopAll, StopAny)
+	}
+	return nil
+}
+
+func (s *Stage) validateLengths() error {
+	if s.MinLength < 0 || s.MaxLength < 0 {
+		return fmt.Errorf("lengths must not be negative")
+	}
+	if s.MaxLength > 0 && s.MinLength > s.MaxLength {
+		return fmt.Errorf("min_length %d is greater than max_length %d", s.MinLength, s.MaxLength)
+	}
+	return nil
+}
+
+func loadEngine(names []string) (*rules.Engine, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	var sets [][]rules.Rule
+	for _, name := range names {
+		set, err := rules.Load(name)
+		if err != nil {
+			return nil, err
+		}
+		sets = append(sets, set)
+	}
+	return rules.NewEngine(sets...), nil
+}
+
+func (s *Stage) incrementalConfig() attacks.IncrementalConfig {
+	config := attacks.IncrementalConfig{
+		Charset:   attacks.ResolveCharset(s.Charset),
+		MinLength: s.MinLength,
+		MaxLength: s.MaxLength,
+	}
+	if config.MinLength == 0 {
+		config.MinLength = 1
+	}
+	if config.MaxLength == 0 {
+		config.MaxLength = 8
+	}
+	return config
+}
+
+// randomConfig uses Seed as is, so a checkpointed stage resumes the same
+// order.
+func (s *Stage) randomConfig() attacks.RandomConfig {
+	config := s.incrementalConfig()
+	return attacks.RandomConfig{
+		Charset:   config.Charset,
+		MinLength: config.MinLength,
+		MaxLength: config.MaxLength,
+		Seed:      s.Seed,
+	}
+}
+
+// patternConfig defaults to dates from 1940 to today and to 10-digit phone
+// and Luhn numbers.
+func (s *Stage) patternConfig() (attacks.PatternConfig, error) {
+	config := attacks.PatternConfig{
+		Patterns:      s.Patterns,
+		Months:        s.Months,
+		Names:         s.Names,
+		PhonePrefixes: s.PhonePrefixes,
+		PhoneDigits:   s.PhoneDigits,
+		CountryCode:   s.CountryCode,
+		LuhnDigits:    s.LuhnDigits,
+		LuhnPrefix:    s.LuhnPrefix,
+	}
+	if config.PhoneDigits == 0 {
+		config.PhoneDigits = 10
+	}
+	if config.LuhnDigits == 0 {
+		config.LuhnDigits = 10
+	}
+
+	from, to := s.DateFrom, s.DateTo
+	if from == "" {
+		from = "1940"
+	}
+	if to == "" {
+		to = time.Now().Format("2006-01-02")
+	}
+	var err error
+	if config.From, err = attacks.ParseDateBound(from, false); err != nil {
+		return config, err
+	}
+	if config.To, err = attacks.ParseDateBound(to, true); err != nil {
+		return config, err
+	}
+	return config, nil
+}
+
+// keyboardConfig defaults to straight US walks of 4 to 8 keys, up to two
+// copies and no shift.
+func (s *Stage) keyboardConfig() (attacks.KeyboardConfig, error) {
+	config := attacks.KeyboardConfig{
+		MinLength: s.MinLength,
+		MaxLength: s.MaxLength,
+		MaxTurns:  s.Turns,
+		MaxCopies: s.Copies,
+		Shift:     s.Shift,
+	}
+	if config.MinLength == 0 {
+		config.MinLength = 4
+	}
+	if config.MaxLength == 0 {
+		config.MaxLength = 8
+	}
+	if config.MaxCopies == 0 {
+		config.MaxCopies = 2
+	}
+	layouts := s.Layouts
+	if len(layouts) == 0 {
+		layouts = []string{"us"}
+	}
+	for _, name := range layouts {
+		layout, err := attacks.LoadKeyboardLayout(name)
+		if err != nil {
+			return config, err
+		}
+		config.Layouts = append(config.Layouts, layout)
+	}
+	return config, nil
+}
+
+// passphraseConfig defaults to 2 to 4 words from the EFF large list,
+// separated by nothing, - or a space, in lower, first and title case.
+func (s *Stage) passphraseConfig() (attacks.PassphraseConfig, error) {
+	config := attacks.PassphraseConfig{
+		MinWords:   s.MinWords,
+		MaxWords:   s.MaxWords,
+		Separators: s.Separators,
+		Cases:      s.Cases,
+		Suffixes:   s.Suffixes,
+	}
+	copy(config.Custom[:], s.CustomCharsets)
+	if config.MinWords == 0 {
+		config.MinWords = 2
+	}
+	if config.MaxWords == 0 {
+		config.MaxWords = max(4, config.MinWords)
+	}
+	if len(config.Separators) == 0 {
+		config.Separators = []string{"", "-", " "}
+	}
+	if len(config.Cases) == 0 {
+		config.Cases = []string{attacks.CaseLower, attacks.CaseFirst, attacks.CaseTitle}
+	}
+	list := s.Wordlist
+	if list == "" {
+		list = "eff-large"
+	}
+	var err error
+	config.Words, err = attacks.LoadPassphraseWords(list)
+	return config, err
+}
+
+func (s *Stage) maskConfig() attacks.MaskConfig {
+	config := attacks.MaskConfig{
+		Mask:         s.Mask,
+		Increment:    s.Increment,
+		IncrementMin: s.IncrementMin,
+		IncrementMax: s.IncrementMax,
+	}
+	copy(config.Custom[:], s.CustomCharsets)
+	return config
+}
+
+func (s *Stage) markovKeyspace() *attacks.MarkovKeyspace {
+	config := s.incrementalConfig()
+	return attacks.NewMarkovKeyspace(attacks.MarkovConfig{
+		Charset:   config.Charset,
+		MinLength: config.MinLength,
+		MaxLength: config.MaxLength,
+		Threshold: s.MarkovThreshold,
+		Stats:     s.markov,
+	})
+}
+
+// PerWord is the number of candidates the stage's rules make from each word.
+func (s *Stage) PerWord() uint64 {
+	if s.engine == nil {
+		return 1
+	}
+	return s.engine.Size()
+}
+
+// CandidatesPerLine is the number of candidates made from each wordlist line
+// by wordlist and hybrid stages, and 0 for stages that read no wordlist.
+func (s *Stage) CandidatesPerLine() uint64 {
+	switch s.Attack {
+	case AttackWordlist:
+		return s.PerWord()
+	case AttackHybridWM, AttackHybridMW:
+		if ks, err := attacks.NewMaskKeyspace(s.maskConfig()); err == nil {
+			return ks.Size()
+		}
+	case AttackCombinator:
+		return s.combinator.PerWord()
+	}
+	return 0
+}
+
+func (s *Stage) IsHybrid() bool {
+	return s.Attack == AttackHybridWM || s.Attack == AttackHybridMW
+}
+
+func (s *Stage) String() string {
+	var ruleText string
+	if len(s.Rules) > 0 {
+		ruleText = " with rules " + strings.Join(s.Rules, "+")
+	}
+
+	switch s.Attack {
+	case AttackList:
+		return fmt.Sprintf("list of %d%s", len(s.Passwords), ruleText)
+	case AttackWordlist:
+		return "wordlist " + s.Wordlist + ruleText
+	case AttackMask:
+		if s.Increment {
+			return "mask " + s.Mask + " (increment)"
+		}
+		return "mask " + s.Mask
+	case AttackHybridWM:
+		return fmt.Sprintf("hybrid %s + %s", s.Wordlist, s.Mask)
+	case AttackHybridMW:
+		return fmt.Sprintf("hybrid %s + %s", s.Mask, s.Wordlist)
+	case AttackCombinator:
+		if s.Middle != "" {
+			return fmt.Sprintf("combinator %s + %s + %s", s.Left, s.Middle, s.Right)
+		}
+		return fmt.Sprintf("combinator %s + %s", s.Left, s.Right)
+	case AttackPCFG:
+		if s.PCFGGrammar == "" {
+			return "pcfg (built-in grammar)"
+		}
+		return "pcfg " + s.PCFGGrammar
+	case AttackPrince:
+		config := s.incrementalConfig()
+		return fmt.Sprintf("prince %s %d-%d", s.Wordlist, config.MinLength, config.MaxLength)
+	case AttackTemplate:
+		return "template " + s.Template
+	case AttackPattern:
+		if len(s.Patterns) == 0 {
+			return "pattern dates"
+		}
+		return "pattern " + strings.Join(s.Patterns, "+")
+	case AttackKeyboard:
+		layouts := s.Layouts
+		if len(layouts) == 0 {
+			layouts = []string{"us"}
+		}
+		return "keyboard " + strings.Join(layouts, "+") + ruleText
+	case AttackPassphrase:
+		list := s.Wordlist
+		if list == "" {
+			list = "eff-large"
+		}
+		return "passphrase " + list
+	default:
+		config := s.incrementalConfig()
+		charset := s.Charset
+		if charset == "" {
+			charset = "alnum"
+		}
+		return fmt.Sprintf("%s %s %d-%d", s.Attack, charset, config.MinLength, config.MaxLength)
+	}
+}
+
+// Keyspace returns the number of candidates the stage would try without an
+// attempt budget. ok is false when that is unknown up front (wordlists) or
+// too large to count.
+func (s *Stage) Keyspace() (size uint64, ok bool) {
+	switch s.Attack {
+	case AttackList:
+		hi, lo := bits.Mul64(uint64(len(s.Passwords)), s.PerWord())
+		if hi != 0 {
+			return 0, false
+		}
+		size = lo
+	case AttackMask:
+		ks, err := attacks.NewMaskKeyspace(s.maskConfig())
+		if err != nil || ks.Overflow() {
+			return 0, false
+		}
+		size = ks.Size()
+	case AttackMarkov:
+		ks := s.markovKeyspace()
+		if ks.Overflow() {
+			return 0, false
+		}
+		size = ks.Size()
+	case AttackPCFG:
+		if s.pcfg.Overflow() {
+			return 0, false
+		}
+		size = s.pcfg.Size()
+	case AttackPrince:
+		if s.prince.Overflow() {
+			return 0, false
+		}
+		size = s.prince.Size()
+	case AttackTemplate:
+		size = s.template.Size()
+	case AttackPattern:
+		if s.pattern.Overflow() {
+			return 0, false
+		}
+		size = s.pattern.Size()
+	case AttackKeyboard:
+		if s.keyboard.Overflow() {
+			return 0, false
+		}
+		hi, lo := bits.Mul64(s.keyboard.Size(), s.PerWord())
+		if hi != 0 {
+			return 0, false
+		}
+		size = lo
+	case AttackPassphrase:
+		if s.passphrase.Overflow() {
+			return 0, false
+		}
+		size = s.passphrase.Size()
+	case AttackIncremental:
+		var overflow bool
+		size, overflow = attacks.EstimateCombinationsChecked(s.incrementalConfig())
+		if overflow {
+			return 0, false
+		}
+	case AttackRandom:
+		ks := attacks.NewRandomKeyspace(s.randomConfig())
+		if ks.Overflow() {
+			return 0, false
+		}
+		size = ks.Size()
+	default:
+		return 0, false
+	}
+	if s.MaxAttempts > 0 && s.MaxAttempts < size {
+		size = s.MaxAttempts
+	}
+	return size, true
+}
+
+// Generator produces the stage's candidates starting at position start,
+// which is how far a checkpointed run got. Wordlist stages report their read
+// position through progress when it is non-nil. For stages with rules, start
+// counts words rather than candidates.
+func (s *Stage) Generator(start uint64, progress *attacks.WordlistProgress) (func(ctx context.Context) <-chan string, error) {
+	var gen func(ctx context.Context) <-chan string
+
+	switch s.Attack {
+	case AttackList:
+		passwords := s.Passwords
+		if start < uint64(len(passwords)) {
+			passwords = passwords[start:]
+		} else {
+			passwords = nil
+		}
+		gen = func(ctx context.Context) <-chan string {
+			return attacks.SliceGenerator(ctx, passwords)
+		}
+	case AttackWordlist:
+		if _, err := os.Stat(s.Wordlist); err != nil {
+			return nil, err
+		}
+		gen = func(ctx context.Context) <-chan string {
+			ch, err := attacks.WordlistGeneratorFrom(ctx, s.Wordlist, start, progress)
+			if err != nil {
+				closed := make(chan string)
+				close(closed)
+				return closed
+			}
+			return ch
+		}
+	case AttackIncremental:
+		config := s.incrementalConfig()
+		ks := attacks.NewIncrementalKeyspace(config)
+		gen = func(ctx context.Context) <-chan string {
+			if start == 0 || ks.Overflow() {
+				return attacks.IncrementalGenerator(ctx, config)
+			}
+			return attacks.KeyspaceGenerator(ctx, ks, start, ks.Size())
+		}
+	case AttackMask:
+		ks, err := attacks.NewMaskKeyspace(s.maskConfig())
+		if err != nil {
+			return nil, err
+		}
+		gen = func(ctx context.Context) <-chan string {
+			return attacks.KeyspaceGenerator(ctx, ks, start, ks.Size())
+		}
+	case AttackHybridWM, AttackHybridMW:
+		h, err := attacks.NewHybrid(s.Wordlist, s.maskConfig(), s.Attack == AttackHybridMW)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := os.Stat(s.Wordlist); err != nil {
+			return nil, err
+		}
+		gen = func(ctx context.Context) <-chan string {
+			ch, err := h.Generator(ctx, start, math.MaxUint64, progress)
+			if err != nil {
+				closed := make(chan string)
+				close(closed)
+				return closed
+			}
+			return ch
+		}
+	case AttackCombinator:
+		gen = func(ctx context.Context) <-chan string {
+			ch, err := s.combinator.Generator(ctx, start, math.MaxUint64, progress)
+			if err != nil {
+				closed := make(chan string)
+				close(closed)
+				return closed
+			}
+			return ch
+		}
+	case AttackMarkov:
+		ks := s.markovKeyspace()
+		gen = func(ctx context.Context) <-chan string {
+			return ks.Generator(ctx, start, ks.Size())
+		}
+	case AttackPCFG:
+		gen = func(ctx context.Context) <-chan string {
+			return s.pcfg.Generator(ctx, start)
+		}
+	case AttackPrince:
+		gen = s.prince.Generator(start)
+	case AttackTemplate:
+		ks := s.template
+		gen = func(ctx context.Context) <-chan string {
+			return attacks.KeyspaceGenerator(ctx, ks, start, ks.Size())
+		}
+	case AttackPattern:
+		ks := s.pattern
+		gen = func(ctx context.Context) <-chan string {
+			return attacks.KeyspaceGenerator(ctx, ks, start, ks.Size())
+		}
+	case AttackKeyboard:
+		ks := s.keyboard
+		gen = func(ctx context.Context) <-chan string {
+			return attacks.KeyspaceGenerator(ctx, ks, start, ks.Size())
+		}
+	case AttackPassphrase:
+		ks := s.passphrase
+		gen = func(ctx context.Context) <-chan string {
+			return attacks.KeyspaceGenerator(ctx, ks, start, ks.Size())
+		}
+	case AttackRandom:
+		config := s.randomConfig()
+		gen = func(ctx context.Context) <-chan string {
+			return attacks.RandomKeyspaceGenerator(ctx, config, start)
+		}
+	default:
+		return nil, fmt.Errorf("unknown attack %q", s.Attack)
+	}
+
+	done := start
+	if s.engine != nil {
+		words := gen
+		gen = func(ctx context.Context) <-chan string {
+			return s.engine.Generator(ctx, words(ctx))
+		}
+		done *= s.engine.Size()
+	}
+
+	if s.MaxAttempts == 0 {
+		return gen, nil
+	}
+	budget := s.MaxAttempts
+	if done >= budget {
+		budget = 0
+	} else {
+		budget -= done
+	}
+	return func(ctx context.Context) <-chan string {
+		return limit(ctx, gen(ctx), budget)
+	}, nil
+}
+
+func limit(ctx context.Context, in <-chan string, n uint64) <-chan string {
+	ch := make(chan string, 1000)
+
+	go func() {
+		defer close(ch)
+		for i := uint64(0); i < n; i++ {
+			password, ok := <-in
+			if !ok {
+				return
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case ch <- password:
+			}
+		}
+	}()
+
+	return ch
+}
@@ -0,0 +1,204 @@
+package plan
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+const examplePlan = `
+name: escalation
+stages:
+  - name: empty and common
+    attack: list
+    passwords: ["", "password", "123456"]
+  - name: pins
+    attack: incremental
+    charset: digits
+    min_length: 4
+    max_length: 8
+    max_time: 30m
+    stop_when: any
+  - attack: random
+    max_attempts: 1000000
+`
+
+func TestParse(t *testing.T) {
+	p, err := Parse([]byte(examplePlan))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if p.Name != "escalation" || len(p.Stages) != 3 {
+		t.Fatalf("plan = %+v", p)
+	}
+
+	pins := p.Stages[1]
+	if pins.MaxTime != 30*time.Minute || pins.StopWhen != StopAny {
+		t.Errorf("pins stage = %+v", pins)
+	}
+	if size, ok := pins.Keyspace(); !ok || size != 111110000 {
+		t.Errorf("pins Keyspace() = %d, %v, want 111110000", size, ok)
+	}
+	if p.Stages[2].Name != "stage 3" || p.Stages[2].StopWhen != StopAll {
+		t.Errorf("defaults not applied: %+v", p.Stages[2])
+	}
+	if size, ok := p.Stages[2].Keyspace(); !ok || size != 1000000 {
+		t.Errorf("random Keyspace() = %d, %v, want the max_attempts budget", size, ok)
+	}
+	if p.Stages[0].Passwords[0] != "" {
+		t.Errorf("empty password lost: %q", p.Stages[0].Passwords)
+	}
+
+	json := `{"stages": [{"attack": "list", "passwords": ["a"], "max_time": "10s"}]}`
+	p, err = Parse([]byte(json))
+	if err != nil {
+		t.Fatalf("Parse JSON: %v", err)
+	}
+	if p.Stages[0].MaxTime != 10*time.Second {
+		t.Errorf("JSON max_time = %v", p.Stages[0].MaxTime)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		plan string
+		want string
+	}{
+		{`stages: []`, "no stages"},
+		{`stages: [{attack: bogus}]`, "unknown attack"},
+		{`stages: [{attack: mask}]`, "needs a mask"},
+		{`stages: [{attack: mask, mask: "?1?d"}]`, "custom charset 1"},
+		{`stages: [{attack: hybrid-wm, mask: "?d"}]`, "needs a wordlist"},
+		{`stages: [{attack: combinator, left: a.txt}]`, "left and a right"},
+		{`stages: [{attack: markov, markov_stats: /nonexistent/stats.bin}]`, "no such file"},
+		{`stages: [{attack: pcfg, pcfg_grammar: /nonexistent/grammar.pcfg}]`, "no such file"},
+		{`stages: [{attack: prince}]`, "needs a wordlist"},
+		{`stages: [{attack: prince, wordlist: /nonexistent/words.txt}]`, "no such file"},
+		{`stages: [{attack: template}]`, "needs a template"},
+		{`stages: [{attack: template, template: "Rex{?d"}]`, "unclosed"},
+		{`stages: [{attack: pattern, patterns: [name4-ddmm]}]`, "needs at least one name"},
+		{`stages: [{attack: pattern, date_from: 85}]`, "invalid date"},
+		{`stages: [{attack: passphrase, wordlist: no-such-list}]`, "unknown passphrase list"},
+		{`stages: [{name: x}]`, "missing attack"},
+		{`stages: [{attack: list}]`, "needs passwords"},
+		{`stages: [{attack: wordlist}]`, "needs a wordlist"},
+		{`stages: [{attack: incremental, min_length: 6, max_length: 4}]`, "greater than"},
+		{`stages: [{attack: random, stop_when: sometimes}]`, "stop_when"},
+		{`stages: [{attack: random, max_time: soon}]`, "time.Duration"},
+	}
+
+	for _, tt := range tests {
+		_, err := Parse([]byte(tt.plan))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Parse(%q) error = %v, want %q", tt.plan, err, tt.want)
+		}
+	}
+}
+
+func TestStageGenerator(t *testing.T) {
+	tests := []struct {
+		stage Stage
+		start uint64
+		want  []string
+	}{
+		{Stage{Attack: AttackList, Passwords: []string{"a", "b", "c"}}, 0, []string{"a", "b", "c"}},
+		{Stage{Attack: AttackList, Passwords: []string{"a", "b", "c"}}, 2, []string{"c"}},
+		{Stage{Attack: AttackList, Passwords: []string{"a", "b", "c"}, MaxAttempts: 2}, 0, []string{"a", "b"}},
+		{Stage{Attack: AttackList, Passwords: []string{"a", "b", "c"}, MaxAttempts: 2}, 1, []string{"b"}},
+		{Stage{Attack: AttackIncremental, Charset: "01", MinLength: 2, MaxLength: 2}, 1, []string{"01", "10", "11"}},
+		{Stage{Attack: AttackIncremental, Charset: "01", MinLength: 1, MaxLength: 2, MaxAttempts: 3}, 0, []string{"0", "1", "00"}},
+		{Stage{Attack: AttackMask, Mask: "x?1", CustomCharsets: []string{"ab"}}, 1, []string{"xb"}},
+		{Stage{Attack: AttackMask, Mask: "?1?1", CustomCharsets: []string{"01"}, Increment: true}, 0, []string{"0", "1", "00", "01", "10", "11"}},
+	}
+
+	for _, tt := range tests {
+		gen, err := tt.stage.Generator(tt.start, nil)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.stage.String(), err)
+		}
+
+		var got []string
+		for p := range gen(context.Background()) {
+			got = append(got, p)
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%s from %d = %q, want %q", tt.stage.String(), tt.start, got, tt.want)
+		}
+	}
+}
+
+func TestStageRules(t *testing.T) {
+	p, err := Parse([]byte(`stages: [{attack: list, passwords: [winter, summer], rules: [best64], max_attempts: 70}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := p.Stages[0]
+	if s.PerWord() != 64 {
+		t.Fatalf("PerWord() = %d, want 64", s.PerWord())
+	}
+	if size, ok := s.Keyspace(); !ok || size != 70 {
+		t.Errorf("Keyspace() = %d, %v, want 70 (attempt budget)", size, ok)
+	}
+
+	gen, err := s.Generator(1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for c := range gen(context.Background()) {
+		got = append(got, c)
+	}
+	if len(got) != 6 || got[0] != "summer" || got[1] != "Summer" {
+		t.Errorf("resumed at word 1 with 6 attempts left, got %q", got)
+	}
+
+	if _, err := Parse([]byte(`stages: [{attack: incremental, rules: [best64]}]`)); err == nil {
+		t.Error("rules accepted on an incremental stage")
+	}
+}
+
+func TestStageResume(t *testing.T) {
+	tests := []struct {
+		name  string
+		plan  string
+		words uint64 // keyspace in candidates before rules
+		start uint64
+		want  []string // first candidates from start
+	}{
+		{"pattern", `stages: [{attack: pattern, patterns: [name-yyyy], names: [Al], date_from: "2000", date_to: "2001"}]`, 6, 4, []string{"al2000", "al2001"}},
+		{"template", `stages: [{attack: template, template: "{Rex|rex}{?1,1-2}", custom_charsets: ["01"]}]`, 12, 10, []string{"rex10", "rex11"}},
+		{"keyboard", `stages: [{attack: keyboard, min_length: 6, max_length: 6, copies: 1, rules: [best64]}]`, 54, 53, []string{"/.,mnb"}},
+		{"passphrase", `stages: [{attack: passphrase, wordlist: eff-short, min_words: 1, max_words: 1, cases: [lower], suffixes: ["?d"]}]`, 1296 * 11, 11, []string{"abandoned", "abandoned0", "abandoned1"}},
+	}
+
+	for _, tt := range tests {
+		p, err := Parse([]byte(tt.plan))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		s := p.Stages[0]
+		if size, ok := s.Keyspace(); !ok || size != tt.words*s.PerWord() {
+			t.Errorf("%s: Keyspace() = %d, %v, want %d", tt.name, size, ok, tt.words*s.PerWord())
+		}
+
+		gen, err := s.Generator(tt.start, nil)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		var got []string
+		for c := range gen(ctx) {
+			got = append(got, c)
+			if len(got) == len(tt.want) {
+				break
+			}
+		}
+		cancel()
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%s: from %d got %q, want %q", tt.name, tt.start, got, tt.want)
+		}
+	}
+}
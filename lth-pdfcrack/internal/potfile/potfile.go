@@ -0,0 +1,138 @@
+package potfile
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/lth/pdfcrack/internal/pdf"
+)
+
+type Potfile struct {
+	path    string
+	entries map[string]string
+	mu      sync.Mutex
+}
+
+func DefaultPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".pdfcrack", "pdfcrack.pot"), nil
+}
+
+// Fingerprint identifies a target by the values the password check depends
+// on, so renamed or copied documents still match.
+func Fingerprint(info *pdf.EncryptionInfo) string {
+	h := sha256.New()
+	for _, field := range [][]byte{info.FileID, info.OwnerHash, info.UserHash} {
+		binary.Write(h, binary.BigEndian, uint32(len(field)))
+		h.Write(field)
+	}
+	binary.Write(h, binary.BigEndian, info.Permissions)
+	binary.Write(h, binary.BigEndian, int32(info.Revision))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func Open(path string) (*Potfile, error) {
+	p := &Potfile{
+		path:    path,
+		entries: make(map[string]string),
+	}
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return p, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fingerprint, password, ok := strings.Cut(scanner.Text(), ":")
+		if !ok || fingerprint == "" {
+			continue
+		}
+		p.entries[fingerprint] = decodePassword(password)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read potfile: %w", err)
+	}
+
+	return p, nil
+}
+
+func (p *Potfile) Path() string {
+	return p.path
+}
+
+func (p *Potfile) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.entries)
+}
+
+func (p *Potfile) Lookup(info *pdf.EncryptionInfo) (string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	password, ok := p.entries[Fingerprint(info)]
+	return password, ok
+}
+
+func (p *Potfile) Add(info *pdf.EncryptionInfo, password string) error {
+	fingerprint := Fingerprint(info)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if existing, ok := p.entries[fingerprint]; ok && existing == password {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(p.path), 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(p.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := fmt.Fprintf(f, "%s:%s\n", fingerprint, encodePassword(password)); err != nil {
+		return err
+	}
+
+	p.entries[fingerprint] = password
+	return nil
+}
+
+func encodePassword(password string) string {
+	for i := 0; i < len(password); i++ {
+		if password[i] < 0x20 || password[i] == 0x7f || password[i] == ':' {
+			return "$HEX[" + hex.EncodeToString([]byte(password)) + "]"
+		}
+	}
+	if strings.HasPrefix(password, "$HEX[") {
+		return "$HEX[" + hex.EncodeToString([]byte(password)) + "]"
+	}
+	return password
+}
+
+func decodePassword(s string) string {
+	if strings.HasPrefix(s, "$HEX[") && strings.HasSuffix(s, "]") {
+		if decoded, err := hex.DecodeString(s[5 : len(s)-1]); err == nil {
+			return string(decoded)
+		}
+	}
+	return s
+}
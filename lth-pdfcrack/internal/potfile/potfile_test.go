@@ -0,0 +1,65 @@
+package potfile
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/lth/pdfcrack/internal/pdf/pdftest"
+)
+
+func TestFingerprint(t *testing.T) {
+	a := pdftest.Encrypt("secret", 3, "a.pdf")
+	b := pdftest.Encrypt("secret", 3, "b.pdf")
+
+	if Fingerprint(a) != Fingerprint(pdftest.Encrypt("secret", 3, "a.pdf")) {
+		t.Error("fingerprint is not stable for identical targets")
+	}
+	if Fingerprint(a) == Fingerprint(b) {
+		t.Error("different /ID values produced the same fingerprint")
+	}
+
+	c := pdftest.Encrypt("secret", 3, "a.pdf")
+	c.Revision = 4
+	if Fingerprint(a) == Fingerprint(c) {
+		t.Error("different revisions produced the same fingerprint")
+	}
+}
+
+func TestPotfileRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", "test.pot")
+
+	pot, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+
+	passwords := map[string]string{
+		"plain.pdf": "hunter2",
+		"colon.pdf": "a:b",
+		"ctrl.pdf":  "line\nbreak",
+		"hex.pdf":   "$HEX[41]",
+	}
+	for id, password := range passwords {
+		if err := pot.Add(pdftest.Encrypt(password, 3, id), password); err != nil {
+			t.Fatalf("Add(%q): %v", password, err)
+		}
+	}
+
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if reopened.Len() != len(passwords) {
+		t.Fatalf("Len() = %d, want %d", reopened.Len(), len(passwords))
+	}
+	for id, password := range passwords {
+		got, ok := reopened.Lookup(pdftest.Encrypt(password, 3, id))
+		if !ok || got != password {
+			t.Errorf("Lookup(%s) = %q, %v, want %q", id, got, ok, password)
+		}
+	}
+
+	if _, ok := reopened.Lookup(pdftest.Encrypt("other", 3, "unknown.pdf")); ok {
+		t.Error("Lookup found an entry for an uncracked target")
+	}
+}
@@ -0,0 +1,156 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const DefaultName = "pdfcrack"
+
+// Session is a checkpoint of an interrupted run: the settings needed to
+// start it again and how far each attack mode got.
+type Session struct {
+	Files           []string `json:"files,omitempty"`
+	HashFile        string   `json:"hash_file,omitempty"`
+	NoPotfile       bool     `json:"no_potfile,omitempty"`
+	UseWordlist     bool     `json:"use_wordlist,omitempty"`
+	UseHarvest      bool     `json:"use_harvest,omitempty"`
+	HarvestMax      int      `json:"harvest_max,omitempty"`
+	UseIncremental  bool     `json:"use_incremental,omitempty"`
+	UseRandom       bool     `json:"use_random,omitempty"`
+	RandomSeed      int64    `json:"random_seed,omitempty"`
+	UseMask         bool     `json:"use_mask,omitempty"`
+	UseHybrid       bool     `json:"use_hybrid,omitempty"`
+	HybridMaskFirst bool     `json:"hybrid_mask_first,omitempty"`
+	UseCombinator   bool     `json:"use_combinator,omitempty"`
+	UseMarkov       bool     `json:"use_markov,omitempty"`
+	MarkovThreshold int      `json:"markov_threshold,omitempty"`
+	MarkovStats     string   `json:"markov_stats,omitempty"`
+	UsePCFG         bool     `json:"use_pcfg,omitempty"`
+	PCFGGrammar     string   `json:"pcfg_grammar,omitempty"`
+	UsePrince       bool     `json:"use_prince,omitempty"`
+	PrinceElemMin   int      `json:"prince_elem_cnt_min,omitempty"`
+	PrinceElemMax   int      `json:"prince_elem_cnt_max,omitempty"`
+	PrinceSkip      uint64   `json:"prince_skip,omitempty"`
+	PrinceLimit     uint64   `json:"prince_limit,omitempty"`
+	UseTemplate     bool     `json:"use_template,omitempty"`
+	Template        string   `json:"template,omitempty"`
+	UsePattern      bool     `json:"use_pattern,omitempty"`
+	Patterns        []string `json:"patterns,omitempty"`
+	DateFrom        string   `json:"date_from,omitempty"`
+	DateTo          string   `json:"date_to,omitempty"`
+	Months          []string `json:"months,omitempty"`
+	Names           []string `json:"names,omitempty"`
+	PhonePrefixes   []string `json:"phone_prefixes,omitempty"`
+	PhoneDigits     int      `json:"phone_digits,omitempty"`
+	CountryCode     string   `json:"country_code,omitempty"`
+	LuhnDigits      int      `json:"luhn_digits,omitempty"`
+	LuhnPrefix      string   `json:"luhn_prefix,omitempty"`
+	UseKeyboard     bool     `json:"use_keyboard,omitempty"`
+	KbLayouts       []string `json:"kb_layouts,omitempty"`
+	KbMin           int      `json:"kb_min,omitempty"`
+	KbMax           int      `json:"kb_max,omitempty"`
+	KbTurns         int      `json:"kb_turns,omitempty"`
+	KbCopies        int      `json:"kb_copies,omitempty"`
+	KbShift         []string `json:"kb_shift,omitempty"`
+	UsePassphrase   bool     `json:"use_passphrase,omitempty"`
+	PhraseList      string   `json:"phrase_list,omitempty"`
+	PhraseMin       int      `json:"phrase_min,omitempty"`
+	PhraseMax       int      `json:"phrase_max,omitempty"`
+	PhraseSeps      []string `json:"phrase_separators,omitempty"`
+	PhraseCases     []string `json:"phrase_cases,omitempty"`
+	PhraseSuffixes  []string `json:"phrase_suffixes,omitempty"`
+	Left            string   `json:"left,omitempty"`
+	Middle          string   `json:"middle,omitempty"`
+	Right           string   `json:"right,omitempty"`
+	Separators      []string `json:"separators,omitempty"`
+	LeftRules       []string `json:"left_rules,omitempty"`
+	RightRules      []string `json:"right_rules,omitempty"`
+	Mask            string   `json:"mask,omitempty"`
+	CustomCharsets  []string `json:"custom_charsets,omitempty"`
+	Increment       bool     `json:"increment,omitempty"`
+	IncrementMin    int      `json:"increment_min,omitempty"`
+	IncrementMax    int      `json:"increment_max,omitempty"`
+	Wordlist        string   `json:"wordlist,omitempty"`
+	Wordlists       []string `json:"wordlists,omitempty"`
+	Encoding        string   `json:"wordlist_encoding,omitempty"`
+	Dedupe          bool     `json:"dedupe,omitempty"`
+	Shards          int      `json:"wordlist_shards,omitempty"`
+	Mmap            bool     `json:"mmap,omitempty"`
+	ToggleCase      string   `json:"toggle_case,omitempty"`
+	Leet            string   `json:"leet,omitempty"`
+	Accents         string   `json:"accents,omitempty"`
+	VariantsMax     uint64   `json:"variants_max,omitempty"`
+	// WordlistPositions is the line reached in each wordlist source, or in
+	// each shard of a source read in shards.
+	WordlistPositions map[string]uint64 `json:"wordlist_positions,omitempty"`
+	Charset           string            `json:"charset,omitempty"`
+	MinLength         int               `json:"min_length"`
+	MaxLength         int               `json:"max_length"`
+	Workers           int               `json:"workers"`
+	Weights           map[string]int    `json:"weights,omitempty"`
+	Rules             []string          `json:"rules,omitempty"`
+	Plan              string            `json:"plan,omitempty"`
+	PlanStage         int               `json:"plan_stage,omitempty"`
+	Positions         map[string]uint64 `json:"positions"`
+	Updated           time.Time         `json:"updated"`
+}
+
+func Path(name string) (string, error) {
+	if name == "" {
+		name = DefaultName
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".pdfcrack", "sessions", name+".session"), nil
+}
+
+func Load(path string) (*Session, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Session{}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("invalid session file %s: %w", path, err)
+	}
+	if s.Positions == nil {
+		s.Positions = make(map[string]uint64)
+	}
+	return s, nil
+}
+
+// Save writes the session atomically so an interrupted save never leaves a
+// truncated checkpoint behind.
+func (s *Session) Save(path string) error {
+	s.Updated = time.Now()
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func Remove(path string) error {
+	err := os.Remove(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
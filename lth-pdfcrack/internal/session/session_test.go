@@ -0,0 +1,51 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sessions", "case-17.session")
+
+	s := &Session{
+		Files:          []string{"a.pdf", "b.pdf"},
+		NoPotfile:      true,
+		UseWordlist:    true,
+		UseIncremental: true,
+		Wordlist:       "rockyou.txt",
+		Charset:        "0123456789",
+		MinLength:      4,
+		MaxLength:      8,
+		Workers:        6,
+		Positions:      map[string]uint64{"W": 123456, "I": 42},
+	}
+	if err := s.Save(path); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Error("temporary file left behind")
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(loaded.Files) != 2 || loaded.Wordlist != "rockyou.txt" || loaded.MaxLength != 8 || !loaded.UseIncremental || !loaded.NoPotfile {
+		t.Errorf("loaded settings = %+v", loaded)
+	}
+	if loaded.Positions["W"] != 123456 || loaded.Positions["I"] != 42 {
+		t.Errorf("loaded positions = %v", loaded.Positions)
+	}
+
+	if err := Remove(path); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if err := Remove(path); err != nil {
+		t.Errorf("Remove of a missing session: %v", err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Error("Load succeeded after Remove")
+	}
+}